@@ -0,0 +1,56 @@
+package main
+
+import (
+	"ai30-project/internal/events"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+func main() {
+	recordsPath := flag.String("records", "", "historical punctuality records (CSV)")
+	groupBy := flag.String("group-by", "", "fit one model per group: line, period, category (default: a single model)")
+	threshold := flag.Float64("threshold", events.DefaultDelayThreshold, "minutes late from which a run counts as delayed")
+	minRecords := flag.Int("min-records", 30, "smallest group fitted separately; smaller groups use the default model")
+	outPath := flag.String("out", "", "fitted model file (JSON, default: standard output)")
+	flag.Parse()
+
+	if *recordsPath == "" {
+		log.Fatal("-records is required")
+	}
+
+	switch events.GroupBy(*groupBy) {
+	case events.GroupByNone, events.GroupByLine, events.GroupByPeriod, events.GroupByCategory:
+	default:
+		log.Fatalf("unknown -group-by %q", *groupBy)
+	}
+
+	file, err := os.Open(*recordsPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	records, err := events.ReadRecords(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	models, err := events.Calibrate(records, events.GroupBy(*groupBy), *threshold, *minRecords)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *outPath == "" {
+		raw, _ := json.MarshalIndent(models, "", "  ")
+		fmt.Println(string(raw))
+		return
+	}
+
+	if err := models.Save(*outPath); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Fitted %d records into %d group models, written to %s\n", len(records), len(models.Groups), *outPath)
+}
@@ -1,16 +1,316 @@
 package main
 
 import (
+	"ai30-project/internal/circulation"
+	"ai30-project/internal/clock"
+	"ai30-project/internal/connections"
+	"ai30-project/internal/crew"
+	"ai30-project/internal/dispatcher"
+	"ai30-project/internal/disruptions"
+	"ai30-project/internal/energy"
+	"ai30-project/internal/events"
+	"ai30-project/internal/messaging"
+	"ai30-project/internal/passengers"
+	"ai30-project/internal/plugins"
+	"ai30-project/internal/power"
 	"ai30-project/internal/simulation"
+	"ai30-project/internal/stations"
+	"ai30-project/internal/trains"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
 	"time"
 )
 
+// params gathers repeated name=value flags.
+type params map[string]any
+
+func (p params) String() string {
+	return fmt.Sprint(map[string]any(p))
+}
+
+func (p params) Set(value string) error {
+	name, raw, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("want name=value, got %q", value)
+	}
+	p[name] = raw
+	return nil
+}
+
 func main() {
-	sim := simulation.NewSimulation("eco", "no_sort")
+	driverParams, strategyParams, policyParams, plannerParams := params{}, params{}, params{}, params{}
+	driverName := flag.String("driver", "eco", "driver behaviour: "+strings.Join(trains.Drivers.Names(), ", ")+" (see -list)")
+	flag.Var(driverParams, "driver-param", "driver parameter as name=value, repeatable")
+	strategyName := flag.String("strategy", "no_sort", "station strategy: "+strings.Join(stations.Strategies.Names(), ", ")+" (see -list)")
+	flag.Var(strategyParams, "strategy-param", "station strategy parameter as name=value, repeatable")
+	list := flag.Bool("list", false, "list the drivers, station strategies, delay policies and dispatcher planners with their parameters, then exit")
+	regeneration := flag.Float64("regen", energy.DefaultModel().Regeneration, "share of the braking energy regenerated, between 0 and 1")
+	demandPath := flag.String("demand", "", "origin-destination passenger demand file (JSON)")
+	capacityPath := flag.String("capacity", "", "train capacity file, with a default and per-train capacities (JSON)")
+	connectionsPath := flag.String("connections", "", "transfer connections file (JSON)")
+	delayPolicy := flag.String("delay-policy", "no_wait", "delay-management policy for connections: "+strings.Join(connections.Policies.Names(), ", ")+" (see -list)")
+	flag.Var(policyParams, "delay-policy-param", "delay-management policy parameter as name=value, repeatable")
+	rotationsPath := flag.String("rotations", "", "rolling-stock rotations file (JSON)")
+	rosterPath := flag.String("roster", "", "crew roster file (JSON)")
+	powerPath := flag.String("power", "", "power supply file with substations and feeding sections (JSON)")
+	dispatcherPlanner := flag.String("dispatcher", "", "central dispatcher planner: "+strings.Join(dispatcher.Planners.Names(), ", ")+" (default: decentralised control, see -list)")
+	flag.Var(plannerParams, "dispatcher-param", "dispatcher planner parameter as name=value, repeatable")
+	dispatcherHorizon := flag.Duration("dispatcher-horizon", 15*time.Minute, "how far ahead the dispatcher predicts conflicts")
+	closedSegments := flag.String("close", "", "comma-separated segments closed from the start of the run")
+	disruptionsPath := flag.String("disruptions", "", "scripted infrastructure disruptions file (JSON)")
+	seed := flag.Int64("seed", 0, "seed of the train events, for reproducible runs (default: random)")
+	eventModelPath := flag.String("event-model", "", "fitted event model file (JSON, see cmd/calibrate)")
+	checkInvariants := flag.Bool("check-invariants", false, "validate safety and consistency rules after each tick")
+	strictInvariants := flag.Bool("strict", false, "abort the run on the first invariant violation (implies -check-invariants)")
+	deadlockPolicy := flag.String("deadlock", "", "detect deadlocks and resolve them: report, abort, cancel")
+	maxTime := flag.Duration("max-time", 0, "abort the run if trains are still running at this time of day (e.g. 30h)")
+	supervision := flag.String("supervision", "restart", "what to do when an agent panics: restart, fail")
+	scheduler := flag.String("scheduler", "concurrent", "how agents run: concurrent, sequential (one goroutine, trains in ID order), shuffled (one goroutine, seeded train order)")
+	requestTimeout := flag.Duration("request-timeout", messaging.DefaultTimeout, "how long to wait for an agent to answer a request")
+	tracePath := flag.String("trace", "", "write the delay propagation trace to this file (JSON)")
+	traceDotPath := flag.String("trace-dot", "", "write the delay propagation graph to this file (Graphviz DOT)")
+	messagesPath := flag.String("messages", "", "write every message between agents to this file (JSON lines)")
+	messagesPlantUML := flag.String("messages-puml", "", "write the messages as a sequence diagram to this file (PlantUML)")
+	messagesMermaid := flag.String("messages-mermaid", "", "write the messages as a sequence diagram to this file (Mermaid)")
+	messagesAgent := flag.String("messages-agent", "", "only keep the messages sent or received by this agent, e.g. \"train ID\" or \"station ID\"")
+	messagesType := flag.String("messages-type", "", "only keep the messages of this type, e.g. EntryRequest")
+	messagesFrom := flag.String("messages-from", "", "only keep the messages sent from this time of day (HH:MM)")
+	messagesUntil := flag.String("messages-until", "", "only keep the messages sent until this time of day (HH:MM)")
+	flag.Parse()
+
+	if *list {
+		printPlugins("Drivers", trains.Drivers.List())
+		printPlugins("Station strategies", stations.Strategies.List())
+		printPlugins("Delay policies", connections.Policies.List())
+		printPlugins("Dispatcher planners", dispatcher.Planners.List())
+		return
+	}
+
+	sim, err := simulation.NewScenarioSimulationWithParams(simulation.DefaultScenario(), *driverName, driverParams, *strategyName, strategyParams)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *regeneration < 0 || *regeneration > 1 {
+		log.Fatal("-regen must be between 0 and 1")
+	}
+	energyModel := energy.DefaultModel()
+	energyModel.Regeneration = *regeneration
+	sim.SetEnergyModel(energyModel)
+
+	if *eventModelPath != "" {
+		models, err := events.LoadModelSet(*eventModelPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		sim.SetEventModels(models)
+	}
+
+	if *seed != 0 {
+		sim.SetSeed(*seed)
+	}
+
+	if *demandPath != "" {
+		demand, err := passengers.LoadDemand(*demandPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		sim.SetDemand(demand)
+	}
+
+	if *capacityPath != "" {
+		capacities, err := passengers.LoadCapacities(*capacityPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := sim.SetCapacities(capacities); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *connectionsPath != "" {
+		conns, err := connections.LoadConnections(*connectionsPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		policy, err := connections.NewPolicy(*delayPolicy, policyParams)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := sim.SetConnections(conns, *delayPolicy, policy); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *rotationsPath != "" {
+		rotations, err := circulation.LoadRotations(*rotationsPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := sim.SetRotations(rotations); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *rosterPath != "" {
+		roster, err := crew.LoadRoster(*rosterPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		sim.SetRoster(roster)
+	}
+
+	if *powerPath != "" {
+		network, err := power.LoadNetwork(*powerPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		sim.SetPowerSupply(network)
+	}
+
+	if *dispatcherPlanner != "" {
+		planner, err := dispatcher.NewPlanner(*dispatcherPlanner, plannerParams)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := sim.SetDispatcher(planner, *dispatcherHorizon); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *closedSegments != "" {
+		for _, segmentID := range strings.Split(*closedSegments, ",") {
+			if err := sim.CloseSegment(segmentID); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	if *disruptionsPath != "" {
+		ds, err := disruptions.LoadDisruptions(*disruptionsPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := sim.AddDisruption(ds...); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *checkInvariants || *strictInvariants {
+		sim.SetInvariantChecks(*strictInvariants)
+	}
+
+	if *deadlockPolicy != "" {
+		if err := sim.SetDeadlockDetection(*deadlockPolicy); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	sim.SetMaxTime(*maxTime)
+
+	if err := sim.SetSupervision(*supervision); err != nil {
+		log.Fatal(err)
+	}
+	sim.SetRequestTimeout(*requestTimeout)
+
+	if err := sim.SetScheduler(*scheduler); err != nil {
+		log.Fatal(err)
+	}
+
+	if *tracePath != "" || *traceDotPath != "" {
+		sim.SetTracing()
+	}
+
+	messageFilter := messaging.Filter{Agent: *messagesAgent, Message: *messagesType}
+	if *messagesFrom != "" {
+		from, err := clock.Parse(*messagesFrom)
+		if err != nil {
+			log.Fatal(err)
+		}
+		messageFilter.From = from
+	}
+	if *messagesUntil != "" {
+		until, err := clock.Parse(*messagesUntil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		messageFilter.Until = until
+	}
+	if *messagesPath != "" || *messagesPlantUML != "" || *messagesMermaid != "" {
+		sim.SetMessageTap()
+	}
+
 	sim.Start()
 
 	for !sim.IsFinished() {
 		sim.Tick()
 		time.Sleep(70 * time.Millisecond)
 	}
+	sim.Stop()
+
+	report, _ := json.MarshalIndent(sim.Report(), "", "  ")
+	fmt.Printf("[Simulation] Report: %s\n", report)
+
+	messages := messageFilter.Apply(sim.Messages())
+	if *messagesPath != "" {
+		var b strings.Builder
+		for _, record := range messages {
+			raw, _ := json.Marshal(record)
+			b.Write(raw)
+			b.WriteByte('\n')
+		}
+		if err := os.WriteFile(*messagesPath, []byte(b.String()), 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *messagesPlantUML != "" {
+		if err := os.WriteFile(*messagesPlantUML, []byte(messaging.PlantUML(messages)), 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *messagesMermaid != "" {
+		if err := os.WriteFile(*messagesMermaid, []byte(messaging.Mermaid(messages)), 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := sim.Failure(); err != nil {
+		log.Fatal(err)
+	}
+
+	if trace, ok := sim.DelayPropagation(); ok {
+		if *tracePath != "" {
+			raw, _ := json.MarshalIndent(trace, "", "  ")
+			if err := os.WriteFile(*tracePath, raw, 0o644); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if *traceDotPath != "" {
+			if err := os.WriteFile(*traceDotPath, []byte(trace.DOT()), 0o644); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+}
+
+func printPlugins(title string, infos []plugins.Info) {
+	fmt.Printf("%s:\n", title)
+	for _, info := range infos {
+		fmt.Printf("  %-26s %s\n", info.Name, info.Description)
+		for _, param := range info.Params {
+			if param.Required {
+				fmt.Printf("      %s (%s, required", param.Name, param.Type)
+			} else {
+				fmt.Printf("      %s (%s, default %v", param.Name, param.Type, param.Default)
+			}
+			if param.Type == plugins.Number {
+				fmt.Printf(", %g to %g", param.Min, param.Max)
+			}
+			fmt.Printf("): %s\n", param.Description)
+		}
+	}
 }
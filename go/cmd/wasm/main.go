@@ -1,16 +1,25 @@
 package main
 
 import (
+	"ai30-project/internal/dispatcher"
+	"ai30-project/internal/disruptions"
+	"ai30-project/internal/plugins"
 	"ai30-project/internal/simulation"
+	"ai30-project/internal/stations"
+	"ai30-project/internal/trains"
 	"encoding/json"
+	"fmt"
 	"syscall/js"
 )
 
 var sim *simulation.Simulation
 
+// start takes the driver and the station strategy with, optionally, their
+// parameters as JSON objects.
 func start(this js.Value, args []js.Value) any {
 	driverBehavior := "eco"
 	stationStrategy := "no_sort"
+	var driverParams, strategyParams map[string]any
 
 	if len(args) >= 1 && args[0].Type() == js.TypeString {
 		driverBehavior = args[0].String()
@@ -18,8 +27,27 @@ func start(this js.Value, args []js.Value) any {
 	if len(args) >= 2 && args[1].Type() == js.TypeString {
 		stationStrategy = args[1].String()
 	}
+	if len(args) >= 3 && args[2].Type() == js.TypeString {
+		if err := json.Unmarshal([]byte(args[2].String()), &driverParams); err != nil {
+			return errorJSON(fmt.Errorf("parsing driver parameters: %w", err))
+		}
+	}
+	if len(args) >= 4 && args[3].Type() == js.TypeString {
+		if err := json.Unmarshal([]byte(args[3].String()), &strategyParams); err != nil {
+			return errorJSON(fmt.Errorf("parsing strategy parameters: %w", err))
+		}
+	}
 
-	sim = simulation.NewSimulation(driverBehavior, stationStrategy)
+	next, err := simulation.NewScenarioSimulationWithParams(simulation.DefaultScenario(), driverBehavior, driverParams, stationStrategy, strategyParams)
+	if err != nil {
+		return errorJSON(err)
+	}
+
+	// Release the agents of the previous run before starting a new one
+	if sim != nil {
+		sim.Stop()
+	}
+	sim = next
 	sim.Start()
 	jsonData, _ := json.Marshal(sim)
 	return string(jsonData)
@@ -34,8 +62,78 @@ func tick(this js.Value, args []js.Value) any {
 	return string(jsonData)
 }
 
+func stop(this js.Value, args []js.Value) any {
+	if sim == nil {
+		return "{}"
+	}
+	sim.Stop()
+	jsonData, _ := json.Marshal(sim)
+	return string(jsonData)
+}
+
+func setSegmentClosed(closed bool) func(this js.Value, args []js.Value) any {
+	return func(this js.Value, args []js.Value) any {
+		if sim == nil || len(args) < 1 || args[0].Type() != js.TypeString {
+			return "{}"
+		}
+
+		var err error
+		if closed {
+			err = sim.CloseSegment(args[0].String())
+		} else {
+			err = sim.OpenSegment(args[0].String())
+		}
+		if err != nil {
+			jsonData, _ := json.Marshal(map[string]string{"error": err.Error()})
+			return string(jsonData)
+		}
+
+		jsonData, _ := json.Marshal(sim)
+		return string(jsonData)
+	}
+}
+
+func addDisruption(this js.Value, args []js.Value) any {
+	if sim == nil || len(args) < 1 || args[0].Type() != js.TypeString {
+		return "{}"
+	}
+
+	ds, err := disruptions.Parse([]byte(args[0].String()))
+	if err == nil {
+		err = sim.AddDisruption(ds...)
+	}
+	if err != nil {
+		jsonData, _ := json.Marshal(map[string]string{"error": err.Error()})
+		return string(jsonData)
+	}
+
+	jsonData, _ := json.Marshal(sim)
+	return string(jsonData)
+}
+
+// listPlugins gives the drivers, station strategies and dispatcher planners
+// with their parameters.
+func listPlugins(this js.Value, args []js.Value) any {
+	jsonData, _ := json.Marshal(map[string][]plugins.Info{
+		"drivers":           trains.Drivers.List(),
+		"stationStrategies": stations.Strategies.List(),
+		"planners":          dispatcher.Planners.List(),
+	})
+	return string(jsonData)
+}
+
+func errorJSON(err error) string {
+	jsonData, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return string(jsonData)
+}
+
 func main() {
+	js.Global().Set("ListPlugins", js.FuncOf(listPlugins))
 	js.Global().Set("Start", js.FuncOf(start))
 	js.Global().Set("Tick", js.FuncOf(tick))
+	js.Global().Set("Stop", js.FuncOf(stop))
+	js.Global().Set("CloseSegment", js.FuncOf(setSegmentClosed(true)))
+	js.Global().Set("OpenSegment", js.FuncOf(setSegmentClosed(false)))
+	js.Global().Set("AddDisruption", js.FuncOf(addDisruption))
 	select {}
 }
@@ -0,0 +1,126 @@
+package circulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type CirculationMessage interface {
+	isMessage()
+}
+
+// FinishNotification is sent when a trip ends, at its terminus or at the
+// station where it was cancelled.
+type FinishNotification struct {
+	TrainID   string
+	StationID string
+	Time      time.Duration
+}
+
+func (FinishNotification) isMessage() {}
+
+func (c *CirculationService) handleFinish(notif FinishNotification) {
+	c.finishes[notif.TrainID] = finish{
+		stationID: notif.StationID,
+		time:      notif.Time,
+	}
+}
+
+type ReadyRequest struct {
+	TrainID            string
+	StationID          string
+	ScheduledDeparture time.Duration
+	Time               time.Duration
+	ResponseCh         chan ReadyResponse
+}
+
+func (ReadyRequest) isMessage() {}
+
+// ReadyResponse tells a trip whether its trainset is available. Cancel is set
+// when the trainset will never reach the departure station.
+type ReadyResponse struct {
+	Ready           bool
+	Cancel          bool
+	PreviousTrainID string
+	Error           error
+}
+
+func (c *CirculationService) handleReadyRequest(req ReadyRequest) {
+	t, inRotation := c.trips[req.TrainID]
+	if !inRotation || t.previous == "" {
+		req.ResponseCh <- ReadyResponse{Ready: true, Error: nil}
+		return
+	}
+
+	previous, finished := c.finishes[t.previous]
+	if !finished {
+		req.ResponseCh <- ReadyResponse{Ready: false, PreviousTrainID: t.previous, Error: nil}
+		return
+	}
+
+	if previous.stationID != req.StationID {
+		fmt.Printf("  [Circulation] Train %s has no trainset: train %s ended at station %s instead of %s\n",
+			req.TrainID, t.previous, previous.stationID, req.StationID)
+		c.withoutStock[req.TrainID] = true
+		req.ResponseCh <- ReadyResponse{Ready: false, Cancel: true, PreviousTrainID: t.previous, Error: nil}
+		return
+	}
+
+	readyAt := previous.time + t.rotation.MinTurnaround
+	if readyAt > req.Time {
+		req.ResponseCh <- ReadyResponse{Ready: false, PreviousTrainID: t.previous, Error: nil}
+		return
+	}
+
+	if inherited := readyAt - req.ScheduledDeparture; inherited > 0 {
+		c.turnarounds[req.TrainID] = inherited
+	} else {
+		c.turnarounds[req.TrainID] = 0
+	}
+
+	req.ResponseCh <- ReadyResponse{Ready: true, PreviousTrainID: t.previous, Error: nil}
+}
+
+type ReportRequest struct {
+	ResponseCh chan Report
+}
+
+func (ReportRequest) isMessage() {}
+
+// Report summarises the turnarounds performed so far. A turnaround is late
+// when the trainset was not ready at the scheduled departure of its next trip;
+// PropagatedDelay sums by how much.
+type Report struct {
+	Rotations        int
+	Turnarounds      int
+	LateTurnarounds  int
+	PropagatedDelay  time.Duration
+	CancelledByStock int
+}
+
+func (c *CirculationService) handleReportRequest(req ReportRequest) {
+	report := Report{Rotations: len(c.rotations)}
+
+	for _, inherited := range c.turnarounds {
+		report.Turnarounds++
+		if inherited > 0 {
+			report.LateTurnarounds++
+			report.PropagatedDelay += inherited
+		}
+	}
+
+	report.CancelledByStock = len(c.withoutStock)
+
+	req.ResponseCh <- report
+}
+
+func (r Report) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"rotations":        r.Rotations,
+		"turnarounds":      r.Turnarounds,
+		"lateTurnarounds":  r.LateTurnarounds,
+		"propagatedDelay":  r.PropagatedDelay,
+		"cancelledByStock": r.CancelledByStock,
+	})
+}
@@ -0,0 +1,63 @@
+package circulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Rotation is the sequence of trips operated by one trainset. Each trip may
+// only leave once the previous one has reached its terminus and the trainset
+// has been turned around.
+type Rotation struct {
+	ID            string
+	Trips         []string // train IDs, in operating order
+	MinTurnaround time.Duration
+}
+
+type rotationFile []struct {
+	ID                   string   `json:"id"`
+	Trips                []string `json:"trips"`
+	MinTurnaroundMinutes int      `json:"minTurnaroundMinutes"`
+}
+
+// LoadRotations reads trainset rotations from a JSON file:
+//
+//	[{"id": "TGV-01", "trips": ["<train>", "<train>"], "minTurnaroundMinutes": 20}]
+func LoadRotations(path string) ([]Rotation, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading rotations file: %w", err)
+	}
+
+	var file rotationFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("parsing rotations file: %w", err)
+	}
+
+	seen := make(map[string]string)
+	rotations := make([]Rotation, 0, len(file))
+	for i, r := range file {
+		if r.ID == "" {
+			return nil, fmt.Errorf("rotation %d: id is required", i)
+		}
+		if r.MinTurnaroundMinutes < 0 {
+			return nil, fmt.Errorf("rotation %s: turnaround cannot be negative", r.ID)
+		}
+		for _, trip := range r.Trips {
+			if other, exists := seen[trip]; exists {
+				return nil, fmt.Errorf("rotation %s: train %s is already operated by rotation %s", r.ID, trip, other)
+			}
+			seen[trip] = r.ID
+		}
+
+		rotations = append(rotations, Rotation{
+			ID:            r.ID,
+			Trips:         r.Trips,
+			MinTurnaround: time.Duration(r.MinTurnaroundMinutes) * time.Minute,
+		})
+	}
+
+	return rotations, nil
+}
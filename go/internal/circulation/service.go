@@ -0,0 +1,81 @@
+package circulation
+
+import (
+	"ai30-project/internal/messaging"
+	"context"
+	"fmt"
+	"time"
+)
+
+type trip struct {
+	rotation *Rotation
+	previous string // previous trip of the trainset, empty for the first one
+}
+
+type finish struct {
+	stationID string
+	time      time.Duration
+}
+
+// CirculationService links the trips of each rotation: it knows where and
+// when every trainset finished its last trip.
+type CirculationService struct {
+	rotations []Rotation
+	trips     map[string]trip
+	finishes  map[string]finish // train ID -> how its trip ended
+
+	turnarounds  map[string]time.Duration // train ID -> delay inherited from the previous trip
+	withoutStock map[string]bool          // trips cancelled because their trainset never came
+
+	inbox chan CirculationMessage
+}
+
+func NewCirculationService(rotations []Rotation) *CirculationService {
+	c := &CirculationService{
+		rotations:    rotations,
+		trips:        make(map[string]trip),
+		finishes:     make(map[string]finish),
+		turnarounds:  make(map[string]time.Duration),
+		withoutStock: make(map[string]bool),
+		inbox:        make(chan CirculationMessage, 100),
+	}
+
+	for i := range c.rotations {
+		rotation := &c.rotations[i]
+		for j, trainID := range rotation.Trips {
+			t := trip{rotation: rotation}
+			if j > 0 {
+				t.previous = rotation.Trips[j-1]
+			}
+			c.trips[trainID] = t
+		}
+	}
+
+	return c
+}
+
+func (c *CirculationService) Inbox() chan CirculationMessage {
+	return c.inbox
+}
+
+func (c *CirculationService) Run(ctx context.Context) {
+	messaging.Serve(ctx, c.inbox, c.handle)
+}
+
+// Drain handles the messages already in the inbox and returns how many.
+func (c *CirculationService) Drain() int {
+	return messaging.Drain(c.inbox, c.handle)
+}
+
+func (c *CirculationService) handle(msg CirculationMessage) {
+	switch m := msg.(type) {
+	case FinishNotification:
+		c.handleFinish(m)
+	case ReadyRequest:
+		c.handleReadyRequest(m)
+	case ReportRequest:
+		c.handleReportRequest(m)
+	default:
+		fmt.Printf("  [Circulation] ERROR: Unknown message type\n")
+	}
+}
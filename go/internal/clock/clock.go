@@ -0,0 +1,31 @@
+package clock
+
+import (
+	"fmt"
+	"time"
+)
+
+// Parse converts a "HH:MM" or "HH:MM:SS" time of day into a duration since
+// midnight, the representation used for every timestamp in the simulation.
+func Parse(value string) (time.Duration, error) {
+	var hours, minutes, seconds int
+
+	n, err := fmt.Sscanf(value, "%d:%d:%d", &hours, &minutes, &seconds)
+	if n < 2 {
+		if err == nil {
+			err = fmt.Errorf("expected HH:MM")
+		}
+		return 0, fmt.Errorf("invalid time of day %q: %w", value, err)
+	}
+
+	if hours < 0 || minutes < 0 || minutes > 59 || seconds < 0 || seconds > 59 {
+		return 0, fmt.Errorf("invalid time of day %q: out of range", value)
+	}
+
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second, nil
+}
+
+// Format renders a duration since midnight as "HH:MM".
+func Format(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,76 @@
+package connections
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Connection lets passengers of a feeder train transfer to a connecting train
+// at a station, provided they have at least MinTransferTime between the two.
+type Connection struct {
+	FeederTrainID     string
+	ConnectingTrainID string
+	StationID         string
+	MinTransferTime   time.Duration
+	MaxWait           time.Duration
+	Passengers        int // expected number of transferring passengers
+}
+
+func (c Connection) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"feederTrainId":     c.FeederTrainID,
+		"connectingTrainId": c.ConnectingTrainID,
+		"stationId":         c.StationID,
+		"minTransferTime":   c.MinTransferTime,
+		"maxWait":           c.MaxWait,
+		"passengers":        c.Passengers,
+	})
+}
+
+type connectionFile []struct {
+	Feeder             string `json:"feeder"`
+	Connecting         string `json:"connecting"`
+	Station            string `json:"station"`
+	MinTransferMinutes int    `json:"minTransferMinutes"`
+	MaxWaitMinutes     int    `json:"maxWaitMinutes"`
+	Passengers         int    `json:"passengers"`
+}
+
+// LoadConnections reads connection definitions from a JSON file:
+//
+//	[{"feeder": "<train>", "connecting": "<train>", "station": "<station>",
+//	  "minTransferMinutes": 5, "maxWaitMinutes": 10, "passengers": 40}]
+func LoadConnections(path string) ([]Connection, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading connections file: %w", err)
+	}
+
+	var file connectionFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("parsing connections file: %w", err)
+	}
+
+	connections := make([]Connection, 0, len(file))
+	for i, c := range file {
+		if c.Feeder == "" || c.Connecting == "" || c.Station == "" {
+			return nil, fmt.Errorf("connection %d: feeder, connecting and station are required", i)
+		}
+		if c.MinTransferMinutes < 0 || c.MaxWaitMinutes < 0 {
+			return nil, fmt.Errorf("connection %d: durations cannot be negative", i)
+		}
+
+		connections = append(connections, Connection{
+			FeederTrainID:     c.Feeder,
+			ConnectingTrainID: c.Connecting,
+			StationID:         c.Station,
+			MinTransferTime:   time.Duration(c.MinTransferMinutes) * time.Minute,
+			MaxWait:           time.Duration(c.MaxWaitMinutes) * time.Minute,
+			Passengers:        c.Passengers,
+		})
+	}
+
+	return connections, nil
+}
@@ -0,0 +1,147 @@
+package connections
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type ConnectionMessage interface {
+	isMessage()
+}
+
+type ArrivalNotification struct {
+	TrainID   string
+	StationID string
+	Time      time.Duration
+}
+
+func (ArrivalNotification) isMessage() {}
+
+func (c *ConnectionService) handleArrival(notif ArrivalNotification) {
+	c.arrivals[visit{notif.TrainID, notif.StationID}] = notif.Time
+}
+
+type DepartureNotification struct {
+	TrainID            string
+	StationID          string
+	ScheduledDeparture time.Duration
+	Time               time.Duration
+}
+
+func (DepartureNotification) isMessage() {}
+
+func (c *ConnectionService) handleDeparture(notif DepartureNotification) {
+	for i, conn := range c.connections {
+		if conn.ConnectingTrainID != notif.TrainID || conn.StationID != notif.StationID {
+			continue
+		}
+
+		delay := notif.Time - notif.ScheduledDeparture
+		if delay < 0 {
+			delay = 0
+		}
+
+		made := c.transferReady(conn, notif.Time)
+		c.outcomes[i] = outcome{made: made, departureDelay: delay}
+
+		if !made {
+			fmt.Printf("  [Connections] Train %s MISSED connection from train %s at station %s at %v\n",
+				notif.TrainID, conn.FeederTrainID, conn.StationID, notif.Time)
+		}
+	}
+}
+
+type HoldRequest struct {
+	TrainID            string
+	StationID          string
+	ScheduledDeparture time.Duration
+	Time               time.Duration
+	ResponseCh         chan HoldResponse
+}
+
+func (HoldRequest) isMessage() {}
+
+type HoldResponse struct {
+	Hold          bool
+	FeederTrainID string
+	Error         error
+}
+
+func (c *ConnectionService) handleHoldRequest(req HoldRequest) {
+	waited := req.Time - req.ScheduledDeparture
+	if waited < 0 {
+		waited = 0
+	}
+
+	for _, conn := range c.connections {
+		if conn.ConnectingTrainID != req.TrainID || conn.StationID != req.StationID {
+			continue
+		}
+
+		if c.transferReady(conn, req.Time) {
+			continue
+		}
+
+		if c.policy.Wait(conn, waited) {
+			fmt.Printf("  [Connections] Train %s HELD at station %s for feeder %s (waited %v) at %v\n",
+				req.TrainID, req.StationID, conn.FeederTrainID, waited, req.Time)
+			req.ResponseCh <- HoldResponse{Hold: true, FeederTrainID: conn.FeederTrainID, Error: nil}
+			return
+		}
+	}
+
+	req.ResponseCh <- HoldResponse{Hold: false, Error: nil}
+}
+
+type ReportRequest struct {
+	ResponseCh chan Report
+}
+
+func (ReportRequest) isMessage() {}
+
+// Report counts the connections made and missed under the active policy.
+// Connections whose connecting train has not departed yet are pending.
+type Report struct {
+	Policy  string
+	Total   int
+	Made    int
+	Missed  int
+	Pending int
+	// DepartureDelay sums how late the connecting trains left the connection stations.
+	DepartureDelay time.Duration
+	// MissedPassengers sums the expected transferring passengers of missed connections.
+	MissedPassengers int
+}
+
+func (c *ConnectionService) handleReportRequest(req ReportRequest) {
+	report := Report{Policy: c.policyName, Total: len(c.connections)}
+
+	for i, conn := range c.connections {
+		o, resolved := c.outcomes[i]
+		switch {
+		case !resolved:
+			report.Pending++
+		case o.made:
+			report.Made++
+		default:
+			report.Missed++
+			report.MissedPassengers += conn.Passengers
+		}
+		report.DepartureDelay += o.departureDelay
+	}
+
+	req.ResponseCh <- report
+}
+
+func (r Report) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"policy":           r.Policy,
+		"total":            r.Total,
+		"made":             r.Made,
+		"missed":           r.Missed,
+		"pending":          r.Pending,
+		"departureDelay":   r.DepartureDelay,
+		"missedPassengers": r.MissedPassengers,
+	})
+}
@@ -0,0 +1,70 @@
+package connections
+
+import (
+	"ai30-project/internal/plugins"
+	"time"
+)
+
+// Policy decides whether a connecting train keeps holding its departure for a
+// late feeder. waited is how long the departure has already been held past
+// its scheduled time.
+type Policy interface {
+	Wait(c Connection, waited time.Duration) bool
+}
+
+// NoWait always departs on time, whatever the feeder does.
+type NoWait struct{}
+
+func (NoWait) Wait(c Connection, waited time.Duration) bool {
+	return false
+}
+
+// WaitUpToMax holds the departure until the feeder passengers can transfer,
+// but never longer than the connection's MaxWait.
+type WaitUpToMax struct{}
+
+func (WaitUpToMax) Wait(c Connection, waited time.Duration) bool {
+	return waited < c.MaxWait
+}
+
+// WaitIfBusy behaves like WaitUpToMax for connections expected to carry at
+// least MinPassengers transferring passengers, and like NoWait otherwise.
+type WaitIfBusy struct {
+	MinPassengers int
+}
+
+func (p WaitIfBusy) Wait(c Connection, waited time.Duration) bool {
+	return c.Passengers >= p.MinPassengers && waited < c.MaxWait
+}
+
+// Policies are the delay-management policies connections can be managed
+// with, by name.
+var Policies = plugins.NewRegistry[Policy]("delay policy")
+
+func init() {
+	Policies.Register(plugins.Plugin[Policy]{
+		Name:        "no_wait",
+		Description: "connecting trains always depart on time",
+		New:         func(plugins.Values) (Policy, error) { return NoWait{}, nil },
+	})
+	Policies.Register(plugins.Plugin[Policy]{
+		Name:        "wait",
+		Description: "connecting trains wait for late feeders up to the max wait of the connection",
+		New:         func(plugins.Values) (Policy, error) { return WaitUpToMax{}, nil },
+	})
+	Policies.Register(plugins.Plugin[Policy]{
+		Name:        "wait_if_busy",
+		Description: "wait like wait for busy connections only, depart on time otherwise",
+		Params: []plugins.Param{
+			{Name: "minPassengers", Type: plugins.Number, Description: "transferring passengers a connection needs to be waited for", Default: 50.0, Min: 0, Max: 10000},
+		},
+		New: func(params plugins.Values) (Policy, error) {
+			return WaitIfBusy{MinPassengers: int(params.Number("minPassengers"))}, nil
+		},
+	})
+}
+
+// NewPolicy builds a registered delay-management policy from its parameters.
+func NewPolicy(name string, params map[string]any) (Policy, error) {
+	return Policies.New(name, params)
+}
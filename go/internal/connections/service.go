@@ -0,0 +1,77 @@
+package connections
+
+import (
+	"ai30-project/internal/messaging"
+	"context"
+	"fmt"
+	"time"
+)
+
+type visit struct {
+	trainID   string
+	stationID string
+}
+
+// ConnectionService tracks feeder arrivals and tells connecting trains
+// whether the delay-management policy holds their departure.
+type ConnectionService struct {
+	connections []Connection
+	policy      Policy
+	policyName  string
+
+	arrivals map[visit]time.Duration
+	outcomes map[int]outcome // connection index -> outcome once the connecting train departed
+
+	inbox chan ConnectionMessage
+}
+
+type outcome struct {
+	made           bool
+	departureDelay time.Duration
+}
+
+func NewConnectionService(connections []Connection, policyName string, policy Policy) *ConnectionService {
+	return &ConnectionService{
+		connections: connections,
+		policy:      policy,
+		policyName:  policyName,
+		arrivals:    make(map[visit]time.Duration),
+		outcomes:    make(map[int]outcome),
+		inbox:       make(chan ConnectionMessage, 100),
+	}
+}
+
+func (c *ConnectionService) Inbox() chan ConnectionMessage {
+	return c.inbox
+}
+
+func (c *ConnectionService) Run(ctx context.Context) {
+	messaging.Serve(ctx, c.inbox, c.handle)
+}
+
+// Drain handles the messages already in the inbox and returns how many.
+func (c *ConnectionService) Drain() int {
+	return messaging.Drain(c.inbox, c.handle)
+}
+
+func (c *ConnectionService) handle(msg ConnectionMessage) {
+	switch m := msg.(type) {
+	case ArrivalNotification:
+		c.handleArrival(m)
+	case DepartureNotification:
+		c.handleDeparture(m)
+	case HoldRequest:
+		c.handleHoldRequest(m)
+	case ReportRequest:
+		c.handleReportRequest(m)
+	default:
+		fmt.Printf("  [Connections] ERROR: Unknown message type\n")
+	}
+}
+
+// transferReady reports whether the feeder passengers of c can be on the
+// connecting train at currentTime.
+func (c *ConnectionService) transferReady(conn Connection, currentTime time.Duration) bool {
+	arrival, arrived := c.arrivals[visit{conn.FeederTrainID, conn.StationID}]
+	return arrived && arrival+conn.MinTransferTime <= currentTime
+}
@@ -0,0 +1,101 @@
+package connections
+
+import (
+	"testing"
+	"time"
+)
+
+var transfer = Connection{
+	FeederTrainID:     "FEEDER",
+	ConnectingTrainID: "CONNECTING",
+	StationID:         "B",
+	MinTransferTime:   5 * time.Minute,
+	MaxWait:           10 * time.Minute,
+	Passengers:        40,
+}
+
+func TestPolicies(t *testing.T) {
+	cases := []struct {
+		policy Policy
+		waited time.Duration
+		want   bool
+	}{
+		{NoWait{}, 0, false},
+		{WaitUpToMax{}, 9 * time.Minute, true},
+		{WaitUpToMax{}, 10 * time.Minute, false},
+		{WaitIfBusy{MinPassengers: 40}, 5 * time.Minute, true},
+		{WaitIfBusy{MinPassengers: 41}, 5 * time.Minute, false},
+		{WaitIfBusy{MinPassengers: 40}, 10 * time.Minute, false},
+	}
+	for _, c := range cases {
+		if got := c.policy.Wait(transfer, c.waited); got != c.want {
+			t.Errorf("%#v after %v: wait %v, want %v", c.policy, c.waited, got, c.want)
+		}
+	}
+
+	if _, err := NewPolicy("wait_forever", nil); err == nil {
+		t.Error("unknown policy accepted")
+	}
+	policy, err := NewPolicy("wait_if_busy", map[string]any{"minPassengers": "30"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy != (WaitIfBusy{MinPassengers: 30}) {
+		t.Errorf("policy %#v, want a threshold of 30 passengers", policy)
+	}
+}
+
+// TestConnectionService follows a connecting train held for a feeder that
+// arrives three minutes late, until the transfer time has passed.
+func TestConnectionService(t *testing.T) {
+	service := NewConnectionService([]Connection{transfer}, "wait", WaitUpToMax{})
+	send := func(msg ConnectionMessage) {
+		service.Inbox() <- msg
+		service.Drain()
+	}
+	holds := func(at time.Duration) bool {
+		responseCh := make(chan HoldResponse, 1)
+		send(HoldRequest{TrainID: "CONNECTING", StationID: "B", ScheduledDeparture: 9 * time.Hour, Time: at, ResponseCh: responseCh})
+		return (<-responseCh).Hold
+	}
+	report := func() Report {
+		responseCh := make(chan Report, 1)
+		send(ReportRequest{ResponseCh: responseCh})
+		return <-responseCh
+	}
+
+	if !holds(9 * time.Hour) {
+		t.Error("connecting train left before its feeder arrived")
+	}
+	if got := report(); got.Pending != 1 {
+		t.Errorf("report %+v before the departure, want the connection pending", got)
+	}
+
+	send(ArrivalNotification{TrainID: "FEEDER", StationID: "B", Time: 9*time.Hour + 3*time.Minute})
+	if !holds(9*time.Hour + 7*time.Minute) {
+		t.Error("connecting train left before the transfer time passed")
+	}
+	if holds(9*time.Hour + 8*time.Minute) {
+		t.Error("connecting train held once the passengers transferred")
+	}
+
+	send(DepartureNotification{TrainID: "CONNECTING", StationID: "B", ScheduledDeparture: 9 * time.Hour, Time: 9*time.Hour + 8*time.Minute})
+	want := Report{Policy: "wait", Total: 1, Made: 1, DepartureDelay: 8 * time.Minute}
+	if got := report(); got != want {
+		t.Errorf("report %+v, want %+v", got, want)
+	}
+}
+
+func TestMissedConnection(t *testing.T) {
+	service := NewConnectionService([]Connection{transfer}, "no_wait", NoWait{})
+	service.Inbox() <- DepartureNotification{TrainID: "CONNECTING", StationID: "B", ScheduledDeparture: 9 * time.Hour, Time: 9 * time.Hour}
+	service.Inbox() <- ArrivalNotification{TrainID: "FEEDER", StationID: "B", Time: 9*time.Hour + 3*time.Minute}
+	responseCh := make(chan Report, 1)
+	service.Inbox() <- ReportRequest{ResponseCh: responseCh}
+	service.Drain()
+
+	want := Report{Policy: "no_wait", Total: 1, Missed: 1, MissedPassengers: 40}
+	if got := <-responseCh; got != want {
+		t.Errorf("report %+v, want %+v", got, want)
+	}
+}
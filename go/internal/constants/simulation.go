@@ -4,6 +4,7 @@ package constants
 const (
 	KmHToMPerMin = 1000.0 / 60.0
 	KmH_to_MS    = 1000.0 / 3600.0
+	MPerMinToMS  = 1.0 / 60.0
 )
 
 // Acceleration and braking rates (in m/min per minute)
@@ -11,6 +12,8 @@ const (
 	MaxAcceleration = 0.50  // m/s²
 	MaxServiceBrake = -0.50 // m/s²
 	EmergencyBrake  = -1.00 // m/s²
+
+	CoastingDeceleration = -0.05 // m/s², running resistance with traction cut
 )
 
 // Distances (in meters)
@@ -24,4 +27,13 @@ const (
 const (
 	EmergencySpeedReduction = 10.0
 	ApproachSpeedFactor     = 10.0
+	MinDispatchedSpeed      = 15.0 // m/s, lowest speed the dispatcher may order
+	DegradedSpeedFactor     = 0.5  // share of the target speed kept during a rolling-stock delay
+	MinCoastingSpeed        = 10.0 // m/s, below it a coasting driver still applies traction
+)
+
+// Train capacity (in passengers)
+const (
+	DefaultSeatCapacity     = 500
+	DefaultStandingCapacity = 100
 )
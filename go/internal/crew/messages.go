@@ -0,0 +1,120 @@
+package crew
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type CrewMessage interface {
+	isMessage()
+}
+
+type ArrivalNotification struct {
+	TrainID   string
+	StationID string
+	Time      time.Duration
+}
+
+func (ArrivalNotification) isMessage() {}
+
+func (c *CrewService) handleArrival(notif ArrivalNotification) {
+	c.arrivals[visit{notif.TrainID, notif.StationID}] = notif.Time
+}
+
+type CancellationNotification struct {
+	TrainID string
+	Time    time.Duration
+}
+
+func (CancellationNotification) isMessage() {}
+
+func (c *CrewService) handleCancellation(notif CancellationNotification) {
+	c.cancelled[notif.TrainID] = notif.Time
+}
+
+type ReadyRequest struct {
+	TrainID            string
+	StationID          string
+	ScheduledDeparture time.Duration
+	Time               time.Duration
+	ResponseCh         chan ReadyResponse
+}
+
+func (ReadyRequest) isMessage() {}
+
+type ReadyResponse struct {
+	Ready           bool
+	WaitingForDuty  string
+	PreviousTrainID string
+	Error           error
+}
+
+func (c *CrewService) handleReadyRequest(req ReadyRequest) {
+	key := visit{req.TrainID, req.StationID}
+
+	latest := time.Duration(0)
+	for _, r := range c.reliefs[key] {
+		readyAt, known := c.readyAt(r)
+		if !known || readyAt > req.Time {
+			req.ResponseCh <- ReadyResponse{
+				Ready:           false,
+				WaitingForDuty:  r.duty.ID,
+				PreviousTrainID: r.previous.TrainID,
+				Error:           nil,
+			}
+			return
+		}
+		latest = max(latest, readyAt)
+	}
+
+	if _, relieved := c.reliefs[key]; relieved {
+		if delay := latest - req.ScheduledDeparture; delay > 0 {
+			c.crewDelays[key] = delay
+			fmt.Printf("  [Crew] Train %s departs %v late from station %s because of crew changeover\n",
+				req.TrainID, delay, req.StationID)
+		} else {
+			c.crewDelays[key] = 0
+		}
+	}
+
+	req.ResponseCh <- ReadyResponse{Ready: true, Error: nil}
+}
+
+type ReportRequest struct {
+	ResponseCh chan Report
+}
+
+func (ReportRequest) isMessage() {}
+
+// Report summarises the crew changeovers performed so far. CrewDelay sums how
+// late departures were ready because the incoming crew was late.
+type Report struct {
+	Duties          int
+	Changeovers     int
+	LateChangeovers int
+	CrewDelay       time.Duration
+}
+
+func (c *CrewService) handleReportRequest(req ReportRequest) {
+	report := Report{Duties: len(c.roster.Duties)}
+
+	for _, delay := range c.crewDelays {
+		report.Changeovers++
+		if delay > 0 {
+			report.LateChangeovers++
+			report.CrewDelay += delay
+		}
+	}
+
+	req.ResponseCh <- report
+}
+
+func (r Report) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"duties":          r.Duties,
+		"changeovers":     r.Changeovers,
+		"lateChangeovers": r.LateChangeovers,
+		"crewDelay":       r.CrewDelay,
+	})
+}
@@ -0,0 +1,91 @@
+package crew
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Leg is the part of a train journey a crew member works, between two relief
+// points.
+type Leg struct {
+	TrainID       string
+	FromStationID string
+	ToStationID   string
+}
+
+// Duty is the sequence of legs worked by one driver or conductor. Changing
+// train at a relief point takes at least MinChangeover.
+type Duty struct {
+	ID            string
+	Role          string
+	Legs          []Leg
+	MinChangeover time.Duration
+}
+
+type Roster struct {
+	Duties []Duty
+}
+
+type rosterFile struct {
+	MinChangeoverMinutes int `json:"minChangeoverMinutes"`
+	Duties               []struct {
+		ID                   string `json:"id"`
+		Role                 string `json:"role"`
+		MinChangeoverMinutes *int   `json:"minChangeoverMinutes"`
+		Legs                 []struct {
+			Train string `json:"train"`
+			From  string `json:"from"`
+			To    string `json:"to"`
+		} `json:"legs"`
+	} `json:"duties"`
+}
+
+// LoadRoster reads crew duties from a JSON file. minChangeoverMinutes may be
+// set for the whole roster and overridden per duty:
+//
+//	{"minChangeoverMinutes": 10, "duties": [{"id": "D1", "role": "driver",
+//	  "legs": [{"train": "<train>", "from": "<station>", "to": "<station>"}]}]}
+func LoadRoster(path string) (*Roster, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading roster file: %w", err)
+	}
+
+	var file rosterFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("parsing roster file: %w", err)
+	}
+
+	roster := &Roster{}
+	for i, d := range file.Duties {
+		if d.ID == "" {
+			return nil, fmt.Errorf("duty %d: id is required", i)
+		}
+
+		changeover := file.MinChangeoverMinutes
+		if d.MinChangeoverMinutes != nil {
+			changeover = *d.MinChangeoverMinutes
+		}
+		if changeover < 0 {
+			return nil, fmt.Errorf("duty %s: changeover cannot be negative", d.ID)
+		}
+
+		duty := Duty{
+			ID:            d.ID,
+			Role:          d.Role,
+			MinChangeover: time.Duration(changeover) * time.Minute,
+		}
+		for j, l := range d.Legs {
+			if j > 0 && l.From != d.Legs[j-1].To {
+				return nil, fmt.Errorf("duty %s: leg %d starts at %s but the previous leg ends at %s", d.ID, j, l.From, d.Legs[j-1].To)
+			}
+			duty.Legs = append(duty.Legs, Leg{TrainID: l.Train, FromStationID: l.From, ToStationID: l.To})
+		}
+
+		roster.Duties = append(roster.Duties, duty)
+	}
+
+	return roster, nil
+}
@@ -0,0 +1,50 @@
+package crew
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func loadRoster(t *testing.T, content string) (*Roster, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "roster.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadRoster(path)
+}
+
+func TestLoadRoster(t *testing.T) {
+	roster, err := loadRoster(t, `{"minChangeoverMinutes": 10, "duties": [
+		{"id": "D1", "role": "driver", "legs": [{"train": "T1", "from": "A", "to": "B"}, {"train": "T2", "from": "B", "to": "C"}]},
+		{"id": "D2", "role": "conductor", "minChangeoverMinutes": 0, "legs": [{"train": "T1", "from": "A", "to": "B"}]}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roster.Duties) != 2 {
+		t.Fatalf("%d duties, want 2", len(roster.Duties))
+	}
+	if got := roster.Duties[0].MinChangeover; got != 10*time.Minute {
+		t.Errorf("D1 changes over in %v, want the roster's 10m", got)
+	}
+	if got := roster.Duties[1].MinChangeover; got != 0 {
+		t.Errorf("D2 changes over in %v, want its own 0s", got)
+	}
+	if want := (Leg{TrainID: "T2", FromStationID: "B", ToStationID: "C"}); roster.Duties[0].Legs[1] != want {
+		t.Errorf("second leg %+v, want %+v", roster.Duties[0].Legs[1], want)
+	}
+
+	for _, invalid := range []string{
+		`{"duties": [{"role": "driver", "legs": []}]}`,
+		`{"minChangeoverMinutes": -5, "duties": [{"id": "D1", "legs": []}]}`,
+		`{"duties": [{"id": "D1", "legs": [{"train": "T1", "from": "A", "to": "B"}, {"train": "T2", "from": "C", "to": "D"}]}]}`,
+		`{"duties": {}}`,
+	} {
+		if _, err := loadRoster(t, invalid); err == nil {
+			t.Errorf("roster %s accepted", invalid)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package crew
+
+import (
+	"ai30-project/internal/messaging"
+	"context"
+	"fmt"
+	"time"
+)
+
+type visit struct {
+	trainID   string
+	stationID string
+}
+
+// relief is a crew member changing train: it leaves previous at the station
+// and must board the departing train.
+type relief struct {
+	duty     *Duty
+	previous Leg
+}
+
+// CrewService knows when trains reach relief points and holds departures
+// until the incoming crew has changed over.
+type CrewService struct {
+	roster *Roster
+
+	reliefs    map[visit][]relief       // departing train and station -> crews to wait for
+	arrivals   map[visit]time.Duration  // train and station -> arrival time
+	cancelled  map[string]time.Duration // train ID -> cancellation time
+	crewDelays map[visit]time.Duration  // departing train and station -> delay caused by late crews
+
+	inbox chan CrewMessage
+}
+
+func NewCrewService(roster *Roster) *CrewService {
+	c := &CrewService{
+		roster:     roster,
+		reliefs:    make(map[visit][]relief),
+		arrivals:   make(map[visit]time.Duration),
+		cancelled:  make(map[string]time.Duration),
+		crewDelays: make(map[visit]time.Duration),
+		inbox:      make(chan CrewMessage, 100),
+	}
+
+	for i := range roster.Duties {
+		duty := &roster.Duties[i]
+		for j := 1; j < len(duty.Legs); j++ {
+			previous, next := duty.Legs[j-1], duty.Legs[j]
+			if previous.TrainID == next.TrainID {
+				continue // the crew stays on board
+			}
+			key := visit{next.TrainID, next.FromStationID}
+			c.reliefs[key] = append(c.reliefs[key], relief{duty: duty, previous: previous})
+		}
+	}
+
+	return c
+}
+
+func (c *CrewService) Inbox() chan CrewMessage {
+	return c.inbox
+}
+
+func (c *CrewService) Run(ctx context.Context) {
+	messaging.Serve(ctx, c.inbox, c.handle)
+}
+
+// Drain handles the messages already in the inbox and returns how many.
+func (c *CrewService) Drain() int {
+	return messaging.Drain(c.inbox, c.handle)
+}
+
+func (c *CrewService) handle(msg CrewMessage) {
+	switch m := msg.(type) {
+	case ArrivalNotification:
+		c.handleArrival(m)
+	case CancellationNotification:
+		c.handleCancellation(m)
+	case ReadyRequest:
+		c.handleReadyRequest(m)
+	case ReportRequest:
+		c.handleReportRequest(m)
+	default:
+		fmt.Printf("  [Crew] ERROR: Unknown message type\n")
+	}
+}
+
+// readyAt returns when the crew of r can board its next train, if known yet.
+// A crew whose train was cancelled is assumed to reach the relief point by
+// other means at the time of the cancellation.
+func (c *CrewService) readyAt(r relief) (time.Duration, bool) {
+	if arrival, arrived := c.arrivals[visit{r.previous.TrainID, r.previous.ToStationID}]; arrived {
+		return arrival + r.duty.MinChangeover, true
+	}
+	if cancelledAt, isCancelled := c.cancelled[r.previous.TrainID]; isCancelled {
+		return cancelledAt + r.duty.MinChangeover, true
+	}
+	return 0, false
+}
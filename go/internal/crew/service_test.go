@@ -0,0 +1,107 @@
+package crew
+
+import (
+	"testing"
+	"time"
+)
+
+// relay has a driver bring T1 from A to B, then take T2 on to C after a ten
+// minute changeover. T2 is due to leave B at 09:00.
+var relay = &Roster{Duties: []Duty{{
+	ID:            "D1",
+	Role:          "driver",
+	MinChangeover: 10 * time.Minute,
+	Legs: []Leg{
+		{TrainID: "T1", FromStationID: "A", ToStationID: "B"},
+		{TrainID: "T2", FromStationID: "B", ToStationID: "C"},
+	},
+}}}
+
+func send(service *CrewService, msg CrewMessage) {
+	service.Inbox() <- msg
+	service.Drain()
+}
+
+func ready(service *CrewService, trainID, stationID string, at time.Duration) ReadyResponse {
+	responseCh := make(chan ReadyResponse, 1)
+	send(service, ReadyRequest{TrainID: trainID, StationID: stationID, ScheduledDeparture: 9 * time.Hour, Time: at, ResponseCh: responseCh})
+	return <-responseCh
+}
+
+func report(service *CrewService) Report {
+	responseCh := make(chan Report, 1)
+	send(service, ReportRequest{ResponseCh: responseCh})
+	return <-responseCh
+}
+
+// TestCrewService follows T2 held at B for its driver, whose train arrives
+// five minutes too late for the changeover.
+func TestCrewService(t *testing.T) {
+	service := NewCrewService(relay)
+
+	if got := ready(service, "T1", "A", 8*time.Hour); !got.Ready {
+		t.Errorf("T1 held at A without a relief: %+v", got)
+	}
+	want := ReadyResponse{WaitingForDuty: "D1", PreviousTrainID: "T1"}
+	if got := ready(service, "T2", "B", 9*time.Hour); got != want {
+		t.Errorf("T2 before its driver arrived: %+v, want %+v", got, want)
+	}
+
+	send(service, ArrivalNotification{TrainID: "T1", StationID: "B", Time: 8*time.Hour + 55*time.Minute})
+	if got := ready(service, "T2", "B", 9*time.Hour+4*time.Minute); got.Ready {
+		t.Error("T2 ready before its driver changed over")
+	}
+	if got := ready(service, "T2", "B", 9*time.Hour+5*time.Minute); !got.Ready {
+		t.Errorf("T2 held once its driver changed over: %+v", got)
+	}
+
+	wantReport := Report{Duties: 1, Changeovers: 1, LateChangeovers: 1, CrewDelay: 5 * time.Minute}
+	if got := report(service); got != wantReport {
+		t.Errorf("report %+v, want %+v", got, wantReport)
+	}
+}
+
+func TestCrewServiceOnTime(t *testing.T) {
+	service := NewCrewService(relay)
+	send(service, ArrivalNotification{TrainID: "T1", StationID: "B", Time: 8*time.Hour + 40*time.Minute})
+
+	if got := ready(service, "T2", "B", 9*time.Hour); !got.Ready {
+		t.Errorf("T2 held by a driver on time: %+v", got)
+	}
+	want := Report{Duties: 1, Changeovers: 1}
+	if got := report(service); got != want {
+		t.Errorf("report %+v, want %+v", got, want)
+	}
+}
+
+// TestCrewServiceCancellation checks a driver whose train is cancelled is
+// assumed to reach the relief point by the time of the cancellation.
+func TestCrewServiceCancellation(t *testing.T) {
+	service := NewCrewService(relay)
+	send(service, CancellationNotification{TrainID: "T1", Time: 9 * time.Hour})
+
+	if got := ready(service, "T2", "B", 9*time.Hour+9*time.Minute); got.Ready {
+		t.Error("T2 ready before the changeover after the cancellation")
+	}
+	if got := ready(service, "T2", "B", 9*time.Hour+10*time.Minute); !got.Ready {
+		t.Errorf("T2 held after the changeover: %+v", got)
+	}
+}
+
+func TestCrewStaysOnBoard(t *testing.T) {
+	service := NewCrewService(&Roster{Duties: []Duty{{
+		ID:            "D1",
+		MinChangeover: 10 * time.Minute,
+		Legs: []Leg{
+			{TrainID: "T1", FromStationID: "A", ToStationID: "B"},
+			{TrainID: "T1", FromStationID: "B", ToStationID: "C"},
+		},
+	}}})
+
+	if got := ready(service, "T1", "B", 9*time.Hour); !got.Ready {
+		t.Errorf("T1 held at B for the crew already on board: %+v", got)
+	}
+	if got := report(service); got.Changeovers != 0 {
+		t.Errorf("report %+v, want no changeover", got)
+	}
+}
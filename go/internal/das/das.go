@@ -0,0 +1,184 @@
+// Package das is a Driver Advisory System: for the run to the next stop, it
+// computes an energy-efficient speed profile that still keeps the schedule,
+// and advises the driver along it.
+package das
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// Section is a stretch of the run with its own line speed.
+type Section struct {
+	Length   float64 // meters
+	MaxSpeed float64 // m/s
+}
+
+// Performance is how the train is driven along the profile.
+type Performance struct {
+	Accel         float64 // m/s²
+	Brake         float64 // m/s², positive
+	Coast         float64 // m/s², running resistance with traction cut, positive
+	MinCoastSpeed float64 // m/s, traction is applied again below it
+}
+
+type Phase string
+
+const (
+	Accelerate Phase = "accelerate"
+	Cruise     Phase = "cruise"
+	Coast      Phase = "coast"
+	Brake      Phase = "brake"
+)
+
+// cruiseMargin is how much faster than the slowest cruise keeping time the
+// profile runs, to gain the time it then spends coasting.
+const cruiseMargin = 1.15
+
+// step is the resolution of the running time predictions, well below the
+// minute of a tick.
+const step = 5 * time.Second
+
+// searchSteps halves the range of the cruise speed and of the coasting point
+// down to a few thousandths of it.
+const searchSteps = 10
+
+// Profile is accelerate, cruise, coast, brake along a run. CoastFrom past the
+// end of the run means no coasting.
+type Profile struct {
+	Sections    []Section
+	Cruise      float64 // m/s
+	CoastFrom   float64 // meters from the start of the run
+	RunningTime time.Duration
+	OnTime      bool // the profile keeps the available time
+}
+
+// Compute finds the profile of a run started at speed with available time to
+// reach its end. It cruises a little above the slowest speed keeping time and
+// coasts from the earliest point that still does. A train that cannot keep
+// time runs at line speed without coasting.
+func Compute(sections []Section, speed float64, available time.Duration, perf Performance) Profile {
+	length := 0.0
+	lineSpeed := 0.0
+	for _, s := range sections {
+		length += s.Length
+		lineSpeed = math.Max(lineSpeed, s.MaxSpeed)
+	}
+
+	p := Profile{Sections: sections, Cruise: lineSpeed, CoastFrom: length}
+	p.RunningTime = p.runningTime(speed, perf, available)
+	if p.RunningTime > available || length <= 0 {
+		return p
+	}
+	p.OnTime = true
+
+	// Slowest cruise keeping time, without coasting, above the average speed
+	low, high := math.Max(perf.MinCoastSpeed, length/available.Seconds()), lineSpeed
+	for range searchSteps {
+		mid := (low + high) / 2
+		if (Profile{Sections: sections, Cruise: mid, CoastFrom: length}).runningTime(speed, perf, available) <= available {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+	p.Cruise = math.Min(high*cruiseMargin, lineSpeed)
+
+	// Earliest coasting point keeping time
+	low, high = 0, length
+	for range searchSteps {
+		mid := (low + high) / 2
+		if (Profile{Sections: sections, Cruise: p.Cruise, CoastFrom: mid}).runningTime(speed, perf, available) <= available {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+	p.CoastFrom = high
+	p.RunningTime = p.runningTime(speed, perf, available)
+	return p
+}
+
+// runningTime drives the profile from speed until the train stops at the end
+// of the run, giving up past limit.
+func (p Profile) runningTime(speed float64, perf Performance, limit time.Duration) time.Duration {
+	length := p.length()
+	dt := step.Seconds()
+	position, elapsed := 0.0, time.Duration(0)
+
+	for position < length && elapsed <= limit {
+		advice := p.Advise(position, speed, perf)
+		switch {
+		case speed > advice.Speed:
+			speed = math.Max(advice.Speed, speed-perf.Brake*dt)
+		case advice.Phase == Coast:
+			speed -= perf.Coast * dt
+		case speed < advice.Speed:
+			speed = math.Min(advice.Speed, speed+perf.Accel*dt)
+		}
+		// A train braking short of the end creeps to it
+		position += math.Max(speed, 1) * dt
+		elapsed += step
+	}
+	return elapsed
+}
+
+// Advice is the phase to drive and the speed to aim for.
+type Advice struct {
+	Phase Phase
+	Speed float64 // m/s
+}
+
+// Advise tells the driver what to do at position, meters from the start of
+// the run, and speed. Trains slow down for the end of every section, where
+// they ask to enter the next one.
+func (p Profile) Advise(position, speed float64, perf Performance) Advice {
+	section, remaining := p.sectionAt(position)
+	limit := math.Min(p.Cruise, section.MaxSpeed)
+
+	if braking := math.Sqrt(2 * perf.Brake * math.Max(remaining, 0)); braking < limit {
+		return Advice{Phase: Brake, Speed: braking}
+	}
+	if position >= p.CoastFrom {
+		if speed >= perf.MinCoastSpeed {
+			return Advice{Phase: Coast, Speed: limit}
+		}
+		return Advice{Phase: Accelerate, Speed: math.Min(limit, perf.MinCoastSpeed)}
+	}
+	if speed < limit {
+		return Advice{Phase: Accelerate, Speed: limit}
+	}
+	return Advice{Phase: Cruise, Speed: limit}
+}
+
+func (p Profile) length() float64 {
+	total := 0.0
+	for _, s := range p.Sections {
+		total += s.Length
+	}
+	return total
+}
+
+// sectionAt returns the section at position and the distance to its end.
+func (p Profile) sectionAt(position float64) (Section, float64) {
+	for _, s := range p.Sections {
+		if position < s.Length {
+			return s, s.Length - position
+		}
+		position -= s.Length
+	}
+	if len(p.Sections) == 0 {
+		return Section{}, 0
+	}
+	return p.Sections[len(p.Sections)-1], 0
+}
+
+func (p Profile) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"cruise":      p.Cruise,
+		"coastFrom":   p.CoastFrom,
+		"runningTime": p.RunningTime,
+		"onTime":      p.OnTime,
+	})
+}
@@ -0,0 +1,128 @@
+package deadlock
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Patience is how many consecutive ticks a train must stay blocked before it
+// is considered for a deadlock, so that waits resolving on their own within a
+// tick or two are ignored.
+const Patience = 3
+
+// Wait is what a blocked train waits for: a segment or station, and the
+// trains holding it. Any of the holders moving on unblocks the train.
+type Wait struct {
+	ResourceID string
+	Holders    []string
+}
+
+// Link is an edge of a wait-for cycle: TrainID waits for ResourceID, held by
+// HolderID.
+type Link struct {
+	TrainID    string
+	ResourceID string
+	HolderID   string
+}
+
+func (l Link) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"train":    l.TrainID,
+		"resource": l.ResourceID,
+		"holder":   l.HolderID,
+	})
+}
+
+// Deadlock is a circular wait found at Tick.
+type Deadlock struct {
+	Tick       time.Duration
+	Cycle      []Link
+	Resolution string
+}
+
+func (d Deadlock) Key() string {
+	ids := make([]string, len(d.Cycle))
+	for i, link := range d.Cycle {
+		ids[i] = link.TrainID
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, "|")
+}
+
+func (d Deadlock) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"tick":       d.Tick,
+		"cycle":      d.Cycle,
+		"resolution": d.Resolution,
+	})
+}
+
+// Detect finds the circular waits among blocked trains. A train can only be
+// deadlocked if every holder it waits for is deadlocked as well, so trains
+// waiting for at least one train that is free to move are pruned first; every
+// train left is part of, or stuck behind, a cycle.
+func Detect(waits map[string]Wait) []Deadlock {
+	stuck := make(map[string]bool, len(waits))
+	for trainID := range waits {
+		stuck[trainID] = true
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for trainID := range stuck {
+			for _, holder := range waits[trainID].Holders {
+				if !stuck[holder] {
+					delete(stuck, trainID)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(stuck))
+	for trainID := range stuck {
+		ids = append(ids, trainID)
+	}
+	sort.Strings(ids)
+
+	var deadlocks []Deadlock
+	seen := make(map[string]bool)
+	for _, start := range ids {
+		cycle := findCycle(start, waits)
+		if cycle == nil {
+			continue
+		}
+		d := Deadlock{Cycle: cycle}
+		if !seen[d.Key()] {
+			seen[d.Key()] = true
+			deadlocks = append(deadlocks, d)
+		}
+	}
+	return deadlocks
+}
+
+// findCycle follows the first holder of each wait from start until a train
+// repeats, and returns the loop it closed.
+func findCycle(start string, waits map[string]Wait) []Link {
+	var path []Link
+	position := make(map[string]int)
+
+	for trainID := start; ; {
+		if i, ok := position[trainID]; ok {
+			return path[i:]
+		}
+		wait, ok := waits[trainID]
+		if !ok || len(wait.Holders) == 0 {
+			return nil
+		}
+
+		position[trainID] = len(path)
+		holders := append([]string(nil), wait.Holders...)
+		sort.Strings(holders)
+		path = append(path, Link{TrainID: trainID, ResourceID: wait.ResourceID, HolderID: holders[0]})
+		trainID = holders[0]
+	}
+}
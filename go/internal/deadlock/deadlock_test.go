@@ -0,0 +1,51 @@
+package deadlock
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	waits := map[string]Wait{
+		// T1 and T2 hold each other's segment; T3 is stuck behind T1
+		"T1": {ResourceID: "S1", Holders: []string{"T2"}},
+		"T2": {ResourceID: "S2", Holders: []string{"T1"}},
+		"T3": {ResourceID: "S3", Holders: []string{"T1"}},
+		// T4 waits for a station held by T5, which is free, and T1
+		"T4": {ResourceID: "X", Holders: []string{"T1", "T5"}},
+		// T6, T7 and T8 wait in a circle
+		"T6": {ResourceID: "S6", Holders: []string{"T7"}},
+		"T7": {ResourceID: "S7", Holders: []string{"T8"}},
+		"T8": {ResourceID: "S8", Holders: []string{"T6"}},
+	}
+
+	want := []Deadlock{
+		{Cycle: []Link{{TrainID: "T1", ResourceID: "S1", HolderID: "T2"}, {TrainID: "T2", ResourceID: "S2", HolderID: "T1"}}},
+		{Cycle: []Link{
+			{TrainID: "T6", ResourceID: "S6", HolderID: "T7"},
+			{TrainID: "T7", ResourceID: "S7", HolderID: "T8"},
+			{TrainID: "T8", ResourceID: "S8", HolderID: "T6"},
+		}},
+	}
+	if got := Detect(waits); !reflect.DeepEqual(got, want) {
+		t.Errorf("deadlocks %+v, want %+v", got, want)
+	}
+
+	// Once T8 waits for a free train as well, the circle can unwind
+	waits["T8"] = Wait{ResourceID: "S8", Holders: []string{"T6", "T5"}}
+	if got := Detect(waits); len(got) != 1 || got[0].Key() != "T1|T2" {
+		t.Errorf("deadlocks %+v, want T1 and T2 only", got)
+	}
+
+	if got := Detect(map[string]Wait{"T1": {ResourceID: "S1", Holders: []string{"T2"}}}); got != nil {
+		t.Errorf("deadlocks %+v in a plain wait", got)
+	}
+}
+
+func TestDeadlockKey(t *testing.T) {
+	a := Deadlock{Cycle: []Link{{TrainID: "T2"}, {TrainID: "T1"}}}
+	b := Deadlock{Cycle: []Link{{TrainID: "T1"}, {TrainID: "T2"}}}
+	if a.Key() != b.Key() {
+		t.Errorf("keys %q and %q differ for the same trains", a.Key(), b.Key())
+	}
+}
@@ -0,0 +1,198 @@
+package dispatcher
+
+import (
+	"ai30-project/internal/messaging"
+	"ai30-project/internal/stations"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Dispatcher is a central agent with a global view of the network. Trains
+// report their status every tick; after each tick the dispatcher predicts the
+// conflicts within its horizon and sends orders to trains and stations.
+type Dispatcher struct {
+	planner    Planner
+	horizon    time.Duration
+	capacities map[string]int
+
+	statuses       map[string]StatusReport
+	trainInboxes   map[string]chan Order
+	stationInboxes map[string]chan stations.StationMessage
+
+	ordered   map[string]bool // stations following an admission order
+	conflicts int
+	issued    map[string]int // order kind -> orders issued
+
+	inbox          chan DispatcherMessage
+	tap            *messaging.Tap
+	ctx            context.Context
+	requestTimeout time.Duration
+	deliver        func(inbox any) // set when drained in place rather than run
+}
+
+func NewDispatcher(planner Planner, horizon time.Duration, stationList map[string]*stations.Station) (*Dispatcher, error) {
+	if horizon <= 0 {
+		return nil, fmt.Errorf("dispatcher horizon must be positive, got %v", horizon)
+	}
+
+	d := &Dispatcher{
+		planner:        planner,
+		horizon:        horizon,
+		capacities:     make(map[string]int),
+		statuses:       make(map[string]StatusReport),
+		trainInboxes:   make(map[string]chan Order),
+		stationInboxes: make(map[string]chan stations.StationMessage),
+		ordered:        make(map[string]bool),
+		issued:         make(map[string]int),
+		inbox:          make(chan DispatcherMessage, 1000),
+		ctx:            context.Background(),
+		requestTimeout: messaging.DefaultTimeout,
+	}
+
+	for id, station := range stationList {
+		d.capacities[id] = station.Capacity()
+		d.stationInboxes[id] = station.Inbox()
+	}
+
+	return d, nil
+}
+
+func (d *Dispatcher) Inbox() chan DispatcherMessage {
+	return d.inbox
+}
+
+// SetTap records the orders the dispatcher sends.
+func (d *Dispatcher) SetTap(tap *messaging.Tap) {
+	d.tap = tap
+}
+
+// SetRequestTimeout bounds how long the dispatcher waits for a full inbox of
+// a train or a station to take an order.
+func (d *Dispatcher) SetRequestTimeout(timeout time.Duration) {
+	d.requestTimeout = timeout
+}
+
+// SetDeliver lets the caller drain the dispatcher instead of running it.
+// deliver must have the agent owning inbox handle its waiting messages.
+func (d *Dispatcher) SetDeliver(deliver func(inbox any)) {
+	d.deliver = deliver
+}
+
+// RegisterTrain gives the dispatcher the inbox where a train reads its orders.
+func (d *Dispatcher) RegisterTrain(trainID string, orders chan Order) {
+	d.trainInboxes[trainID] = orders
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+	d.ctx = ctx
+	messaging.Serve(ctx, d.inbox, d.handle)
+}
+
+// Drain handles the messages already in the inbox and returns how many.
+func (d *Dispatcher) Drain() int {
+	return messaging.Drain(d.inbox, d.handle)
+}
+
+func (d *Dispatcher) handle(msg DispatcherMessage) {
+	switch m := msg.(type) {
+	case StatusReport:
+		d.handleStatusReport(m)
+	case PlanRequest:
+		d.handlePlanRequest(m)
+	case ReportRequest:
+		d.handleReportRequest(m)
+	default:
+		fmt.Printf("  [Dispatcher] ERROR: Unknown message type\n")
+	}
+}
+
+// view builds the snapshot handed to the planner, keeping only the trains
+// that reported during the current tick.
+func (d *Dispatcher) view(currentTime time.Duration) *View {
+	v := &View{
+		Time:       currentTime,
+		Horizon:    d.horizon,
+		Capacities: d.capacities,
+	}
+
+	for id, status := range d.statuses {
+		if status.Time < currentTime {
+			delete(d.statuses, id)
+			continue
+		}
+		v.Trains = append(v.Trains, status)
+	}
+
+	sort.Slice(v.Trains, func(i, j int) bool {
+		return v.Trains[i].TrainID < v.Trains[j].TrainID
+	})
+	return v
+}
+
+func (d *Dispatcher) issue(plan Plan) {
+	for trainID, orders := range plan.TrainOrders {
+		inbox, ok := d.trainInboxes[trainID]
+		if !ok {
+			continue
+		}
+		for _, order := range orders {
+			if notify(d, "train "+trainID, inbox, order) {
+				d.issued[orderKind(order)]++
+			}
+		}
+	}
+
+	// Stations no longer in conflict go back to their own strategy.
+	for stationID := range d.ordered {
+		if _, stillOrdered := plan.StationOrders[stationID]; !stillOrdered {
+			d.send(stationID, stations.PriorityOrder{TrainIDs: nil})
+			delete(d.ordered, stationID)
+		}
+	}
+
+	for stationID, order := range plan.StationOrders {
+		if _, ok := d.stationInboxes[stationID]; !ok {
+			continue
+		}
+		d.send(stationID, stations.PriorityOrder{TrainIDs: order})
+		d.ordered[stationID] = true
+		d.issued["order"]++
+	}
+}
+
+func (d *Dispatcher) send(stationID string, order stations.PriorityOrder) {
+	notify[stations.StationMessage](d, "station "+stationID, d.stationInboxes[stationID], order)
+}
+
+// notify sends an order to an agent, giving up after the request timeout if
+// its inbox stays full, and tells whether it was delivered.
+func notify[M any](d *Dispatcher, agent string, inbox chan M, msg M) bool {
+	var err error
+	if d.deliver != nil {
+		err = messaging.Post(agent, inbox, msg, func() { d.deliver(inbox) })
+	} else {
+		err = messaging.Notify(d.ctx, agent, inbox, msg, d.requestTimeout)
+	}
+	d.tap.Notification("dispatcher", agent, msg, err)
+	if err != nil {
+		fmt.Printf("  [Dispatcher] ERROR: Sending order %v\n", err)
+	}
+	return err == nil
+}
+
+func orderKind(order Order) string {
+	switch order.(type) {
+	case HoldOrder:
+		return "hold"
+	case SpeedOrder:
+		return "speed"
+	case RerouteOrder:
+		return "reroute"
+	case CancelOrder:
+		return "cancel"
+	default:
+		return "unknown"
+	}
+}
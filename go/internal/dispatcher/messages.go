@@ -0,0 +1,87 @@
+package dispatcher
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type DispatcherMessage interface {
+	isMessage()
+}
+
+// StatusReport is sent by every running train at the end of each tick.
+type StatusReport struct {
+	TrainID string
+	Time    time.Duration
+	Delay   time.Duration
+
+	// At a station: StationID is the current station and NextStationID the
+	// next stop. On a segment: StationID is empty.
+	AtStation          bool
+	StationID          string
+	ScheduledDeparture time.Duration
+
+	NextStationID    string
+	ScheduledArrival time.Duration // at NextStationID
+
+	SegmentID         string
+	Position          float64 // meters
+	Speed             float64 // m/s
+	RemainingDistance float64 // meters to NextStationID
+	Waiting           bool    // stopped at the end of a segment, waiting for entry
+	WaitingSince      time.Duration
+	BlockedSegmentID  string // segment waited for, empty when waiting for NextStationID
+}
+
+func (StatusReport) isMessage() {}
+
+func (d *Dispatcher) handleStatusReport(report StatusReport) {
+	d.statuses[report.TrainID] = report
+}
+
+type PlanRequest struct {
+	Time       time.Duration
+	ResponseCh chan PlanResponse
+}
+
+func (PlanRequest) isMessage() {}
+
+type PlanResponse struct {
+	Conflicts int
+	Error     error
+}
+
+func (d *Dispatcher) handlePlanRequest(req PlanRequest) {
+	plan := d.planner.Plan(d.view(req.Time))
+	d.conflicts += plan.Conflicts
+	d.issue(plan)
+
+	req.ResponseCh <- PlanResponse{Conflicts: plan.Conflicts, Error: nil}
+}
+
+type ReportRequest struct {
+	ResponseCh chan Report
+}
+
+func (ReportRequest) isMessage() {}
+
+type Report struct {
+	Conflicts int
+	Orders    map[string]int // order kind -> orders issued
+}
+
+func (d *Dispatcher) handleReportRequest(req ReportRequest) {
+	orders := make(map[string]int, len(d.issued))
+	for kind, n := range d.issued {
+		orders[kind] = n
+	}
+
+	req.ResponseCh <- Report{Conflicts: d.conflicts, Orders: orders}
+}
+
+func (r Report) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"conflicts": r.Conflicts,
+		"orders":    r.Orders,
+	})
+}
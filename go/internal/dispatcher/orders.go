@@ -0,0 +1,44 @@
+package dispatcher
+
+import "time"
+
+// Order is an instruction sent by the dispatcher to a train. Trains read their
+// orders at the beginning of each tick.
+type Order interface {
+	isOrder()
+}
+
+// HoldOrder keeps a train at its current station until Until.
+type HoldOrder struct {
+	Until time.Duration
+}
+
+func (HoldOrder) isOrder() {}
+
+// SpeedOrder caps the speed of a train running on a segment until Until.
+type SpeedOrder struct {
+	MaxSpeed float64 // m/s
+	Until    time.Duration
+}
+
+func (SpeedOrder) isOrder() {}
+
+// RerouteOrder asks a train blocked in front of a segment for a new path to
+// its next stop that keeps off that segment.
+type RerouteOrder struct{}
+
+func (RerouteOrder) isOrder() {}
+
+// ClosureNotice announces that a segment closed. A running train whose path
+// crosses it asks for another path from the end of its current segment.
+type ClosureNotice struct {
+	SegmentID string
+}
+
+func (ClosureNotice) isOrder() {}
+
+// CancelOrder takes a train out of service: it terminates at its current
+// station, or leaves the line at once when running.
+type CancelOrder struct{}
+
+func (CancelOrder) isOrder() {}
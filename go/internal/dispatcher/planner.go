@@ -0,0 +1,248 @@
+package dispatcher
+
+import (
+	"ai30-project/internal/constants"
+	"ai30-project/internal/plugins"
+	"math"
+	"sort"
+	"time"
+)
+
+// View is the global snapshot of the network the planner works on.
+type View struct {
+	Time       time.Duration
+	Horizon    time.Duration
+	Trains     []StatusReport // sorted by train ID
+	Capacities map[string]int // station ID -> capacity
+}
+
+// Plan is what the planner decides for the next tick.
+type Plan struct {
+	Conflicts     int
+	TrainOrders   map[string][]Order
+	StationOrders map[string][]string // station ID -> admission order
+}
+
+func newPlan() Plan {
+	return Plan{
+		TrainOrders:   make(map[string][]Order),
+		StationOrders: make(map[string][]string),
+	}
+}
+
+type Planner interface {
+	Plan(view *View) Plan
+}
+
+// eta estimates when a train running on a segment reaches its next stop.
+func eta(train StatusReport) time.Duration {
+	if train.Waiting || train.RemainingDistance <= 0 {
+		return 0
+	}
+	speed := math.Max(train.Speed, 1.0)
+	return time.Duration(train.RemainingDistance / speed * float64(time.Second))
+}
+
+// stationLoad groups, for one station, the trains in it and those expected
+// within the horizon.
+type stationLoad struct {
+	occupants   []StatusReport
+	approaching []StatusReport
+}
+
+func loads(view *View) map[string]*stationLoad {
+	byStation := make(map[string]*stationLoad)
+	get := func(id string) *stationLoad {
+		if byStation[id] == nil {
+			byStation[id] = &stationLoad{}
+		}
+		return byStation[id]
+	}
+
+	for _, train := range view.Trains {
+		switch {
+		case train.AtStation:
+			get(train.StationID).occupants = append(get(train.StationID).occupants, train)
+		case train.NextStationID != "" && eta(train) <= view.Horizon:
+			get(train.NextStationID).approaching = append(get(train.NextStationID).approaching, train)
+		}
+	}
+
+	return byStation
+}
+
+// ConflictPlanner resolves predicted station capacity conflicts by giving
+// priority to the most delayed trains, slowing down the others so they do not
+// stop in front of a full station, and holding departures towards saturated
+// stations. It also smooths segment headways by matching the speed of trains
+// catching up with the one ahead, and reroutes trains blocked in front of a
+// segment for longer than the horizon. With CancelAfter set, it also cancels
+// the trains still blocked in front of a segment after that long.
+type ConflictPlanner struct {
+	CancelAfter time.Duration // 0 never cancels
+}
+
+func (p ConflictPlanner) Plan(view *View) Plan {
+	plan := newPlan()
+	byStation := loads(view)
+	saturated := make(map[string]bool)
+
+	stationIDs := make([]string, 0, len(byStation))
+	for id := range byStation {
+		stationIDs = append(stationIDs, id)
+	}
+	sort.Strings(stationIDs)
+
+	for _, stationID := range stationIDs {
+		load := byStation[stationID]
+		free := view.Capacities[stationID] - len(load.occupants)
+		if len(load.approaching) <= free {
+			continue
+		}
+
+		plan.Conflicts++
+		saturated[stationID] = true
+
+		approaching := load.approaching
+		sort.SliceStable(approaching, func(i, j int) bool {
+			if approaching[i].Delay != approaching[j].Delay {
+				return approaching[i].Delay > approaching[j].Delay
+			}
+			return eta(approaching[i]) < eta(approaching[j])
+		})
+
+		order := make([]string, len(approaching))
+		for i, train := range approaching {
+			order[i] = train.TrainID
+		}
+		plan.StationOrders[stationID] = order
+
+		// The trains that will not get a slot slow down to reach the
+		// station when the first occupant is scheduled to leave.
+		slotFreesIn := view.Horizon
+		for _, occupant := range load.occupants {
+			if wait := occupant.ScheduledDeparture - view.Time; wait < slotFreesIn {
+				slotFreesIn = wait
+			}
+		}
+		slotFreesIn = max(slotFreesIn, time.Minute)
+
+		for _, train := range approaching[max(free, 0):] {
+			if train.Waiting || eta(train) >= slotFreesIn {
+				continue
+			}
+			plan.TrainOrders[train.TrainID] = append(plan.TrainOrders[train.TrainID], SpeedOrder{
+				MaxSpeed: math.Max(train.RemainingDistance/slotFreesIn.Seconds(), constants.MinDispatchedSpeed),
+				Until:    view.Time + time.Minute,
+			})
+		}
+	}
+
+	// Departures towards a saturated station are held, unless leaving frees a
+	// slot in a saturated station too (holding would then lock both
+	// stations) or the train has already been held for the whole horizon.
+	for _, train := range view.Trains {
+		if train.AtStation && saturated[train.NextStationID] && !saturated[train.StationID] &&
+			train.ScheduledDeparture <= view.Time && train.Delay < view.Horizon &&
+			train.ScheduledArrival-view.Time <= view.Horizon {
+			plan.TrainOrders[train.TrainID] = append(plan.TrainOrders[train.TrainID], HoldOrder{Until: view.Time + time.Minute})
+		}
+	}
+
+	// Trains waiting for their next station to free a slot have no detour
+	for _, train := range view.Trains {
+		if !train.Waiting || train.BlockedSegmentID == "" {
+			continue
+		}
+		switch waited := view.Time - train.WaitingSince; {
+		case p.CancelAfter > 0 && waited >= p.CancelAfter:
+			plan.TrainOrders[train.TrainID] = append(plan.TrainOrders[train.TrainID], CancelOrder{})
+		case waited >= view.Horizon:
+			plan.TrainOrders[train.TrainID] = append(plan.TrainOrders[train.TrainID], RerouteOrder{})
+		}
+	}
+
+	matchLeaderSpeeds(view, plan)
+	return plan
+}
+
+// matchLeaderSpeeds orders trains closing in on the train ahead on the same
+// segment to run at its speed.
+func matchLeaderSpeeds(view *View, plan Plan) {
+	bySegment := make(map[string][]StatusReport)
+	for _, train := range view.Trains {
+		if !train.AtStation && train.SegmentID != "" {
+			bySegment[train.SegmentID] = append(bySegment[train.SegmentID], train)
+		}
+	}
+
+	for _, onSegment := range bySegment {
+		sort.Slice(onSegment, func(i, j int) bool {
+			return onSegment[i].Position > onSegment[j].Position
+		})
+		for i := 1; i < len(onSegment); i++ {
+			leader, follower := onSegment[i-1], onSegment[i]
+			gap := leader.Position - follower.Position
+			if gap < 2*constants.SafetyDistance && follower.Speed > math.Max(leader.Speed, constants.MinDispatchedSpeed) {
+				plan.TrainOrders[follower.TrainID] = append(plan.TrainOrders[follower.TrainID], SpeedOrder{
+					MaxSpeed: math.Max(leader.Speed, constants.MinDispatchedSpeed),
+					Until:    view.Time + time.Minute,
+				})
+			}
+		}
+	}
+}
+
+// FirstComeFirstServedPlanner only detects station conflicts and makes the
+// stations admit trains in order of predicted arrival, network-wide.
+type FirstComeFirstServedPlanner struct{}
+
+func (FirstComeFirstServedPlanner) Plan(view *View) Plan {
+	plan := newPlan()
+
+	for stationID, load := range loads(view) {
+		if len(load.approaching) <= view.Capacities[stationID]-len(load.occupants) {
+			continue
+		}
+
+		plan.Conflicts++
+		approaching := load.approaching
+		sort.SliceStable(approaching, func(i, j int) bool {
+			return eta(approaching[i]) < eta(approaching[j])
+		})
+
+		order := make([]string, len(approaching))
+		for i, train := range approaching {
+			order[i] = train.TrainID
+		}
+		plan.StationOrders[stationID] = order
+	}
+
+	return plan
+}
+
+// Planners are the planners the dispatcher can work with, by name.
+var Planners = plugins.NewRegistry[Planner]("planner")
+
+func init() {
+	Planners.Register(plugins.Plugin[Planner]{
+		Name:        "conflict",
+		Description: "gives priority to the most delayed trains at saturated stations, holds and slows down the others and reroutes trains blocked in front of a segment",
+		Params: []plugins.Param{
+			{Name: "cancelAfterMin", Type: plugins.Number, Description: "minutes a train blocked in front of a segment waits before it is cancelled, 0 never cancels", Default: 0.0, Min: 0, Max: 1440},
+		},
+		New: func(params plugins.Values) (Planner, error) {
+			return ConflictPlanner{CancelAfter: time.Duration(params.Number("cancelAfterMin") * float64(time.Minute))}, nil
+		},
+	})
+	Planners.Register(plugins.Plugin[Planner]{
+		Name:        "fcfs",
+		Description: "stations admit the trains in order of predicted arrival",
+		New:         func(plugins.Values) (Planner, error) { return FirstComeFirstServedPlanner{}, nil },
+	})
+}
+
+// NewPlanner builds a registered planner from its parameters.
+func NewPlanner(name string, params map[string]any) (Planner, error) {
+	return Planners.New(name, params)
+}
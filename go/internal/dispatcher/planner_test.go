@@ -0,0 +1,105 @@
+package dispatcher
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const now = 8 * time.Hour
+
+func view(capacities map[string]int, trains ...StatusReport) *View {
+	return &View{Time: now, Horizon: 15 * time.Minute, Trains: trains, Capacities: capacities}
+}
+
+// stationConflict has two trains due at B, which has room for one: EARLY in
+// two minutes and LATE, ten minutes late, in four. READY is about to leave A
+// for B.
+func stationConflict() *View {
+	return view(map[string]int{"A": 2, "B": 1},
+		StatusReport{TrainID: "EARLY", SegmentID: "A-B", NextStationID: "B", RemainingDistance: 6000, Speed: 50},
+		StatusReport{TrainID: "LATE", SegmentID: "D-B", NextStationID: "B", RemainingDistance: 12000, Speed: 50, Delay: 10 * time.Minute},
+		StatusReport{TrainID: "READY", AtStation: true, StationID: "A", ScheduledDeparture: now, NextStationID: "B", ScheduledArrival: now + 10*time.Minute},
+	)
+}
+
+func TestConflictPlannerOrdersStation(t *testing.T) {
+	plan := ConflictPlanner{}.Plan(stationConflict())
+
+	if plan.Conflicts != 1 {
+		t.Errorf("%d conflicts, want 1", plan.Conflicts)
+	}
+	if want := []string{"LATE", "EARLY"}; !reflect.DeepEqual(plan.StationOrders["B"], want) {
+		t.Errorf("B admits %v, want the most delayed first %v", plan.StationOrders["B"], want)
+	}
+
+	// EARLY cannot reach B in time for the slot freed past the horizon: it
+	// slows down to the lowest speed the dispatcher orders. READY is held.
+	want := map[string][]Order{
+		"EARLY": {SpeedOrder{MaxSpeed: 15, Until: now + time.Minute}},
+		"READY": {HoldOrder{Until: now + time.Minute}},
+	}
+	if !reflect.DeepEqual(plan.TrainOrders, want) {
+		t.Errorf("orders %v, want %v", plan.TrainOrders, want)
+	}
+}
+
+func TestConflictPlannerMatchesLeaderSpeed(t *testing.T) {
+	plan := ConflictPlanner{}.Plan(view(map[string]int{"B": 2},
+		StatusReport{TrainID: "LEADER", SegmentID: "A-B", NextStationID: "B", Position: 10000, RemainingDistance: 20000, Speed: 20},
+		StatusReport{TrainID: "FOLLOWER", SegmentID: "A-B", NextStationID: "B", Position: 4000, RemainingDistance: 26000, Speed: 40},
+	))
+
+	want := map[string][]Order{"FOLLOWER": {SpeedOrder{MaxSpeed: 20, Until: now + time.Minute}}}
+	if !reflect.DeepEqual(plan.TrainOrders, want) {
+		t.Errorf("orders %v, want the follower at the leader's speed %v", plan.TrainOrders, want)
+	}
+}
+
+// TestConflictPlannerReroutesBlockedTrains checks only the trains waiting in
+// front of a segment for longer than the horizon are rerouted, and cancelled
+// only when asked to.
+func TestConflictPlannerReroutesBlockedTrains(t *testing.T) {
+	blocked := view(map[string]int{"C": 2, "D": 2},
+		StatusReport{TrainID: "BLOCKED", SegmentID: "A-B", NextStationID: "C", Waiting: true, WaitingSince: now - 20*time.Minute, BlockedSegmentID: "B-C"},
+		StatusReport{TrainID: "RECENT", SegmentID: "E-B", NextStationID: "C", Waiting: true, WaitingSince: now - 5*time.Minute, BlockedSegmentID: "B-C"},
+		StatusReport{TrainID: "QUEUED", SegmentID: "B-D", NextStationID: "D", Waiting: true, WaitingSince: now - 20*time.Minute},
+	)
+
+	if got, want := (ConflictPlanner{}).Plan(blocked).TrainOrders, map[string][]Order{"BLOCKED": {RerouteOrder{}}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("orders %v, want %v", got, want)
+	}
+	if got, want := (ConflictPlanner{CancelAfter: 20 * time.Minute}).Plan(blocked).TrainOrders, map[string][]Order{"BLOCKED": {CancelOrder{}}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("orders %v cancelling after 20 minutes, want %v", got, want)
+	}
+}
+
+func TestFirstComeFirstServedPlanner(t *testing.T) {
+	plan := FirstComeFirstServedPlanner{}.Plan(stationConflict())
+
+	if plan.Conflicts != 1 {
+		t.Errorf("%d conflicts, want 1", plan.Conflicts)
+	}
+	if want := []string{"EARLY", "LATE"}; !reflect.DeepEqual(plan.StationOrders["B"], want) {
+		t.Errorf("B admits %v, want the first due first %v", plan.StationOrders["B"], want)
+	}
+	if len(plan.TrainOrders) != 0 {
+		t.Errorf("orders %v, want none", plan.TrainOrders)
+	}
+}
+
+func TestNewPlanner(t *testing.T) {
+	planner, err := NewPlanner("conflict", map[string]any{"cancelAfterMin": "30"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (ConflictPlanner{CancelAfter: 30 * time.Minute}); planner != want {
+		t.Errorf("planner %+v, want %+v", planner, want)
+	}
+	if planner, _ := NewPlanner("conflict", nil); planner != (ConflictPlanner{}) {
+		t.Errorf("default planner %+v cancels trains", planner)
+	}
+	if _, err := NewPlanner("fifo", nil); err == nil {
+		t.Error("unknown planner accepted")
+	}
+}
@@ -0,0 +1,138 @@
+package disruptions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"ai30-project/internal/clock"
+	"ai30-project/internal/constants"
+)
+
+type Kind string
+
+const (
+	SegmentBlocked   Kind = "segment_blocked"
+	StationCapacity  Kind = "station_capacity"
+	SpeedRestriction Kind = "speed_restriction"
+)
+
+// Disruption is a scripted infrastructure failure affecting a segment or a
+// station during [Start, End). An End of zero means until the end of the run.
+type Disruption struct {
+	Kind   Kind
+	Target string // segment ID, or station ID for StationCapacity
+	Start  time.Duration
+	End    time.Duration
+
+	Capacity int     // StationCapacity: trains allowed in the station
+	MaxSpeed float64 // SpeedRestriction: m/s
+}
+
+func (d Disruption) IsActive(currentTime time.Duration) bool {
+	return currentTime >= d.Start && (d.End == 0 || currentTime < d.End)
+}
+
+// Validate checks the window and the setting of the disruption, whatever its
+// target.
+func (d Disruption) Validate() error {
+	if d.End != 0 && d.End <= d.Start {
+		return fmt.Errorf("end %v is not after start %v", d.End, d.Start)
+	}
+
+	switch d.Kind {
+	case SegmentBlocked:
+	case StationCapacity:
+		if d.Capacity < 0 {
+			return fmt.Errorf("capacity cannot be negative")
+		}
+	case SpeedRestriction:
+		if d.MaxSpeed <= 0 {
+			return fmt.Errorf("max speed must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown kind %q", d.Kind)
+	}
+	return nil
+}
+
+func (d Disruption) MarshalJSON() ([]byte, error) {
+	data := map[string]any{
+		"kind":      d.Kind,
+		"target":    d.Target,
+		"startTime": d.Start,
+		"endTime":   d.End,
+	}
+	switch d.Kind {
+	case StationCapacity:
+		data["capacity"] = d.Capacity
+	case SpeedRestriction:
+		data["maxSpeed"] = d.MaxSpeed
+	}
+	return json.Marshal(data)
+}
+
+type disruptionEntry struct {
+	Kind        Kind    `json:"kind"`
+	Target      string  `json:"target"`
+	Start       string  `json:"start"`
+	End         string  `json:"end"`
+	Capacity    int     `json:"capacity"`
+	MaxSpeedKmH float64 `json:"maxSpeedKmH"`
+}
+
+// Parse reads a list of disruptions in JSON:
+//
+//	[{"kind": "segment_blocked", "target": "<segment>", "start": "10:00", "end": "11:30"},
+//	 {"kind": "station_capacity", "target": "<station>", "start": "08:00", "capacity": 2},
+//	 {"kind": "speed_restriction", "target": "<segment>", "start": "06:00", "end": "12:00", "maxSpeedKmH": 80}]
+func Parse(raw []byte) ([]Disruption, error) {
+	var entries []disruptionEntry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("parsing disruptions: %w", err)
+	}
+
+	disruptions := make([]Disruption, 0, len(entries))
+	for i, e := range entries {
+		d := Disruption{Kind: e.Kind, Target: e.Target}
+		if d.Target == "" {
+			return nil, fmt.Errorf("disruption %d: target is required", i)
+		}
+
+		var err error
+		if d.Start, err = clock.Parse(e.Start); err != nil {
+			return nil, fmt.Errorf("disruption %d: %w", i, err)
+		}
+		if e.End != "" {
+			if d.End, err = clock.Parse(e.End); err != nil {
+				return nil, fmt.Errorf("disruption %d: %w", i, err)
+			}
+			if d.End <= d.Start {
+				return nil, fmt.Errorf("disruption %d: end %s is not after start %s", i, e.End, e.Start)
+			}
+		}
+
+		switch d.Kind {
+		case StationCapacity:
+			d.Capacity = e.Capacity
+		case SpeedRestriction:
+			d.MaxSpeed = e.MaxSpeedKmH * constants.KmH_to_MS
+		}
+		if err := d.Validate(); err != nil {
+			return nil, fmt.Errorf("disruption %d: %w", i, err)
+		}
+
+		disruptions = append(disruptions, d)
+	}
+
+	return disruptions, nil
+}
+
+func LoadDisruptions(path string) ([]Disruption, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading disruptions file: %w", err)
+	}
+	return Parse(raw)
+}
@@ -0,0 +1,89 @@
+package disruptions
+
+import (
+	"testing"
+	"time"
+
+	"ai30-project/internal/constants"
+)
+
+func TestParse(t *testing.T) {
+	list, err := Parse([]byte(`[
+		{"kind": "segment_blocked", "target": "A-B", "start": "10:00", "end": "11:30"},
+		{"kind": "station_capacity", "target": "B", "start": "08:00", "capacity": 0},
+		{"kind": "speed_restriction", "target": "B-C", "start": "06:00", "end": "12:00", "maxSpeedKmH": 80}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Disruption{
+		{Kind: SegmentBlocked, Target: "A-B", Start: 10 * time.Hour, End: 11*time.Hour + 30*time.Minute},
+		{Kind: StationCapacity, Target: "B", Start: 8 * time.Hour},
+		{Kind: SpeedRestriction, Target: "B-C", Start: 6 * time.Hour, End: 12 * time.Hour, MaxSpeed: 80 * constants.KmH_to_MS},
+	}
+	if len(list) != len(want) {
+		t.Fatalf("%d disruptions, want %d", len(list), len(want))
+	}
+	for i := range want {
+		if list[i] != want[i] {
+			t.Errorf("disruption %d: %+v, want %+v", i, list[i], want[i])
+		}
+	}
+
+	for _, invalid := range []string{
+		`[{"kind": "segment_blocked", "start": "10:00"}]`,
+		`[{"kind": "segment_blocked", "target": "A-B", "start": "10h"}]`,
+		`[{"kind": "segment_blocked", "target": "A-B", "start": "10:00", "end": "10:00"}]`,
+		`[{"kind": "station_capacity", "target": "B", "start": "08:00", "capacity": -1}]`,
+		`[{"kind": "speed_restriction", "target": "B-C", "start": "06:00"}]`,
+		`[{"kind": "flood", "target": "A-B", "start": "10:00"}]`,
+		`{}`,
+	} {
+		if _, err := Parse([]byte(invalid)); err == nil {
+			t.Errorf("disruptions %s accepted", invalid)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		disruption Disruption
+		valid      bool
+	}{
+		{Disruption{Kind: SegmentBlocked, Start: 8 * time.Hour}, true},
+		{Disruption{Kind: SegmentBlocked, Start: 8 * time.Hour, End: 9 * time.Hour}, true},
+		{Disruption{Kind: SegmentBlocked, Start: 8 * time.Hour, End: 8 * time.Hour}, false},
+		{Disruption{Kind: SegmentBlocked, Start: 8 * time.Hour, End: 7 * time.Hour}, false},
+		{Disruption{Kind: StationCapacity, Capacity: 0}, true},
+		{Disruption{Kind: StationCapacity, Capacity: -1}, false},
+		{Disruption{Kind: SpeedRestriction, MaxSpeed: 10}, true},
+		{Disruption{Kind: SpeedRestriction, MaxSpeed: 0}, false},
+		{Disruption{Kind: SpeedRestriction, MaxSpeed: -10}, false},
+		{Disruption{Kind: "flood"}, false},
+	}
+	for _, c := range cases {
+		if err := c.disruption.Validate(); (err == nil) != c.valid {
+			t.Errorf("%+v: error %v, want valid %v", c.disruption, err, c.valid)
+		}
+	}
+}
+
+func TestIsActive(t *testing.T) {
+	window := Disruption{Kind: SegmentBlocked, Start: 8 * time.Hour, End: 9 * time.Hour}
+	for at, want := range map[time.Duration]bool{
+		7*time.Hour + 59*time.Minute: false,
+		8 * time.Hour:                true,
+		8*time.Hour + 59*time.Minute: true,
+		9 * time.Hour:                false,
+	} {
+		if got := window.IsActive(at); got != want {
+			t.Errorf("active at %v: %v, want %v", at, got, want)
+		}
+	}
+
+	untilEnd := Disruption{Kind: SegmentBlocked, Start: 8 * time.Hour}
+	if !untilEnd.IsActive(23 * time.Hour) {
+		t.Error("disruption without end lifted")
+	}
+}
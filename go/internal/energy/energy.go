@@ -0,0 +1,136 @@
+// Package energy turns the running of trains into the electrical energy they
+// draw from the line, net of what regenerative braking feeds back.
+package energy
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"time"
+
+	"ai30-project/internal/constants"
+)
+
+const joulesPerKWh = 3.6e6
+
+// Model is the traction of a train.
+type Model struct {
+	Mass          float64 // kg, empty train
+	PassengerMass float64 // kg per passenger on board
+	Resistance    float64 // m/s², running resistance, positive
+	Efficiency    float64 // share of the energy drawn reaching the wheels
+	Regeneration  float64 // share of the braking energy fed back to the line
+}
+
+// DefaultModel is a 400 t electric trainset recovering 60% of its braking
+// energy. Its running resistance is the deceleration of a coasting train.
+func DefaultModel() Model {
+	return Model{
+		Mass:          400_000,
+		PassengerMass: 80,
+		Resistance:    -constants.CoastingDeceleration,
+		Efficiency:    0.85,
+		Regeneration:  0.6,
+	}
+}
+
+// Usage is energy drawn for traction and regenerated while braking, in joules.
+type Usage struct {
+	Traction    float64
+	Regenerated float64
+}
+
+// Net is the energy taken from the line.
+func (u Usage) Net() float64 {
+	return u.Traction - u.Regenerated
+}
+
+func (u Usage) Add(other Usage) Usage {
+	return Usage{Traction: u.Traction + other.Traction, Regenerated: u.Regenerated + other.Regenerated}
+}
+
+func (u Usage) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"tractionKWh":    roundedKWh(u.Traction),
+		"regeneratedKWh": roundedKWh(u.Regenerated),
+		"netKWh":         roundedKWh(u.Net()),
+	})
+}
+
+// KWh converts joules to kilowatt-hours.
+func KWh(joules float64) float64 {
+	return joules / joulesPerKWh
+}
+
+// roundedKWh rounds to a tenth of a kilowatt-hour.
+func roundedKWh(joules float64) float64 {
+	return math.Round(KWh(joules)*10) / 10
+}
+
+// Step is the energy of running distance meters from speed from to speed to,
+// m/s, with passengers on board. The wheels must provide the change of
+// kinetic energy and overcome the running resistance: traction draws that
+// work through the efficiency of the drive, and brakes absorb what is left
+// when it is negative, part of it fed back to the line.
+func (m Model) Step(passengers int, from, to, distance float64) Usage {
+	mass := m.Mass + float64(passengers)*m.PassengerMass
+	work := mass*(to*to-from*from)/2 + mass*m.Resistance*distance
+
+	if work >= 0 {
+		return Usage{Traction: work / m.Efficiency}
+	}
+	return Usage{Regenerated: -work * m.Regeneration}
+}
+
+// Power is the mean traction power, in watts, of running dt from speed from
+// to speed to, at the speed reached.
+func (m Model) Power(passengers int, from, to float64, dt time.Duration) float64 {
+	return m.Step(passengers, from, to, to*dt.Seconds()).Traction / dt.Seconds()
+}
+
+// Report is the energy of the run, by train, driver behaviour and segment.
+type Report struct {
+	total    Usage
+	trains   map[string]Usage
+	drivers  map[string]Usage
+	segments map[string]Usage
+}
+
+func NewReport() Report {
+	return Report{
+		trains:   make(map[string]Usage),
+		drivers:  make(map[string]Usage),
+		segments: make(map[string]Usage),
+	}
+}
+
+// Add books the usage of a train, by segment it ran on.
+func (r *Report) Add(trainID, driver string, bySegment map[string]Usage) {
+	// Segments in order, so that sums do not depend on map order
+	segmentIDs := make([]string, 0, len(bySegment))
+	for segmentID := range bySegment {
+		segmentIDs = append(segmentIDs, segmentID)
+	}
+	sort.Strings(segmentIDs)
+
+	for _, segmentID := range segmentIDs {
+		usage := bySegment[segmentID]
+		r.total = r.total.Add(usage)
+		r.trains[trainID] = r.trains[trainID].Add(usage)
+		r.drivers[driver] = r.drivers[driver].Add(usage)
+		r.segments[segmentID] = r.segments[segmentID].Add(usage)
+	}
+}
+
+func (r Report) Total() Usage {
+	return r.total
+}
+
+func (r Report) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"total":    r.total,
+		"trains":   r.trains,
+		"drivers":  r.drivers,
+		"segments": r.segments,
+	})
+}
@@ -0,0 +1,100 @@
+package energy
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-6*math.Max(1, math.Abs(b))
+}
+
+// model is a 100 t train, with 100 passengers of 100 kg, running without
+// resistance through a drive of 80% efficiency and recovering half of its
+// braking energy.
+var model = Model{Mass: 90_000, PassengerMass: 100, Efficiency: 0.8, Regeneration: 0.5}
+
+func TestStep(t *testing.T) {
+	// Reaching 20 m/s takes 20 MJ at the wheels, 25 MJ from the line
+	if got := model.Step(100, 0, 20, 200); !near(got.Traction, 25e6) || got.Regenerated != 0 {
+		t.Errorf("accelerating: %+v, want 25 MJ of traction", got)
+	}
+	// Braking from 20 m/s feeds half of 20 MJ back
+	if got := model.Step(100, 20, 0, 200); got.Traction != 0 || !near(got.Regenerated, 10e6) {
+		t.Errorf("braking: %+v, want 10 MJ regenerated", got)
+	}
+	if got := model.Step(100, 20, 20, 1000); got != (Usage{}) {
+		t.Errorf("cruising without resistance: %+v, want nothing", got)
+	}
+
+	// Cruising against 0.01 m/s² over 1 km takes 1 MJ at the wheels
+	resisted := model
+	resisted.Resistance = 0.01
+	if got := resisted.Step(100, 20, 20, 1000); !near(got.Traction, 1.25e6) {
+		t.Errorf("cruising: %+v, want 1.25 MJ of traction", got)
+	}
+	// Coasting down from 20 to 10 m/s over 1 km: the resistance takes 1 MJ
+	// of the 15 MJ of kinetic energy lost, the brakes the rest
+	if got := resisted.Step(100, 20, 10, 1000); !near(got.Regenerated, 7e6) {
+		t.Errorf("slowing down: %+v, want 7 MJ regenerated", got)
+	}
+
+	// Empty, the train needs 10% less
+	if got := model.Step(0, 0, 20, 200); !near(got.Traction, 22.5e6) {
+		t.Errorf("accelerating empty: %+v, want 22.5 MJ of traction", got)
+	}
+}
+
+func TestPower(t *testing.T) {
+	// 25 MJ over 100 s
+	if got := model.Power(100, 0, 20, 100*time.Second); !near(got, 250e3) {
+		t.Errorf("power %v W, want 250 kW", got)
+	}
+	if got := model.Power(100, 20, 0, 100*time.Second); got != 0 {
+		t.Errorf("power %v W while braking, want none", got)
+	}
+}
+
+func TestReport(t *testing.T) {
+	report := NewReport()
+	report.Add("T1", "eco", map[string]Usage{"A-B": {Traction: 10 * joulesPerKWh, Regenerated: 2 * joulesPerKWh}, "B-C": {Traction: 5 * joulesPerKWh}})
+	report.Add("T2", "eco", map[string]Usage{"A-B": {Traction: 20 * joulesPerKWh, Regenerated: 4 * joulesPerKWh}})
+
+	if got, want := report.Total(), (Usage{Traction: 35 * joulesPerKWh, Regenerated: 6 * joulesPerKWh}); got != want {
+		t.Errorf("total %+v, want %+v", got, want)
+	}
+	if got := KWh(report.Total().Net()); got != 29 {
+		t.Errorf("net %v kWh, want 29", got)
+	}
+
+	raw, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Trains   map[string]map[string]float64 `json:"trains"`
+		Drivers  map[string]map[string]float64 `json:"drivers"`
+		Segments map[string]map[string]float64 `json:"segments"`
+	}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	checks := []struct {
+		what string
+		got  map[string]float64
+		want float64
+	}{
+		{"T1", decoded.Trains["T1"], 13},
+		{"T2", decoded.Trains["T2"], 16},
+		{"eco", decoded.Drivers["eco"], 29},
+		{"A-B", decoded.Segments["A-B"], 24},
+		{"B-C", decoded.Segments["B-C"], 5},
+	}
+	for _, c := range checks {
+		if c.got["netKWh"] != c.want {
+			t.Errorf("%s: %v, want %v kWh net", c.what, c.got, c.want)
+		}
+	}
+}
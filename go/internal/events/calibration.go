@@ -0,0 +1,225 @@
+package events
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"ai30-project/internal/clock"
+)
+
+// DefaultDelayThreshold is the usual punctuality threshold, in minutes.
+const DefaultDelayThreshold = 5.0
+
+// Record is one historical train run.
+type Record struct {
+	Profile
+	DelayMinutes float64
+	Cancelled    bool
+	Cause        DelayCause // empty when unknown or on time
+}
+
+// ReadRecords parses punctuality records from a CSV with a header row. The
+// delay_minutes and cancelled columns are required; line, category,
+// departure (HH:MM) and cause are optional. Without line or category columns,
+// they are read from a train_id column holding SNCF train IDs.
+func ReadRecords(r io.Reader) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"delay_minutes", "cancelled"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing column %s", required)
+		}
+	}
+
+	field := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var records []Record
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		var record Record
+		if delay := field(row, "delay_minutes"); delay != "" {
+			if record.DelayMinutes, err = strconv.ParseFloat(delay, 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid delay_minutes %q", line, delay)
+			}
+		}
+		switch strings.ToLower(field(row, "cancelled")) {
+		case "1", "true", "yes":
+			record.Cancelled = true
+		case "", "0", "false", "no":
+		default:
+			return nil, fmt.Errorf("line %d: invalid cancelled %q", line, field(row, "cancelled"))
+		}
+		if departure := field(row, "departure"); departure != "" {
+			if record.Departure, err = clock.Parse(departure); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		record.Cause = DelayCause(strings.ToLower(field(row, "cause")))
+		if record.Cause != "" && !isKnownCause(record.Cause) {
+			return nil, fmt.Errorf("line %d: unknown cause %q", line, record.Cause)
+		}
+
+		trainID := field(row, "train_id")
+		if record.Line = field(row, "line"); record.Line == "" {
+			record.Line = LineOf(trainID)
+		}
+		if record.Category = field(row, "category"); record.Category == "" {
+			record.Category = CategoryOf(trainID)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func isKnownCause(cause DelayCause) bool {
+	for _, known := range DelayCauses {
+		if cause == known {
+			return true
+		}
+	}
+	return false
+}
+
+// LineOf extracts the line identifier from an SNCF train ID such as
+// "OCESN6101F1187_F:OUI:FR:Line::BAF1DE9A-...::87686006:...".
+func LineOf(trainID string) string {
+	_, rest, found := strings.Cut(trainID, ":Line::")
+	if !found {
+		return ""
+	}
+	line, _, _ := strings.Cut(rest, "::")
+	return line
+}
+
+// CategoryOf extracts the commercial category (OUI, OGO...) from an SNCF
+// train ID.
+func CategoryOf(trainID string) string {
+	parts := strings.Split(trainID, ":")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[1]
+}
+
+// Fit estimates a model from records. A run is delayed when it arrives at
+// least threshold minutes late and was not cancelled; the lognormal
+// parameters are those of the delays of delayed runs.
+func Fit(records []Record, threshold float64) (Model, error) {
+	if len(records) == 0 {
+		return Model{}, fmt.Errorf("no records")
+	}
+
+	model := Model{
+		CauseProbabilities: make(map[DelayCause]float64),
+		Records:            len(records),
+	}
+
+	var logDelays []float64
+	causes := 0
+	for _, record := range records {
+		if record.Cancelled {
+			model.ProportionCancellation++
+			continue
+		}
+		if record.DelayMinutes < threshold || record.DelayMinutes <= 0 {
+			continue
+		}
+
+		logDelays = append(logDelays, math.Log(record.DelayMinutes))
+		if record.Cause != "" {
+			model.CauseProbabilities[record.Cause]++
+			causes++
+		}
+	}
+
+	total := float64(len(records))
+	model.ProportionCancellation /= total
+	model.ProportionDelay = float64(len(logDelays)) / total
+
+	for _, logDelay := range logDelays {
+		model.MuDelayed += logDelay
+	}
+	if len(logDelays) > 0 {
+		model.MuDelayed /= float64(len(logDelays))
+	}
+	for _, logDelay := range logDelays {
+		model.StdDelayed += (logDelay - model.MuDelayed) * (logDelay - model.MuDelayed)
+	}
+	if len(logDelays) > 1 {
+		model.StdDelayed = math.Sqrt(model.StdDelayed / float64(len(logDelays)-1))
+	}
+
+	// Without any known cause, keep the national breakdown
+	if causes == 0 {
+		model.CauseProbabilities = DefaultModel().CauseProbabilities
+	} else {
+		for cause := range model.CauseProbabilities {
+			model.CauseProbabilities[cause] /= float64(causes)
+		}
+	}
+
+	return model, model.validate()
+}
+
+// Calibrate fits a default model on every record and, when grouping, one
+// model per group with at least minRecords records. Smaller groups fall back
+// to the default model.
+func Calibrate(records []Record, groupBy GroupBy, threshold float64, minRecords int) (*ModelSet, error) {
+	defaultModel, err := Fit(records, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	ms := &ModelSet{Default: defaultModel, GroupBy: groupBy}
+	if groupBy == GroupByNone {
+		return ms, nil
+	}
+
+	groups := make(map[string][]Record)
+	for _, record := range records {
+		if key := record.key(groupBy); key != "" {
+			groups[key] = append(groups[key], record)
+		}
+	}
+
+	ms.Groups = make(map[string]Model)
+	for key, group := range groups {
+		if len(group) < minRecords {
+			continue
+		}
+		model, err := Fit(group, threshold)
+		if err != nil {
+			return nil, fmt.Errorf("group %s: %w", key, err)
+		}
+		ms.Groups[key] = model
+	}
+
+	return ms, nil
+}
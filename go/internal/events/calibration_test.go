@@ -0,0 +1,117 @@
+package events
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadRecords(t *testing.T) {
+	records, err := ReadRecords(strings.NewReader(`train_id,departure,delay_minutes,cancelled,cause
+OCESN1F1187_F:OUI:FR:Line::L1::1:2,07:58,18,false,External
+X,17:30,,TRUE,
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Record{
+		{Profile: Profile{Line: "L1", Category: "OUI", Departure: 7*time.Hour + 58*time.Minute}, DelayMinutes: 18, Cause: DelayCauseExternal},
+		{Profile: Profile{Departure: 17*time.Hour + 30*time.Minute}, Cancelled: true},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("%d records, want %d", len(records), len(want))
+	}
+	for i := range want {
+		if records[i] != want[i] {
+			t.Errorf("record %d: %+v, want %+v", i, records[i], want[i])
+		}
+	}
+
+	for _, invalid := range []string{
+		"delay_minutes\n5\n",
+		"delay_minutes,cancelled\nlate,false\n",
+		"delay_minutes,cancelled\n5,maybe\n",
+		"delay_minutes,cancelled,departure\n5,false,7h58\n",
+		"delay_minutes,cancelled,cause\n5,false,weather\n",
+	} {
+		if _, err := ReadRecords(strings.NewReader(invalid)); err == nil {
+			t.Errorf("records %q accepted", invalid)
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	records := []Record{
+		{DelayMinutes: 0},
+		{DelayMinutes: 3, Cause: DelayCauseTraffic}, // under the threshold
+		{DelayMinutes: 10, Cause: DelayCauseExternal},
+		{DelayMinutes: 40, Cause: DelayCauseStation},
+		{Cancelled: true},
+	}
+	model, err := Fit(records, DefaultDelayThreshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if model.ProportionDelay != 0.4 || model.ProportionCancellation != 0.2 || model.Records != 5 {
+		t.Errorf("model %+v, want 40%% delayed and 20%% cancelled out of 5 records", model)
+	}
+	if mu := (math.Log(10) + math.Log(40)) / 2; math.Abs(model.MuDelayed-mu) > 1e-9 {
+		t.Errorf("mu %v, want %v", model.MuDelayed, mu)
+	}
+	if std := math.Log(4) / math.Sqrt2; math.Abs(model.StdDelayed-std) > 1e-9 {
+		t.Errorf("std %v, want %v", model.StdDelayed, std)
+	}
+	want := map[DelayCause]float64{DelayCauseExternal: 0.5, DelayCauseStation: 0.5}
+	if len(model.CauseProbabilities) != len(want) || model.CauseProbabilities[DelayCauseExternal] != 0.5 || model.CauseProbabilities[DelayCauseStation] != 0.5 {
+		t.Errorf("causes %v, want %v", model.CauseProbabilities, want)
+	}
+
+	if _, err := Fit(nil, DefaultDelayThreshold); err == nil {
+		t.Error("model fitted without records")
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	var records []Record
+	for i := range 10 {
+		records = append(records, Record{Profile: Profile{Line: "BIG"}, DelayMinutes: float64(10 * (i % 2))})
+	}
+	records = append(records, Record{Profile: Profile{Line: "SMALL"}, Cancelled: true})
+
+	ms, err := Calibrate(records, GroupByLine, DefaultDelayThreshold, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ms.Groups["SMALL"]; ok {
+		t.Error("model fitted for a group under the minimum records")
+	}
+	if got := ms.For(Profile{Line: "BIG"}); got.ProportionDelay != 0.5 || got.Records != 10 {
+		t.Errorf("BIG model %+v, want half of 10 records delayed", got)
+	}
+	if got := ms.For(Profile{Line: "SMALL"}); got.Records != 11 {
+		t.Errorf("SMALL model %+v, want the default fitted on every record", got)
+	}
+}
+
+func TestLoadModelSetRejectsUnknownCauses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.json")
+	load := func(content string) error {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadModelSet(path)
+		return err
+	}
+
+	if err := load(`{"default": {"proportionDelay": 0.1, "causeProbabilities": {"external": 1}}}`); err != nil {
+		t.Errorf("valid model rejected: %v", err)
+	}
+	if err := load(`{"default": {"proportionDelay": 0.1, "causeProbabilities": {"weather": 1}}}`); err == nil {
+		t.Error("model with an unknown cause accepted")
+	}
+}
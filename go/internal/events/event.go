@@ -2,30 +2,20 @@ package events
 
 import (
 	"encoding/json"
-	"math"
-	"math/rand"
 	"time"
 )
 
+// Not fitted on data: rough orders of magnitude for the rarer events.
 const (
-	proportionDelay        = 0.0960013641401654
-	proportionCancellation = 0.03363966841008551
-
-	muDelayed  = 3.477863772291891
-	stdDelayed = 0.3791282825460146
+	shareShortTurned         = 0.4
+	proportionSkippedStop    = 0.01
+	proportionSpeedReduction = 0.03
+	reducedMaxSpeedKmH       = 100.0
 )
 
-var delayCauseProbabilities = map[DelayCause]float64{
-	DelayCauseExternal:       0.21873635169470653,
-	DelayCauseInfrastructure: 0.22102479850910456,
-	DelayCauseTraffic:        0.20142373232243163,
-	DelayCauseRollingStock:   0.19090507527285972,
-	DelayCauseStation:        0.07300637791365844,
-	DelayCausePassenger:      0.07681434828215401,
-}
-
 type Event interface {
 	isEvent()
+	Start() time.Duration
 }
 
 type DelayCause string
@@ -39,25 +29,79 @@ const (
 	DelayCausePassenger      DelayCause = "passenger"
 )
 
+// DelayCauses lists every cause in a fixed order.
+var DelayCauses = []DelayCause{
+	DelayCauseExternal,
+	DelayCauseInfrastructure,
+	DelayCauseTraffic,
+	DelayCauseRollingStock,
+	DelayCauseStation,
+	DelayCausePassenger,
+}
+
+// Effect is how an active delay hinders a train running between stations.
+// At a station, every delay keeps the train from departing.
+type Effect string
+
+const (
+	EffectServiceStop   Effect = "service_stop"   // stopped at a signal, normal braking
+	EffectEmergencyStop Effect = "emergency_stop" // obstacle on the line
+	EffectSlowdown      Effect = "slowdown"       // degraded running
+	EffectDwell         Effect = "dwell"          // only extends the stop
+)
+
+func (c DelayCause) Effect() Effect {
+	switch c {
+	case DelayCauseInfrastructure, DelayCauseTraffic:
+		return EffectServiceStop
+	case DelayCauseRollingStock:
+		return EffectSlowdown
+	case DelayCauseStation, DelayCausePassenger:
+		return EffectDwell
+	default:
+		return EffectEmergencyStop
+	}
+}
+
+// DelayEvent is anchored where its cause makes sense: station and passenger
+// causes extend the dwell at StationID, infrastructure causes stop every train
+// crossing SegmentID, other causes follow the train.
 type DelayEvent struct {
 	Cause     DelayCause
 	Duration  time.Duration
 	StartTime time.Duration
+	StationID string
+	SegmentID string
 }
 
 func (DelayEvent) isEvent() {}
 
+func (e DelayEvent) Start() time.Duration { return e.StartTime }
+
 func (e DelayEvent) IsActive(currentTime time.Duration) bool {
 	return currentTime >= e.StartTime && currentTime < e.StartTime+e.Duration
 }
 
+// FollowsTrain reports whether the delay hinders the train wherever it is,
+// rather than at a station or on a segment.
+func (e DelayEvent) FollowsTrain() bool {
+	return e.StationID == "" && e.SegmentID == ""
+}
+
 func (e DelayEvent) MarshalJSON() ([]byte, error) {
-	return json.Marshal(map[string]any{
+	data := map[string]any{
 		"kind":      "delay",
 		"cause":     e.Cause,
 		"duration":  e.Duration,
 		"startTime": e.StartTime,
-	})
+	}
+	if e.StationID != "" {
+		data["stationId"] = e.StationID
+	}
+	if e.SegmentID != "" {
+		data["segmentId"] = e.SegmentID
+	}
+	return json.Marshal(data)
 }
 
 type CancellationEvent struct {
@@ -66,6 +110,8 @@ type CancellationEvent struct {
 
 func (CancellationEvent) isEvent() {}
 
+func (e CancellationEvent) Start() time.Duration { return e.StartTime }
+
 func (e CancellationEvent) IsActive(currentTime time.Duration) bool {
 	return currentTime >= e.StartTime
 }
@@ -77,52 +123,74 @@ func (e CancellationEvent) MarshalJSON() ([]byte, error) {
 	})
 }
 
-type NoEvent struct{}
+// PartialCancellationEvent short-turns the train: from StartTime on, it
+// terminates at StationID and the rest of its journey is cancelled.
+type PartialCancellationEvent struct {
+	StationID string
+	StartTime time.Duration
+}
+
+func (PartialCancellationEvent) isEvent() {}
+
+func (e PartialCancellationEvent) Start() time.Duration { return e.StartTime }
+
+func (e PartialCancellationEvent) IsActive(currentTime time.Duration) bool {
+	return currentTime >= e.StartTime
+}
+
+func (e PartialCancellationEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"kind":      "partial_cancellation",
+		"stationId": e.StationID,
+		"startTime": e.StartTime,
+	})
+}
+
+// SkippedStopEvent makes the train run through StationID without stopping if
+// it gets there after StartTime.
+type SkippedStopEvent struct {
+	StationID string
+	StartTime time.Duration
+}
 
-func (NoEvent) isEvent() {}
+func (SkippedStopEvent) isEvent() {}
 
-func (e NoEvent) MarshalJSON() ([]byte, error) {
+func (e SkippedStopEvent) Start() time.Duration { return e.StartTime }
+
+func (e SkippedStopEvent) IsActive(currentTime time.Duration) bool {
+	return currentTime >= e.StartTime
+}
+
+func (e SkippedStopEvent) MarshalJSON() ([]byte, error) {
 	return json.Marshal(map[string]any{
-		"kind": "none",
+		"kind":      "skipped_stop",
+		"stationId": e.StationID,
+		"startTime": e.StartTime,
 	})
 }
 
-func GenerateEvent(firstDeparture, lastArrival time.Duration) Event {
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	roll := rng.Float64()
+// SpeedReductionEvent caps the train speed after a rolling-stock fault, for
+// Duration from StartTime.
+type SpeedReductionEvent struct {
+	MaxSpeed  float64 // m/s
+	Duration  time.Duration
+	StartTime time.Duration
+}
 
-	timeRange := lastArrival - firstDeparture
-	if timeRange < 0 {
-		timeRange = 0
-	}
-	randomOffset := time.Duration(float64(timeRange) * rng.Float64())
-	startTime := firstDeparture + randomOffset
-
-	if roll < proportionDelay {
-		logDelay := rng.NormFloat64()*stdDelayed + muDelayed
-		delayMinutes := math.Exp(logDelay)
-
-		causeRoll := rng.Float64()
-		cumulative := 0.0
-		var selectedCause DelayCause = DelayCauseExternal
-		for cause, prob := range delayCauseProbabilities {
-			cumulative += prob
-			if causeRoll < cumulative {
-				selectedCause = cause
-				break
-			}
-		}
-
-		return DelayEvent{
-			Cause:     selectedCause,
-			Duration:  time.Duration(delayMinutes) * time.Minute,
-			StartTime: startTime,
-		}
-	} else if roll < proportionDelay+proportionCancellation {
-		return CancellationEvent{
-			StartTime: startTime,
-		}
-	}
+func (SpeedReductionEvent) isEvent() {}
+
+func (e SpeedReductionEvent) Start() time.Duration { return e.StartTime }
 
-	return NoEvent{}
+func (e SpeedReductionEvent) IsActive(currentTime time.Duration) bool {
+	return currentTime >= e.StartTime && currentTime < e.StartTime+e.Duration
+}
+
+func (e SpeedReductionEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"kind":      "speed_reduction",
+		"cause":     DelayCauseRollingStock,
+		"maxSpeed":  e.MaxSpeed,
+		"duration":  e.Duration,
+		"startTime": e.StartTime,
+	})
 }
@@ -0,0 +1,185 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// Model holds the fitted parameters of event generation. Delay durations in
+// minutes follow a lognormal distribution of parameters MuDelayed and
+// StdDelayed.
+type Model struct {
+	ProportionDelay        float64                `json:"proportionDelay"`
+	ProportionCancellation float64                `json:"proportionCancellation"`
+	MuDelayed              float64                `json:"muDelayed"`
+	StdDelayed             float64                `json:"stdDelayed"`
+	CauseProbabilities     map[DelayCause]float64 `json:"causeProbabilities"`
+	Records                int                    `json:"records,omitempty"` // sample size, when fitted
+}
+
+// DefaultModel is fitted on the national punctuality records.
+func DefaultModel() Model {
+	return Model{
+		ProportionDelay:        0.0960013641401654,
+		ProportionCancellation: 0.03363966841008551,
+		MuDelayed:              3.477863772291891,
+		StdDelayed:             0.3791282825460146,
+		CauseProbabilities: map[DelayCause]float64{
+			DelayCauseExternal:       0.21873635169470653,
+			DelayCauseInfrastructure: 0.22102479850910456,
+			DelayCauseTraffic:        0.20142373232243163,
+			DelayCauseRollingStock:   0.19090507527285972,
+			DelayCauseStation:        0.07300637791365844,
+			DelayCausePassenger:      0.07681434828215401,
+		},
+	}
+}
+
+func (m Model) validate() error {
+	if m.ProportionDelay < 0 || m.ProportionCancellation < 0 || m.ProportionDelay >= 1 || m.ProportionCancellation > 1 {
+		return fmt.Errorf("proportions must be in [0, 1)")
+	}
+	if m.StdDelayed < 0 {
+		return fmt.Errorf("stdDelayed cannot be negative")
+	}
+
+	total := 0.0
+	for cause, prob := range m.CauseProbabilities {
+		if !isKnownCause(cause) {
+			return fmt.Errorf("unknown cause %q", cause)
+		}
+		if prob < 0 {
+			return fmt.Errorf("probability of cause %s cannot be negative", cause)
+		}
+		total += prob
+	}
+	if m.ProportionDelay > 0 && total == 0 {
+		return fmt.Errorf("causeProbabilities are required when delays may occur")
+	}
+	return nil
+}
+
+// randomCause draws a cause in proportion to the cause probabilities, which
+// need not sum to one.
+func (m Model) randomCause(rng *rand.Rand) DelayCause {
+	total := 0.0
+	for _, prob := range m.CauseProbabilities {
+		total += prob
+	}
+
+	causeRoll := rng.Float64() * total
+	cumulative := 0.0
+	for _, cause := range DelayCauses {
+		cumulative += m.CauseProbabilities[cause]
+		if causeRoll < cumulative {
+			return cause
+		}
+	}
+	return DelayCauseExternal
+}
+
+// GroupBy is the train attribute a model set is split on.
+type GroupBy string
+
+const (
+	GroupByNone     GroupBy = ""
+	GroupByLine     GroupBy = "line"
+	GroupByPeriod   GroupBy = "period"
+	GroupByCategory GroupBy = "category"
+)
+
+// Profile describes a train for model selection.
+type Profile struct {
+	Line      string
+	Category  string
+	Departure time.Duration
+}
+
+func (p Profile) key(groupBy GroupBy) string {
+	switch groupBy {
+	case GroupByLine:
+		return p.Line
+	case GroupByPeriod:
+		return Period(p.Departure)
+	case GroupByCategory:
+		return p.Category
+	default:
+		return ""
+	}
+}
+
+// Period is the time-of-day band of a departure.
+func Period(departure time.Duration) string {
+	switch hour := departure / time.Hour; {
+	case hour < 6:
+		return "night"
+	case hour < 10:
+		return "morning_peak"
+	case hour < 16:
+		return "midday"
+	case hour < 20:
+		return "evening_peak"
+	default:
+		return "evening"
+	}
+}
+
+// ModelSet holds a model per group of trains, with a default for groups that
+// were not fitted.
+type ModelSet struct {
+	Default Model            `json:"default"`
+	GroupBy GroupBy          `json:"groupBy,omitempty"`
+	Groups  map[string]Model `json:"groups,omitempty"`
+}
+
+func DefaultModelSet() *ModelSet {
+	return &ModelSet{Default: DefaultModel()}
+}
+
+// For returns the model of the group of the train.
+func (ms *ModelSet) For(profile Profile) Model {
+	if model, ok := ms.Groups[profile.key(ms.GroupBy)]; ok {
+		return model
+	}
+	return ms.Default
+}
+
+func LoadModelSet(path string) (*ModelSet, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading event model file: %w", err)
+	}
+
+	var ms ModelSet
+	if err := json.Unmarshal(raw, &ms); err != nil {
+		return nil, fmt.Errorf("parsing event model file: %w", err)
+	}
+
+	switch ms.GroupBy {
+	case GroupByNone, GroupByLine, GroupByPeriod, GroupByCategory:
+	default:
+		return nil, fmt.Errorf("unknown groupBy %q", ms.GroupBy)
+	}
+
+	if err := ms.Default.validate(); err != nil {
+		return nil, fmt.Errorf("default model: %w", err)
+	}
+	for key, model := range ms.Groups {
+		if err := model.validate(); err != nil {
+			return nil, fmt.Errorf("model of group %s: %w", key, err)
+		}
+	}
+
+	return &ms, nil
+}
+
+func (ms *ModelSet) Save(path string) error {
+	raw, err := json.MarshalIndent(ms, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(raw, '\n'), 0o644)
+}
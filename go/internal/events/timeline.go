@@ -0,0 +1,162 @@
+package events
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"ai30-project/internal/constants"
+)
+
+// Timeline holds every event affecting a train, ordered by start time. Events
+// compose: a train may be delayed several times, then short-turned.
+type Timeline []Event
+
+// ActiveDelay returns the active delay following the train that started
+// first, if any.
+func (tl Timeline) ActiveDelay(currentTime time.Duration) (DelayEvent, bool) {
+	for _, e := range tl {
+		if delay, ok := e.(DelayEvent); ok && delay.FollowsTrain() && delay.IsActive(currentTime) {
+			return delay, true
+		}
+	}
+	return DelayEvent{}, false
+}
+
+// ExtraDwell is how much longer than planned the train stops at the station,
+// with the cause of the longest delay there.
+func (tl Timeline) ExtraDwell(stationID string) (time.Duration, DelayCause) {
+	var extra, longest time.Duration
+	var cause DelayCause
+	for _, e := range tl {
+		if delay, ok := e.(DelayEvent); ok && delay.StationID == stationID {
+			extra += delay.Duration
+			if delay.Duration > longest {
+				longest, cause = delay.Duration, delay.Cause
+			}
+		}
+	}
+	return extra, cause
+}
+
+func (tl Timeline) IsCancelled(currentTime time.Duration) bool {
+	for _, e := range tl {
+		if cancellation, ok := e.(CancellationEvent); ok && cancellation.IsActive(currentTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// ShortTurnsAt reports whether the train terminates at the station.
+func (tl Timeline) ShortTurnsAt(stationID string, currentTime time.Duration) bool {
+	for _, e := range tl {
+		if partial, ok := e.(PartialCancellationEvent); ok && partial.StationID == stationID && partial.IsActive(currentTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// Skips reports whether the train runs through the station without stopping.
+func (tl Timeline) Skips(stationID string, currentTime time.Duration) bool {
+	for _, e := range tl {
+		if skipped, ok := e.(SkippedStopEvent); ok && skipped.StationID == stationID && skipped.IsActive(currentTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxSpeed returns the lowest speed cap of the active speed reductions.
+func (tl Timeline) MaxSpeed(currentTime time.Duration) (float64, bool) {
+	maxSpeed, found := math.Inf(1), false
+	for _, e := range tl {
+		if reduction, ok := e.(SpeedReductionEvent); ok && reduction.IsActive(currentTime) {
+			maxSpeed, found = math.Min(maxSpeed, reduction.MaxSpeed), true
+		}
+	}
+	return maxSpeed, found
+}
+
+// Stop is what event generation needs to know about a train stop.
+type Stop struct {
+	StationID string
+	Arrival   time.Duration
+	Departure time.Duration
+}
+
+// GenerateTimeline draws the events of a train running through stops from
+// the model with rng. Each kind of event is drawn independently, so several
+// may hit the same train.
+func GenerateTimeline(model Model, stops []Stop, rng *rand.Rand) Timeline {
+	firstDeparture := stops[0].Departure
+	lastArrival := stops[len(stops)-1].Arrival
+	randomTime := func(from, to time.Duration) time.Duration {
+		if to < from {
+			to = from
+		}
+		return from + time.Duration(float64(to-from)*rng.Float64())
+	}
+	randomDuration := func() time.Duration {
+		return time.Duration(math.Exp(rng.NormFloat64()*model.StdDelayed+model.MuDelayed)) * time.Minute
+	}
+
+	timeline := Timeline{}
+
+	// Station and passenger delays happen at a stop the train departs from;
+	// infrastructure delays are placed on a segment by the simulation, which
+	// knows the route.
+	for rng.Float64() < model.ProportionDelay {
+		delay := DelayEvent{
+			Cause:     model.randomCause(rng),
+			Duration:  randomDuration(),
+			StartTime: randomTime(firstDeparture, lastArrival),
+		}
+		if delay.Cause == DelayCauseStation || delay.Cause == DelayCausePassenger {
+			stop := stops[rng.Intn(len(stops)-1)]
+			delay.StationID = stop.StationID
+			delay.StartTime = stop.Departure
+		}
+		timeline = append(timeline, delay)
+	}
+
+	// Short-turns need a stop between the origin and the terminus, and are
+	// announced before the train gets there.
+	intermediate := stops[1 : len(stops)-1]
+	if rng.Float64() < model.ProportionCancellation {
+		if len(intermediate) > 0 && rng.Float64() < shareShortTurned {
+			stop := intermediate[rng.Intn(len(intermediate))]
+			timeline = append(timeline, PartialCancellationEvent{
+				StationID: stop.StationID,
+				StartTime: randomTime(firstDeparture, stop.Arrival),
+			})
+		} else {
+			timeline = append(timeline, CancellationEvent{
+				StartTime: randomTime(firstDeparture, lastArrival),
+			})
+		}
+	}
+
+	if len(intermediate) > 0 && rng.Float64() < proportionSkippedStop {
+		stop := intermediate[rng.Intn(len(intermediate))]
+		timeline = append(timeline, SkippedStopEvent{
+			StationID: stop.StationID,
+			StartTime: randomTime(firstDeparture, stop.Arrival),
+		})
+	}
+
+	if rng.Float64() < proportionSpeedReduction {
+		timeline = append(timeline, SpeedReductionEvent{
+			MaxSpeed:  reducedMaxSpeedKmH * constants.KmH_to_MS,
+			Duration:  randomDuration(),
+			StartTime: randomTime(firstDeparture, lastArrival),
+		})
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Start() < timeline[j].Start()
+	})
+	return timeline
+}
@@ -0,0 +1,181 @@
+package events
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestTimelineComposes(t *testing.T) {
+	timeline := Timeline{
+		DelayEvent{Cause: DelayCauseExternal, Duration: 10 * time.Minute, StartTime: 8 * time.Hour},
+		DelayEvent{Cause: DelayCauseRollingStock, Duration: 10 * time.Minute, StartTime: 8*time.Hour + 5*time.Minute},
+		SpeedReductionEvent{MaxSpeed: 30, Duration: time.Hour, StartTime: 8 * time.Hour},
+		SpeedReductionEvent{MaxSpeed: 20, Duration: 10 * time.Minute, StartTime: 8*time.Hour + 30*time.Minute},
+		SkippedStopEvent{StationID: "B", StartTime: 8 * time.Hour},
+		PartialCancellationEvent{StationID: "C", StartTime: 9 * time.Hour},
+	}
+
+	if delay, ok := timeline.ActiveDelay(8*time.Hour + 7*time.Minute); !ok || delay.Cause != DelayCauseExternal {
+		t.Errorf("active delay %+v (active: %v), want the first one", delay, ok)
+	}
+	if delay, ok := timeline.ActiveDelay(8*time.Hour + 12*time.Minute); !ok || delay.Cause != DelayCauseRollingStock {
+		t.Errorf("active delay %+v (active: %v), want the second one once the first ended", delay, ok)
+	}
+	if _, ok := timeline.ActiveDelay(8*time.Hour + 15*time.Minute); ok {
+		t.Error("delay active once both ended")
+	}
+
+	if maxSpeed, ok := timeline.MaxSpeed(8*time.Hour + 35*time.Minute); !ok || maxSpeed != 20 {
+		t.Errorf("max speed %v (reduced: %v), want the lowest cap 20", maxSpeed, ok)
+	}
+	if maxSpeed, ok := timeline.MaxSpeed(8*time.Hour + 45*time.Minute); !ok || maxSpeed != 30 {
+		t.Errorf("max speed %v (reduced: %v), want 30", maxSpeed, ok)
+	}
+	if _, ok := timeline.MaxSpeed(9 * time.Hour); ok {
+		t.Error("speed reduced once every reduction ended")
+	}
+
+	if !timeline.Skips("B", 8*time.Hour) || timeline.Skips("C", 8*time.Hour) || timeline.Skips("B", 7*time.Hour) {
+		t.Error("want B skipped from 08:00 only")
+	}
+	if timeline.ShortTurnsAt("C", 8*time.Hour+59*time.Minute) || !timeline.ShortTurnsAt("C", 9*time.Hour) {
+		t.Error("want the train short-turned at C from 09:00")
+	}
+	if timeline.IsCancelled(10 * time.Hour) {
+		t.Error("short-turned train cancelled")
+	}
+	if !append(timeline, CancellationEvent{StartTime: 10 * time.Hour}).IsCancelled(10 * time.Hour) {
+		t.Error("train not cancelled by a cancellation event")
+	}
+}
+
+func TestDelayCauseEffects(t *testing.T) {
+	want := map[DelayCause]Effect{
+		DelayCauseExternal:       EffectEmergencyStop,
+		DelayCauseInfrastructure: EffectServiceStop,
+		DelayCauseTraffic:        EffectServiceStop,
+		DelayCauseRollingStock:   EffectSlowdown,
+		DelayCauseStation:        EffectDwell,
+		DelayCausePassenger:      EffectDwell,
+	}
+	for _, cause := range DelayCauses {
+		if got := cause.Effect(); got != want[cause] {
+			t.Errorf("%s: effect %s, want %s", cause, got, want[cause])
+		}
+	}
+}
+
+// TestGenerateTimeline draws many timelines for a train calling at A, B and
+// C, and checks they are ordered and that events compose.
+func TestGenerateTimeline(t *testing.T) {
+	stops := []Stop{
+		{StationID: "A", Arrival: 8 * time.Hour, Departure: 8 * time.Hour},
+		{StationID: "B", Arrival: 8*time.Hour + 30*time.Minute, Departure: 8*time.Hour + 35*time.Minute},
+		{StationID: "C", Arrival: 9 * time.Hour, Departure: 9 * time.Hour},
+	}
+	model := DefaultModel()
+	model.ProportionDelay, model.ProportionCancellation = 0.5, 0.5
+
+	counts := make(map[string]int)
+	for seed := int64(0); seed < 500; seed++ {
+		timeline := GenerateTimeline(model, stops, rand.New(rand.NewSource(seed)))
+
+		delays := 0
+		for i, e := range timeline {
+			if i > 0 && e.Start() < timeline[i-1].Start() {
+				t.Fatalf("seed %d: events out of order: %v", seed, timeline)
+			}
+			switch e := e.(type) {
+			case DelayEvent:
+				delays++
+				if e.Duration <= 0 {
+					t.Errorf("seed %d: delay of %v", seed, e.Duration)
+				}
+			case PartialCancellationEvent:
+				counts["short-turned"]++
+				if e.StationID != "B" || e.StartTime > stops[1].Arrival {
+					t.Errorf("seed %d: short-turn %+v, want it announced before reaching B", seed, e)
+				}
+			case CancellationEvent:
+				counts["cancelled"]++
+			}
+		}
+		if delays > 1 {
+			counts["delayed several times"]++
+		}
+		if delays > 0 && len(timeline) > delays {
+			counts["delayed and more"]++
+		}
+	}
+
+	for _, outcome := range []string{"short-turned", "cancelled", "delayed several times", "delayed and more"} {
+		if counts[outcome] == 0 {
+			t.Errorf("no train %s in 500 draws", outcome)
+		}
+	}
+
+	model.ProportionDelay, model.ProportionCancellation = 0, 0
+	for seed := int64(0); seed < 100; seed++ {
+		for _, e := range GenerateTimeline(model, stops, rand.New(rand.NewSource(seed))) {
+			if _, ok := e.(DelayEvent); ok {
+				t.Fatalf("seed %d: delay drawn with a proportion of 0", seed)
+			}
+		}
+	}
+}
+
+func TestDelaysAnchoredByLocation(t *testing.T) {
+	timeline := Timeline{
+		DelayEvent{Cause: DelayCauseStation, Duration: 3 * time.Minute, StartTime: 8 * time.Hour, StationID: "B"},
+		DelayEvent{Cause: DelayCausePassenger, Duration: 5 * time.Minute, StartTime: 8 * time.Hour, StationID: "B"},
+		DelayEvent{Cause: DelayCauseInfrastructure, Duration: 20 * time.Minute, StartTime: 8 * time.Hour, SegmentID: "A-B"},
+	}
+
+	if extra, cause := timeline.ExtraDwell("B"); extra != 8*time.Minute || cause != DelayCausePassenger {
+		t.Errorf("extra dwell at B %v caused by %s, want 8m0s caused by the longest, passenger", extra, cause)
+	}
+	if extra, cause := timeline.ExtraDwell("A"); extra != 0 || cause != "" {
+		t.Errorf("extra dwell at A %v caused by %s, want none", extra, cause)
+	}
+	// Neither delay follows the train: it only meets them at B or on A-B
+	if delay, ok := timeline.ActiveDelay(8*time.Hour + time.Minute); ok {
+		t.Errorf("anchored delay %+v follows the train", delay)
+	}
+}
+
+// TestGenerateTimelineAnchorsDwells checks station and passenger delays are
+// drawn at a stop the train departs from, when it departs.
+func TestGenerateTimelineAnchorsDwells(t *testing.T) {
+	stops := []Stop{
+		{StationID: "A", Arrival: 8 * time.Hour, Departure: 8 * time.Hour},
+		{StationID: "B", Arrival: 8*time.Hour + 30*time.Minute, Departure: 8*time.Hour + 35*time.Minute},
+		{StationID: "C", Arrival: 9 * time.Hour, Departure: 9 * time.Hour},
+	}
+	departures := map[string]time.Duration{"A": stops[0].Departure, "B": stops[1].Departure}
+	model := Model{
+		ProportionDelay:    0.5,
+		CauseProbabilities: map[DelayCause]float64{DelayCauseStation: 1, DelayCausePassenger: 1, DelayCauseRollingStock: 1},
+	}
+
+	for seed := int64(0); seed < 200; seed++ {
+		for _, e := range GenerateTimeline(model, stops, rand.New(rand.NewSource(seed))) {
+			delay, ok := e.(DelayEvent)
+			if !ok {
+				continue
+			}
+			if delay.SegmentID != "" {
+				t.Errorf("seed %d: %s delay anchored on segment %s", seed, delay.Cause, delay.SegmentID)
+			}
+			if delay.Cause.Effect() != EffectDwell {
+				if !delay.FollowsTrain() {
+					t.Errorf("seed %d: %s delay anchored at %s, want it following the train", seed, delay.Cause, delay.StationID)
+				}
+				continue
+			}
+			if departure, ok := departures[delay.StationID]; !ok || delay.StartTime != departure {
+				t.Errorf("seed %d: %s delay at %q from %v, want it at A or B when the train departs", seed, delay.Cause, delay.StationID, delay.StartTime)
+			}
+		}
+	}
+}
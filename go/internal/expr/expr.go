@@ -0,0 +1,471 @@
+// Package expr is a small expression language to write orderings without
+// recompiling, such as station strategies. A program is a list of sort keys
+// separated by commas, each an expression over named variables:
+//
+//	delay > 30, category == "OGO" ? 0 : 1, -passengers
+//
+// Expressions have numbers, strings and booleans, arithmetic (+ - * /),
+// comparisons, && || !, the conditional a ? b : c and the functions min, max
+// and abs. Types are checked when compiling.
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+type Type int
+
+const (
+	Number Type = iota
+	String
+	Bool
+)
+
+func (t Type) String() string {
+	switch t {
+	case Number:
+		return "number"
+	case String:
+		return "string"
+	default:
+		return "bool"
+	}
+}
+
+// Vars are the values of the variables: float64, string or bool.
+type Vars map[string]any
+
+// Program is a compiled list of sort keys.
+type Program struct {
+	source string
+	keys   []*node
+}
+
+type node struct {
+	typ  Type
+	eval func(vars Vars) (any, error)
+}
+
+// Compile parses source and checks it against the types of the variables it
+// may use.
+func Compile(source string, types map[string]Type) (*Program, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, types: types}
+
+	program := &Program{source: source}
+	for {
+		key, err := p.conditional()
+		if err != nil {
+			return nil, err
+		}
+		program.keys = append(program.keys, key)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if tok := p.peek(); tok.kind != eof {
+		return nil, fmt.Errorf("unexpected %q at %d", tok.text, tok.pos)
+	}
+	return program, nil
+}
+
+func (p *Program) String() string {
+	return p.source
+}
+
+// Eval computes the keys for the variables.
+func (p *Program) Eval(vars Vars) ([]any, error) {
+	keys := make([]any, len(p.keys))
+	for i, key := range p.keys {
+		value, err := key.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = value
+	}
+	return keys, nil
+}
+
+// Compare orders two lists of keys of the same program: by the first key
+// that differs, numbers and strings ascending, false before true.
+func Compare(a, b []any) int {
+	for i := range a {
+		if c := compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compare(a, b any) int {
+	switch a := a.(type) {
+	case float64:
+		b := b.(float64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(a, b.(string))
+	default:
+		switch a, b := a.(bool), b.(bool); {
+		case a == b:
+			return 0
+		case b:
+			return -1
+		}
+		return 1
+	}
+}
+
+type parser struct {
+	tokens []token
+	next   int
+	types  map[string]Type
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) accept(text string) bool {
+	if tok := p.peek(); tok.kind == operator && tok.text == text {
+		p.next++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		tok := p.peek()
+		return fmt.Errorf("expected %q at %d, got %q", text, tok.pos, tok.text)
+	}
+	return nil
+}
+
+func (p *parser) conditional() (*node, error) {
+	condition, err := p.or()
+	if err != nil || !p.accept("?") {
+		return condition, err
+	}
+	if condition.typ != Bool {
+		return nil, fmt.Errorf("condition before ? must be a bool, not a %s", condition.typ)
+	}
+	then, err := p.conditional()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.conditional()
+	if err != nil {
+		return nil, err
+	}
+	if then.typ != otherwise.typ {
+		return nil, fmt.Errorf("both sides of : must have the same type, not %s and %s", then.typ, otherwise.typ)
+	}
+	return &node{typ: then.typ, eval: func(vars Vars) (any, error) {
+		value, err := condition.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		if value.(bool) {
+			return then.eval(vars)
+		}
+		return otherwise.eval(vars)
+	}}, nil
+}
+
+func (p *parser) or() (*node, error) {
+	return p.logical("||", p.and)
+}
+
+func (p *parser) and() (*node, error) {
+	return p.logical("&&", p.comparison)
+}
+
+// logical chains operands with && or ||, evaluating them left to right only
+// as far as needed.
+func (p *parser) logical(op string, operand func() (*node, error)) (*node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(op) {
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ != Bool || right.typ != Bool {
+			return nil, fmt.Errorf("%s needs bools, not %s and %s", op, left.typ, right.typ)
+		}
+		l, shortCircuit := left, op == "||"
+		left = &node{typ: Bool, eval: func(vars Vars) (any, error) {
+			value, err := l.eval(vars)
+			if err != nil || value.(bool) == shortCircuit {
+				return value, err
+			}
+			return right.eval(vars)
+		}}
+	}
+	return left, nil
+}
+
+var comparisons = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func (p *parser) comparison() (*node, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range comparisons {
+		if !p.accept(op) {
+			continue
+		}
+		right, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ != right.typ {
+			return nil, fmt.Errorf("cannot compare a %s with a %s", left.typ, right.typ)
+		}
+		if left.typ == Bool && op != "==" && op != "!=" {
+			return nil, fmt.Errorf("bools can only be compared with == and !=")
+		}
+		return &node{typ: Bool, eval: func(vars Vars) (any, error) {
+			a, b, err := both(left, right, vars)
+			if err != nil {
+				return nil, err
+			}
+			c := compare(a, b)
+			switch op {
+			case "==":
+				return c == 0, nil
+			case "!=":
+				return c != 0, nil
+			case "<=":
+				return c <= 0, nil
+			case ">=":
+				return c >= 0, nil
+			case "<":
+				return c < 0, nil
+			default:
+				return c > 0, nil
+			}
+		}}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) additive() (*node, error) {
+	return p.arithmetic([]string{"+", "-"}, p.multiplicative)
+}
+
+func (p *parser) multiplicative() (*node, error) {
+	return p.arithmetic([]string{"*", "/"}, p.unary)
+}
+
+func (p *parser) arithmetic(ops []string, operand func() (*node, error)) (*node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		for _, candidate := range ops {
+			if p.accept(candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ != Number || right.typ != Number {
+			return nil, fmt.Errorf("%s needs numbers, not %s and %s", op, left.typ, right.typ)
+		}
+		l := left
+		left = &node{typ: Number, eval: func(vars Vars) (any, error) {
+			a, b, err := both(l, right, vars)
+			if err != nil {
+				return nil, err
+			}
+			x, y := a.(float64), b.(float64)
+			switch op {
+			case "+":
+				return x + y, nil
+			case "-":
+				return x - y, nil
+			case "*":
+				return x * y, nil
+			default:
+				if y == 0 {
+					return nil, fmt.Errorf("division by zero")
+				}
+				return x / y, nil
+			}
+		}}
+	}
+}
+
+func (p *parser) unary() (*node, error) {
+	switch {
+	case p.accept("-"):
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if operand.typ != Number {
+			return nil, fmt.Errorf("- needs a number, not a %s", operand.typ)
+		}
+		return &node{typ: Number, eval: func(vars Vars) (any, error) {
+			value, err := operand.eval(vars)
+			if err != nil {
+				return nil, err
+			}
+			return -value.(float64), nil
+		}}, nil
+	case p.accept("!"):
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if operand.typ != Bool {
+			return nil, fmt.Errorf("! needs a bool, not a %s", operand.typ)
+		}
+		return &node{typ: Bool, eval: func(vars Vars) (any, error) {
+			value, err := operand.eval(vars)
+			if err != nil {
+				return nil, err
+			}
+			return !value.(bool), nil
+		}}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (*node, error) {
+	tok := p.peek()
+	switch tok.kind {
+	case number:
+		p.next++
+		return constant(Number, tok.value), nil
+	case text:
+		p.next++
+		return constant(String, tok.value), nil
+	case ident:
+		p.next++
+		switch {
+		case tok.text == "true" || tok.text == "false":
+			return constant(Bool, tok.text == "true"), nil
+		case p.accept("("):
+			return p.call(tok)
+		}
+		typ, ok := p.types[tok.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown variable %q (known: %s)", tok.text, strings.Join(sortedNames(p.types), ", "))
+		}
+		name := tok.text
+		return &node{typ: typ, eval: func(vars Vars) (any, error) {
+			return vars[name], nil
+		}}, nil
+	}
+
+	if p.accept("(") {
+		inner, err := p.conditional()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+	}
+	if tok.kind == eof {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", tok.text, tok.pos)
+}
+
+// call parses the arguments of a function, all numbers.
+func (p *parser) call(name token) (*node, error) {
+	var args []*node
+	for !p.accept(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.conditional()
+		if err != nil {
+			return nil, err
+		}
+		if arg.typ != Number {
+			return nil, fmt.Errorf("%s needs numbers, not a %s", name.text, arg.typ)
+		}
+		args = append(args, arg)
+	}
+
+	var apply func(values []float64) float64
+	switch name.text {
+	case "abs":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("abs takes 1 argument, not %d", len(args))
+		}
+		apply = func(values []float64) float64 { return math.Abs(values[0]) }
+	case "min", "max":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%s needs at least 1 argument", name.text)
+		}
+		pick := math.Min
+		if name.text == "max" {
+			pick = math.Max
+		}
+		apply = func(values []float64) float64 {
+			result := values[0]
+			for _, value := range values[1:] {
+				result = pick(result, value)
+			}
+			return result
+		}
+	default:
+		return nil, fmt.Errorf("unknown function %q at %d", name.text, name.pos)
+	}
+
+	return &node{typ: Number, eval: func(vars Vars) (any, error) {
+		values := make([]float64, len(args))
+		for i, arg := range args {
+			value, err := arg.eval(vars)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value.(float64)
+		}
+		return apply(values), nil
+	}}, nil
+}
+
+func constant(typ Type, value any) *node {
+	return &node{typ: typ, eval: func(Vars) (any, error) { return value, nil }}
+}
+
+func both(left, right *node, vars Vars) (any, any, error) {
+	a, err := left.eval(vars)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := right.eval(vars)
+	return a, b, err
+}
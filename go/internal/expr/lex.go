@@ -0,0 +1,89 @@
+package expr
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	eof tokenKind = iota
+	number
+	text
+	ident
+	operator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value any // float64 for numbers, string for texts
+	pos   int
+}
+
+// operators are matched longest first.
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "+", "-", "*", "/", "!", "?", ":", "(", ")", ","}
+
+func lex(source string) ([]token, error) {
+	var tokens []token
+	for pos := 0; pos < len(source); {
+		c := rune(source[pos])
+		switch {
+		case unicode.IsSpace(c):
+			pos++
+		case unicode.IsDigit(c) || c == '.':
+			end := pos
+			for end < len(source) && (unicode.IsDigit(rune(source[end])) || source[end] == '.') {
+				end++
+			}
+			value, err := strconv.ParseFloat(source[pos:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at %d", source[pos:end], pos)
+			}
+			tokens = append(tokens, token{kind: number, text: source[pos:end], value: value, pos: pos})
+			pos = end
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(source[pos+1:], source[pos])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", pos)
+			}
+			value := source[pos+1 : pos+1+end]
+			tokens = append(tokens, token{kind: text, text: source[pos : pos+2+end], value: value, pos: pos})
+			pos += end + 2
+		case unicode.IsLetter(c) || c == '_':
+			end := pos
+			for end < len(source) && (unicode.IsLetter(rune(source[end])) || unicode.IsDigit(rune(source[end])) || source[end] == '_') {
+				end++
+			}
+			tokens = append(tokens, token{kind: ident, text: source[pos:end], pos: pos})
+			pos = end
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(source[pos:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at %d", c, pos)
+			}
+			tokens = append(tokens, token{kind: operator, text: op, pos: pos})
+			pos += len(op)
+		}
+	}
+	return append(tokens, token{kind: eof, pos: len(source)}), nil
+}
+
+func sortedNames(types map[string]Type) []string {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,215 @@
+// Package gym steps a simulation from the outside, one tick at a time, to
+// train policies on it: the policy chooses station admission orders and
+// driver commands, and is rewarded for the delays, energy or passenger time
+// of the tick.
+package gym
+
+import (
+	"fmt"
+	"sort"
+
+	"ai30-project/internal/simulation"
+	"ai30-project/internal/trains"
+)
+
+// Config is how every episode is set up.
+type Config struct {
+	Scenario        func() simulation.Scenario // builds fresh trains and network for each episode
+	DriverBehavior  string                     // drives the trains given no command
+	DriverParams    map[string]any             // parameters of the driver, defaults when left out
+	StationStrategy string                     // orders the stations given no admission order
+	StationParams   map[string]any             // parameters of the strategy, defaults when left out
+	Reward          Reward
+
+	// Setup enables the optional models of the simulation, such as passenger
+	// demand or the power supply, before it starts.
+	Setup func(sim *simulation.Simulation) error
+}
+
+// Actions are what the policy decides for the next tick. Trains and stations
+// left out follow their own driver and strategy.
+type Actions struct {
+	Admissions map[string][]string             // station ID -> trains to admit first, in order
+	Drivers    map[string]trains.DriverCommand // train ID -> command
+}
+
+// Env is an episode of the simulation. Episodes run on the sequential
+// scheduler, so that a seed and the same actions replay the same episode.
+type Env struct {
+	config Config
+
+	sim      *simulation.Simulation
+	closed   bool
+	trains   []*trains.Train // by ID
+	drivers  map[string]*commandedDriver
+	stations map[string]bool
+	ordered  map[string]bool // stations following an admission order
+	previous Observation
+}
+
+func NewEnv(config Config) *Env {
+	if config.Reward == nil {
+		config.Reward = DelayReward
+	}
+	return &Env{config: config}
+}
+
+// Reset starts a new episode drawn from seed and returns its first
+// observation.
+func (e *Env) Reset(seed int64) (Observation, error) {
+	e.Close()
+
+	fallback, err := trains.NewDriverBehavior(e.config.DriverBehavior, e.config.DriverParams)
+	if err != nil {
+		return Observation{}, err
+	}
+
+	scenario := e.config.Scenario()
+	sim, err := simulation.NewScenarioSimulationWithParams(scenario, e.config.DriverBehavior, e.config.DriverParams, e.config.StationStrategy, e.config.StationParams)
+	if err != nil {
+		return Observation{}, err
+	}
+	sim.SetSeed(seed)
+	if err := sim.SetScheduler(simulation.SchedulerSequential); err != nil {
+		return Observation{}, err
+	}
+	if e.config.Setup != nil {
+		if err := e.config.Setup(sim); err != nil {
+			return Observation{}, err
+		}
+	}
+
+	e.sim, e.closed = sim, false
+	e.trains = append([]*trains.Train(nil), scenario.Trains...)
+	sort.Slice(e.trains, func(i, j int) bool { return e.trains[i].ID() < e.trains[j].ID() })
+	e.drivers = make(map[string]*commandedDriver, len(e.trains))
+	e.ordered = make(map[string]bool)
+
+	for _, train := range e.trains {
+		driver := &commandedDriver{fallback: fallback}
+		e.drivers[train.ID()] = driver
+		train.SetDriver(driver)
+	}
+
+	sim.Start()
+
+	observation, err := e.observe()
+	if err != nil {
+		return Observation{}, err
+	}
+	e.previous = observation
+
+	e.stations = make(map[string]bool, len(observation.Stations))
+	for _, station := range observation.Stations {
+		e.stations[station.ID] = true
+	}
+	return observation, nil
+}
+
+// Step applies the actions, plays one tick and returns what follows: the new
+// observation, the reward of the tick and whether the episode is over.
+func (e *Env) Step(actions Actions) (Observation, float64, bool, error) {
+	if e.sim == nil || e.closed {
+		return Observation{}, 0, true, fmt.Errorf("no episode: call Reset first")
+	}
+	if e.sim.IsFinished() || e.sim.Failure() != nil {
+		return e.previous, 0, true, fmt.Errorf("episode is over: call Reset")
+	}
+
+	if err := e.apply(actions); err != nil {
+		return e.previous, 0, false, err
+	}
+
+	e.sim.Tick()
+
+	observation, err := e.observe()
+	if err != nil {
+		return e.previous, 0, false, err
+	}
+	reward := e.config.Reward(e.previous, observation)
+	e.previous = observation
+
+	return observation, reward, e.sim.IsFinished() || e.sim.Failure() != nil, nil
+}
+
+// Simulation is the simulation of the last episode, for its report. The
+// report stays available once the episode is closed.
+func (e *Env) Simulation() *simulation.Simulation {
+	return e.sim
+}
+
+// Close stops the agents of the current episode.
+func (e *Env) Close() {
+	if e.sim != nil && !e.closed {
+		e.sim.Stop()
+		e.closed = true
+	}
+}
+
+// apply checks the actions before handing them to the trains and stations.
+// Stations ordered at the previous tick and not this one go back to their
+// strategy.
+func (e *Env) apply(actions Actions) error {
+	for trainID, command := range actions.Drivers {
+		if _, ok := e.drivers[trainID]; !ok {
+			return fmt.Errorf("unknown train %s", trainID)
+		}
+		if command.DesiredSpeed <= 0 || command.DesiredAccel < 0 || command.DesiredDecel <= 0 {
+			return fmt.Errorf("train %s: speed and decel must be positive, accel cannot be negative", trainID)
+		}
+	}
+
+	for stationID := range actions.Admissions {
+		if !e.stations[stationID] {
+			return fmt.Errorf("unknown station %s", stationID)
+		}
+	}
+
+	for trainID, driver := range e.drivers {
+		if command, ok := actions.Drivers[trainID]; ok {
+			driver.command = &command
+		} else {
+			driver.command = nil
+		}
+	}
+
+	for stationID := range e.ordered {
+		if _, stillOrdered := actions.Admissions[stationID]; !stillOrdered {
+			if err := e.sim.OrderAdmission(stationID, nil); err != nil {
+				return err
+			}
+			delete(e.ordered, stationID)
+		}
+	}
+
+	for _, stationID := range sortedStations(actions.Admissions) {
+		if err := e.sim.OrderAdmission(stationID, actions.Admissions[stationID]); err != nil {
+			return err
+		}
+		e.ordered[stationID] = true
+	}
+	return nil
+}
+
+func sortedStations(admissions map[string][]string) []string {
+	ids := make([]string, 0, len(admissions))
+	for id := range admissions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// commandedDriver follows the command of the policy for the tick, and its
+// fallback driver without one.
+type commandedDriver struct {
+	fallback trains.DriverBehavior
+	command  *trains.DriverCommand
+}
+
+func (d *commandedDriver) GetCommand(situation trains.DriverSituation) trains.DriverCommand {
+	if d.command != nil {
+		return *d.command
+	}
+	return d.fallback.GetCommand(situation)
+}
@@ -0,0 +1,128 @@
+package gym
+
+import (
+	"sort"
+	"time"
+
+	"ai30-project/internal/energy"
+)
+
+// Observation is the state of the network after a tick.
+type Observation struct {
+	Time     time.Duration
+	Trains   []TrainObservation   // by ID
+	Stations []StationObservation // by ID
+	Energy   float64              // kWh drawn from the line since the start of the episode
+}
+
+type TrainObservation struct {
+	ID                string
+	Running           bool // false once finished or taken out of service
+	AtStation         bool
+	Location          string  // station or segment ID
+	Position          float64 // meters on the segment
+	Speed             float64 // m/s
+	Delay             time.Duration
+	RemainingDistance float64 // meters to the next stop
+	Passengers        int
+}
+
+type StationObservation struct {
+	ID                string
+	Capacity          int // currently available
+	Trains            int
+	Demanding         []string // trains asking to enter, in admission order
+	WaitingPassengers int
+}
+
+// Features per train and per station in Vector.
+const (
+	TrainFeatures   = 7
+	StationFeatures = 4
+)
+
+// Vector flattens the observation for a policy, with the same length for
+// every tick of an episode: for each train, running, at station, position,
+// speed, delay in minutes, remaining distance and passengers; then for each
+// station, capacity, trains, demanding trains and waiting passengers.
+func (o Observation) Vector() []float64 {
+	vector := make([]float64, 0, len(o.Trains)*TrainFeatures+len(o.Stations)*StationFeatures)
+	for _, t := range o.Trains {
+		vector = append(vector,
+			boolFeature(t.Running),
+			boolFeature(t.AtStation),
+			t.Position,
+			t.Speed,
+			t.Delay.Minutes(),
+			t.RemainingDistance,
+			float64(t.Passengers),
+		)
+	}
+	for _, s := range o.Stations {
+		vector = append(vector,
+			float64(s.Capacity),
+			float64(s.Trains),
+			float64(len(s.Demanding)),
+			float64(s.WaitingPassengers),
+		)
+	}
+	return vector
+}
+
+func boolFeature(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func (e *Env) observe() (Observation, error) {
+	now := e.sim.CurrentTime()
+	observation := Observation{Time: now}
+
+	total := energy.Usage{}
+	for _, train := range e.trains {
+		// In segment order, for the same rounding in every episode
+		usage := train.Energy()
+		segmentIDs := make([]string, 0, len(usage))
+		for segmentID := range usage {
+			segmentIDs = append(segmentIDs, segmentID)
+		}
+		sort.Strings(segmentIDs)
+		for _, segmentID := range segmentIDs {
+			total = total.Add(usage[segmentID])
+		}
+
+		t := TrainObservation{ID: train.ID(), Passengers: train.OnBoard()}
+		if status, ok := train.Status(now); ok {
+			t.Running = true
+			t.AtStation = status.AtStation
+			t.Location = status.SegmentID
+			if status.AtStation {
+				t.Location = status.StationID
+			}
+			t.Position = status.Position
+			t.Speed = status.Speed
+			t.Delay = status.Delay
+			t.RemainingDistance = status.RemainingDistance
+		}
+		observation.Trains = append(observation.Trains, t)
+	}
+	observation.Energy = energy.KWh(total.Net())
+
+	states, err := e.sim.StationStates()
+	if err != nil {
+		return Observation{}, err
+	}
+	for _, state := range states {
+		observation.Stations = append(observation.Stations, StationObservation{
+			ID:                state.StationID,
+			Capacity:          state.EffectiveCapacity,
+			Trains:            len(state.Trains),
+			Demanding:         state.Demanding,
+			WaitingPassengers: state.WaitingPassengers,
+		})
+	}
+
+	return observation, nil
+}
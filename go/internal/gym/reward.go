@@ -0,0 +1,68 @@
+package gym
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Reward scores a tick from the observations before and after it. Rewards
+// are penalties: the less delay, energy or passenger time, the higher.
+type Reward func(before, after Observation) float64
+
+// DelayReward is minus the minutes of delay of the running trains, so that
+// over an episode it sums to the train-minutes spent late.
+func DelayReward(before, after Observation) float64 {
+	reward := 0.0
+	for _, t := range after.Trains {
+		if t.Running && t.Delay > 0 {
+			reward -= t.Delay.Minutes()
+		}
+	}
+	return reward
+}
+
+// EnergyReward is minus the kWh drawn from the line during the tick.
+func EnergyReward(before, after Observation) float64 {
+	return before.Energy - after.Energy
+}
+
+// PassengerMinutesReward is minus the minutes of delay of the running trains
+// weighted by their passengers, so that over an episode it sums to the
+// passenger-minutes spent late.
+func PassengerMinutesReward(before, after Observation) float64 {
+	reward := 0.0
+	for _, t := range after.Trains {
+		if t.Running && t.Delay > 0 {
+			reward -= t.Delay.Minutes() * float64(t.Passengers)
+		}
+	}
+	return reward
+}
+
+// Rewards are the built-in rewards, by name.
+var Rewards = map[string]Reward{
+	"delay":             DelayReward,
+	"energy":            EnergyReward,
+	"passenger_minutes": PassengerMinutesReward,
+}
+
+// Weighted sums the named rewards with their weights, e.g. {"delay": 1,
+// "energy": 0.1} to trade a kWh for a tenth of a minute of delay.
+func Weighted(weights map[string]float64) (Reward, error) {
+	names := make([]string, 0, len(weights))
+	for name := range weights {
+		if _, ok := Rewards[name]; !ok {
+			return nil, fmt.Errorf("unknown reward %q", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return func(before, after Observation) float64 {
+		reward := 0.0
+		for _, name := range names {
+			reward += weights[name] * Rewards[name](before, after)
+		}
+		return reward
+	}, nil
+}
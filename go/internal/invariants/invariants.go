@@ -0,0 +1,111 @@
+package invariants
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"ai30-project/internal/constants"
+	"ai30-project/internal/segments"
+	"ai30-project/internal/stations"
+)
+
+type Rule string
+
+const (
+	RuleSafetyDistance  Rule = "safety_distance"
+	RuleStationCapacity Rule = "station_capacity"
+	RuleSingleLocation  Rule = "single_location"
+	RulePositionBounds  Rule = "position_bounds"
+)
+
+// Violation is a rule broken at the end of a tick.
+type Violation struct {
+	Tick   time.Duration
+	Rule   Rule
+	Agents []string // trains, segments and stations involved
+	Detail string
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("invariant %s violated at %v by %s: %s", v.Rule, v.Tick, strings.Join(v.Agents, ", "), v.Detail)
+}
+
+func (v Violation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"tick":   v.Tick,
+		"rule":   v.Rule,
+		"agents": v.Agents,
+		"detail": v.Detail,
+	})
+}
+
+// Check validates the snapshots of every segment and station taken at the end
+// of a tick.
+func Check(tick time.Duration, segmentStates []segments.State, stationStates []stations.State) []Violation {
+	var violations []Violation
+	report := func(rule Rule, detail string, agents ...string) {
+		violations = append(violations, Violation{Tick: tick, Rule: rule, Agents: agents, Detail: detail})
+	}
+
+	locations := make(map[string][]string) // train ID -> segments and stations it is registered on
+
+	for _, state := range segmentStates {
+		trainIDs := make([]string, 0, len(state.Trains))
+		for trainID, train := range state.Trains {
+			trainIDs = append(trainIDs, trainID)
+			locations[trainID] = append(locations[trainID], state.SegmentID)
+
+			if train.Position < 0 || train.Position > state.Length {
+				report(RulePositionBounds,
+					fmt.Sprintf("position %.1f m outside [0, %.1f m]", train.Position, state.Length),
+					trainID, state.SegmentID)
+			}
+		}
+
+		// Sort by position to compare each train with the one ahead
+		sort.Slice(trainIDs, func(i, j int) bool {
+			return state.Trains[trainIDs[i]].Position < state.Trains[trainIDs[j]].Position
+		})
+		for i := 1; i < len(trainIDs); i++ {
+			behind, ahead := trainIDs[i-1], trainIDs[i]
+			gap := state.Trains[ahead].Position - state.Trains[behind].Position
+			if gap < constants.SafetyDistance {
+				report(RuleSafetyDistance,
+					fmt.Sprintf("gap of %.1f m, below %.0f m", gap, constants.SafetyDistance),
+					behind, ahead, state.SegmentID)
+			}
+		}
+	}
+
+	for _, state := range stationStates {
+		for _, trainID := range state.Trains {
+			locations[trainID] = append(locations[trainID], state.StationID)
+		}
+
+		if len(state.Trains) > state.Capacity {
+			report(RuleStationCapacity,
+				fmt.Sprintf("%d trains for a capacity of %d", len(state.Trains), state.Capacity),
+				append([]string{state.StationID}, state.Trains...)...)
+		}
+	}
+
+	for trainID, places := range locations {
+		if len(places) > 1 {
+			sort.Strings(places)
+			report(RuleSingleLocation,
+				fmt.Sprintf("registered on %d places at once", len(places)),
+				append([]string{trainID}, places...)...)
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].Rule != violations[j].Rule {
+			return violations[i].Rule < violations[j].Rule
+		}
+		return strings.Join(violations[i].Agents, ",") < strings.Join(violations[j].Agents, ",")
+	})
+	return violations
+}
@@ -0,0 +1,78 @@
+package invariants_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"ai30-project/internal/invariants"
+	"ai30-project/internal/segments"
+	"ai30-project/internal/stations"
+)
+
+// TestCheck feeds each rule a state breaking it, and checks it is the only
+// one reported, with the agents involved.
+func TestCheck(t *testing.T) {
+	segment := func(trains map[string]segments.TrainPosition) []segments.State {
+		return []segments.State{{SegmentID: "A-B", Length: 30000, Trains: trains}}
+	}
+	station := func(capacity int, trains ...string) []stations.State {
+		return []stations.State{{StationID: "B", Capacity: capacity, Trains: trains}}
+	}
+
+	cases := []struct {
+		name       string
+		segments   []segments.State
+		stations   []stations.State
+		wantRule   invariants.Rule
+		wantAgents []string
+	}{
+		{
+			name:       "trains too close",
+			segments:   segment(map[string]segments.TrainPosition{"T1": {Position: 12000}, "T2": {Position: 12500}}),
+			wantRule:   invariants.RuleSafetyDistance,
+			wantAgents: []string{"T1", "T2", "A-B"},
+		},
+		{
+			name:       "station overfull",
+			stations:   station(1, "T1", "T2"),
+			wantRule:   invariants.RuleStationCapacity,
+			wantAgents: []string{"B", "T1", "T2"},
+		},
+		{
+			name:       "train on a segment and in a station",
+			segments:   segment(map[string]segments.TrainPosition{"T1": {Position: 29000}}),
+			stations:   station(2, "T1"),
+			wantRule:   invariants.RuleSingleLocation,
+			wantAgents: []string{"T1", "A-B", "B"},
+		},
+		{
+			name:       "train past the segment end",
+			segments:   segment(map[string]segments.TrainPosition{"T1": {Position: 30078.3}}),
+			wantRule:   invariants.RulePositionBounds,
+			wantAgents: []string{"T1", "A-B"},
+		},
+		{
+			name:       "train before the segment start",
+			segments:   segment(map[string]segments.TrainPosition{"T1": {Position: -1}}),
+			wantRule:   invariants.RulePositionBounds,
+			wantAgents: []string{"T1", "A-B"},
+		},
+	}
+	for _, c := range cases {
+		violations := invariants.Check(8*time.Hour, c.segments, c.stations)
+		if len(violations) != 1 {
+			t.Errorf("%s: %d violations %v, want one", c.name, len(violations), violations)
+			continue
+		}
+		if v := violations[0]; v.Rule != c.wantRule || !reflect.DeepEqual(v.Agents, c.wantAgents) || v.Tick != 8*time.Hour {
+			t.Errorf("%s: %s by %v at %v, want %s by %v", c.name, v.Rule, v.Agents, v.Tick, c.wantRule, c.wantAgents)
+		}
+	}
+
+	// Trains kept apart, within bounds and in stations with room break nothing
+	valid := segment(map[string]segments.TrainPosition{"T1": {Position: 0}, "T2": {Position: 15000}, "T3": {Position: 30000}})
+	if violations := invariants.Check(8*time.Hour, valid, station(1, "T4")); len(violations) != 0 {
+		t.Errorf("valid state reported %v", violations)
+	}
+}
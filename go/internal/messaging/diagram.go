@@ -0,0 +1,122 @@
+package messaging
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"ai30-project/internal/clock"
+)
+
+// PlantUML renders records as a sequence diagram, with a divider at each
+// tick. Requests are synchronous arrows answered by their response;
+// notifications are asynchronous arrows.
+func PlantUML(records []Record) string {
+	var b strings.Builder
+	b.WriteString("@startuml\n")
+
+	aliases := participants(records)
+	for _, name := range aliases.names {
+		fmt.Fprintf(&b, "participant %q as %s\n", label(name), aliases.ids[name])
+	}
+
+	forEachTick(records, func(tick time.Duration) {
+		fmt.Fprintf(&b, "== %s ==\n", clock.Format(tick))
+	}, func(r Record) {
+		from, to := aliases.ids[r.From], aliases.ids[r.To]
+		if !r.Request {
+			fmt.Fprintf(&b, "%s ->> %s : %s\n", from, to, r.Content)
+			return
+		}
+		fmt.Fprintf(&b, "%s -> %s : %s\n", from, to, r.Content)
+		if r.Err != nil {
+			fmt.Fprintf(&b, "%s -->x %s : %v\n", to, from, r.Err)
+		} else {
+			fmt.Fprintf(&b, "%s --> %s : %s\n", to, from, r.Response)
+		}
+	})
+
+	b.WriteString("@enduml\n")
+	return b.String()
+}
+
+// Mermaid renders records as a Mermaid sequence diagram, with a note at each
+// tick.
+func Mermaid(records []Record) string {
+	var b strings.Builder
+	b.WriteString("sequenceDiagram\n")
+
+	aliases := participants(records)
+	for _, name := range aliases.names {
+		fmt.Fprintf(&b, "  participant %s as %s\n", aliases.ids[name], mermaidText(label(name)))
+	}
+
+	span := ""
+	if n := len(aliases.names); n > 0 {
+		span = aliases.ids[aliases.names[0]]
+		if n > 1 {
+			span += "," + aliases.ids[aliases.names[n-1]]
+		}
+	}
+
+	forEachTick(records, func(tick time.Duration) {
+		fmt.Fprintf(&b, "  Note over %s: %s\n", span, clock.Format(tick))
+	}, func(r Record) {
+		from, to := aliases.ids[r.From], aliases.ids[r.To]
+		if !r.Request {
+			fmt.Fprintf(&b, "  %s-)%s: %s\n", from, to, mermaidText(r.Content))
+			return
+		}
+		fmt.Fprintf(&b, "  %s->>%s: %s\n", from, to, mermaidText(r.Content))
+		if r.Err != nil {
+			fmt.Fprintf(&b, "  %s--x%s: %s\n", to, from, mermaidText(r.Err.Error()))
+		} else {
+			fmt.Fprintf(&b, "  %s-->>%s: %s\n", to, from, mermaidText(r.Response))
+		}
+	})
+
+	return b.String()
+}
+
+type aliases struct {
+	names []string          // in order of appearance
+	ids   map[string]string // name -> diagram identifier
+}
+
+func participants(records []Record) aliases {
+	a := aliases{ids: make(map[string]string)}
+	for _, r := range records {
+		for _, name := range []string{r.From, r.To} {
+			if _, ok := a.ids[name]; !ok {
+				a.ids[name] = fmt.Sprintf("P%d", len(a.names)+1)
+				a.names = append(a.names, name)
+			}
+		}
+	}
+	return a
+}
+
+func forEachTick(records []Record, tick func(time.Duration), record func(Record)) {
+	for i, r := range records {
+		if i == 0 || r.Time != records[i-1].Time {
+			tick(r.Time)
+		}
+		record(r)
+	}
+}
+
+// label keeps the service number of a train, whose full SNCF ID would make
+// its lifeline unreadable.
+func label(name string) string {
+	if id, ok := strings.CutPrefix(name, "train "); ok {
+		service, _, _ := strings.Cut(id, ":")
+		return "train " + service
+	}
+	return name
+}
+
+// mermaidText escapes the characters ending a statement or starting an
+// entity in Mermaid.
+func mermaidText(text string) string {
+	return strings.NewReplacer("#", "#35;", ";", "#59;").Replace(text)
+}
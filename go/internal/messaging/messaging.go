@@ -0,0 +1,160 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultTimeout bounds how long an exchange with an agent may take. Agents
+// answer in microseconds, so reaching it means the agent is stuck or gone.
+const DefaultTimeout = 5 * time.Second
+
+var (
+	ErrTimeout      = errors.New("no response before the deadline")
+	ErrUnknownAgent = errors.New("unknown agent")
+	ErrAgentStopped = errors.New("agent stopped")
+)
+
+// AgentError is a failed exchange with an agent. Its cause is one of
+// ErrTimeout, ErrUnknownAgent or ErrAgentStopped.
+type AgentError struct {
+	Agent string
+	Err   error
+}
+
+func (e *AgentError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Agent, e.Err)
+}
+
+func (e *AgentError) Unwrap() error {
+	return e.Err
+}
+
+// Unknown is the error of an exchange with an agent that does not exist.
+func Unknown(agent string) error {
+	return &AgentError{Agent: agent, Err: ErrUnknownAgent}
+}
+
+// Request sends msg to the inbox of agent and waits for the response on
+// responseCh. responseCh should be buffered, so that an agent answering after
+// the deadline does not block. Stopping ctx stops waiting.
+func Request[M, R any](ctx context.Context, agent string, inbox chan<- M, msg M, responseCh <-chan R, timeout time.Duration) (R, error) {
+	var zero R
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case inbox <- msg:
+	case <-ctx.Done():
+		return zero, &AgentError{Agent: agent, Err: ErrAgentStopped}
+	case <-timer.C:
+		return zero, &AgentError{Agent: agent, Err: ErrTimeout}
+	}
+
+	select {
+	case response := <-responseCh:
+		return response, nil
+	case <-ctx.Done():
+		return zero, &AgentError{Agent: agent, Err: ErrAgentStopped}
+	case <-timer.C:
+		return zero, &AgentError{Agent: agent, Err: ErrTimeout}
+	}
+}
+
+// Notify sends msg to the inbox of agent without waiting for an answer. It
+// only waits when the inbox is full.
+func Notify[M any](ctx context.Context, agent string, inbox chan<- M, msg M, timeout time.Duration) error {
+	select {
+	case inbox <- msg:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case inbox <- msg:
+		return nil
+	case <-ctx.Done():
+		return &AgentError{Agent: agent, Err: ErrAgentStopped}
+	case <-timer.C:
+		return &AgentError{Agent: agent, Err: ErrTimeout}
+	}
+}
+
+// Call is Request for an agent that does not run on its own goroutine:
+// deliver has it handle the messages waiting in its inbox, so the response is
+// there once it returns, or never.
+func Call[M, R any](agent string, inbox chan<- M, msg M, responseCh <-chan R, deliver func()) (R, error) {
+	var zero R
+
+	if err := Post(agent, inbox, msg, deliver); err != nil {
+		return zero, err
+	}
+	deliver()
+
+	select {
+	case response := <-responseCh:
+		return response, nil
+	default:
+		return zero, &AgentError{Agent: agent, Err: ErrTimeout}
+	}
+}
+
+// Post is Notify for an agent that does not run on its own goroutine. A full
+// inbox is delivered first to make room.
+func Post[M any](agent string, inbox chan<- M, msg M, deliver func()) error {
+	select {
+	case inbox <- msg:
+		return nil
+	default:
+	}
+
+	deliver()
+
+	select {
+	case inbox <- msg:
+		return nil
+	default:
+		return &AgentError{Agent: agent, Err: ErrTimeout}
+	}
+}
+
+// Serve is the loop of an agent running on its own goroutine: it handles the
+// messages of inbox one at a time until the inbox is closed or ctx is
+// cancelled.
+func Serve[M any](ctx context.Context, inbox <-chan M, handle func(M)) {
+	for {
+		var (
+			msg M
+			ok  bool
+		)
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok = <-inbox:
+		}
+		if !ok {
+			return
+		}
+
+		handle(msg)
+	}
+}
+
+// Drain handles the messages already in inbox and returns how many, for an
+// agent that does not run on its own goroutine.
+func Drain[M any](inbox <-chan M, handle func(M)) int {
+	for handled := 0; ; handled++ {
+		select {
+		case msg := <-inbox:
+			handle(msg)
+		default:
+			return handled
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package messaging
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record is a message sent to an agent, with the response for a request.
+type Record struct {
+	Seq      int
+	Time     time.Duration
+	From     string
+	To       string
+	Message  string // type of the message, e.g. EntryRequest
+	Content  string
+	Request  bool // the sender waited for a response
+	Response string
+	Err      error // why the exchange failed
+}
+
+func (r Record) MarshalJSON() ([]byte, error) {
+	var err any
+	if r.Err != nil {
+		err = r.Err.Error()
+	}
+	return json.Marshal(map[string]any{
+		"seq":      r.Seq,
+		"time":     r.Time,
+		"from":     r.From,
+		"to":       r.To,
+		"message":  r.Message,
+		"content":  r.Content,
+		"request":  r.Request,
+		"response": r.Response,
+		"error":    err,
+	})
+}
+
+// Tap records the messages exchanged between agents, stamped with the
+// simulation time. A nil Tap records nothing.
+type Tap struct {
+	mu      sync.Mutex
+	now     time.Duration
+	records []Record
+}
+
+func NewTap() *Tap {
+	return &Tap{}
+}
+
+// SetTime stamps the next records.
+func (tap *Tap) SetTime(now time.Duration) {
+	if tap == nil {
+		return
+	}
+	tap.mu.Lock()
+	defer tap.mu.Unlock()
+	tap.now = now
+}
+
+// Request records a request and what came back.
+func (tap *Tap) Request(from, to string, msg, response any, err error) {
+	if tap == nil {
+		return
+	}
+	record := Record{From: from, To: to, Message: typeName(msg), Content: Describe(msg), Request: true, Err: err}
+	if err == nil {
+		record.Response = Describe(response)
+	}
+	tap.add(record)
+}
+
+// Notification records a message that expects no response.
+func (tap *Tap) Notification(from, to string, msg any, err error) {
+	if tap == nil {
+		return
+	}
+	tap.add(Record{From: from, To: to, Message: typeName(msg), Content: Describe(msg), Err: err})
+}
+
+func (tap *Tap) add(record Record) {
+	tap.mu.Lock()
+	defer tap.mu.Unlock()
+	record.Seq = len(tap.records)
+	record.Time = tap.now
+	tap.records = append(tap.records, record)
+}
+
+// Records returns every message recorded so far, in the order they were sent.
+func (tap *Tap) Records() []Record {
+	if tap == nil {
+		return nil
+	}
+	tap.mu.Lock()
+	defer tap.mu.Unlock()
+	return append([]Record(nil), tap.records...)
+}
+
+// Filter selects records by agent, message type and time window. Empty
+// fields select everything.
+type Filter struct {
+	Agent   string        // sender or receiver, e.g. "train X" or "station B"
+	Message string        // message type, e.g. EntryRequest
+	From    time.Duration // first tick, inclusive
+	Until   time.Duration // last tick, inclusive
+}
+
+func (f Filter) Apply(records []Record) []Record {
+	var kept []Record
+	for _, r := range records {
+		if f.Agent != "" && r.From != f.Agent && r.To != f.Agent {
+			continue
+		}
+		if f.Message != "" && r.Message != f.Message {
+			continue
+		}
+		if r.Time < f.From || (f.Until > 0 && r.Time > f.Until) {
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// Describe renders a message or a response with its fields, leaving out
+// response channels and empty references. Floats keep six significant digits.
+func Describe(v any) string {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Struct {
+		return fmt.Sprint(v)
+	}
+
+	var fields []string
+	for i := range value.NumField() {
+		field := value.Field(i)
+		switch field.Kind() {
+		case reflect.Chan, reflect.Func:
+			continue
+		case reflect.Interface, reflect.Pointer, reflect.Slice, reflect.Map:
+			if field.IsNil() {
+				continue
+			}
+		}
+		if !value.Type().Field(i).IsExported() {
+			continue
+		}
+		format := "%s: %v"
+		if field.Kind() == reflect.Float64 {
+			format = "%s: %.6g"
+		}
+		fields = append(fields, fmt.Sprintf(format, value.Type().Field(i).Name, field.Interface()))
+	}
+	return typeName(v) + "{" + strings.Join(fields, ", ") + "}"
+}
+
+func typeName(v any) string {
+	return reflect.TypeOf(v).Name()
+}
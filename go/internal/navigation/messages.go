@@ -1,6 +1,11 @@
 package navigation
 
-import "fmt"
+import (
+	"ai30-project/internal/constants"
+	"ai30-project/internal/segments"
+	"fmt"
+	"time"
+)
 
 type NavigationMessage interface {
 	isMessage()
@@ -9,25 +14,98 @@ type NavigationMessage interface {
 type PathRequest struct {
 	FromStation string
 	ToStation   string
+	Avoid       []string // segments to keep off as if closed, such as an occupied one
 	ResponseCh  chan PathResponse
 }
 
 func (PathRequest) isMessage() {}
 
 type SegmentInfo struct {
-	ID       string
-	Length   float64
-	MaxSpeed float64
+	ID            string
+	FromStationID string
+	ToStationID   string
+	Length        float64 // meters
+	MaxSpeed      float64 // m/min
+}
+
+// LineSpeed is the max speed of the segment in m/s, the unit trains run in.
+func (s SegmentInfo) LineSpeed() float64 {
+	return s.MaxSpeed * constants.MPerMinToMS
 }
 
 type PathResponse struct {
 	Segments []SegmentInfo
-	Error    error
+	// Diverted is set when the usual path crosses a closed or avoided segment
+	// and the returned one does not; AddedRunningTime is the extra time at
+	// line speed.
+	Diverted         bool
+	AddedRunningTime time.Duration
+	Error            error
 }
 
 func (n *NavigationService) handlePathRequest(req PathRequest) {
-	var segments []SegmentInfo
-	currentStation := req.FromStation
+	path, err := n.staticPath(req.FromStation, req.ToStation)
+	if err != nil {
+		req.ResponseCh <- PathResponse{Segments: nil, Error: err}
+		return
+	}
+
+	avoid := make(map[string]bool, len(req.Avoid))
+	for _, segmentID := range req.Avoid {
+		avoid[segmentID] = true
+	}
+
+	response := PathResponse{}
+	if n.crossesClosedSegment(path, avoid) {
+		detour, err := n.detour(req.FromStation, req.ToStation, avoid)
+		if err != nil {
+			req.ResponseCh <- PathResponse{Segments: nil, Error: err}
+			return
+		}
+
+		response.Diverted = true
+		for _, segment := range detour {
+			response.AddedRunningTime += runningTime(segment)
+		}
+		for _, segment := range path {
+			response.AddedRunningTime -= runningTime(segment)
+		}
+		path = detour
+	}
+
+	for _, segment := range path {
+		response.Segments = append(response.Segments, SegmentInfo{
+			ID:            segment.ID(),
+			FromStationID: segment.FromStationID(),
+			ToStationID:   segment.ToStationID(),
+			Length:        segment.Length(),
+			MaxSpeed:      segment.MaxSpeed(),
+		})
+	}
+
+	req.ResponseCh <- response
+}
+
+// ScheduledPath returns the IDs of the segments on the usual path between two
+// stations, ignoring closures. It only reads the static routing table, so it
+// may be called before Run.
+func (n *NavigationService) ScheduledPath(fromStation, toStation string) ([]string, error) {
+	path, err := n.staticPath(fromStation, toStation)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(path))
+	for i, segment := range path {
+		ids[i] = segment.ID()
+	}
+	return ids, nil
+}
+
+// staticPath follows the next-hop table from one station to another.
+func (n *NavigationService) staticPath(fromStation, toStation string) ([]*segments.Segment, error) {
+	var path []*segments.Segment
+	currentStation := fromStation
 
 	// Track visited stations to detect infinite loops (cycles) immediately
 	visited := make(map[string]bool)
@@ -36,63 +114,62 @@ func (n *NavigationService) handlePathRequest(req PathRequest) {
 	maxIterations := 100
 
 	for range maxIterations {
-		if currentStation == req.ToStation {
-			req.ResponseCh <- PathResponse{
-				Segments: segments,
-				Error:    nil,
-			}
-			return
+		if currentStation == toStation {
+			return path, nil
 		}
 
 		// 1. Cycle Detection: If we've been here before, we are in a loop
 		if visited[currentStation] {
-			req.ResponseCh <- PathResponse{
-				Segments: nil,
-				Error:    fmt.Errorf("infinite loop detected at station %s while routing to %s", currentStation, req.ToStation),
-			}
-			return
+			return nil, fmt.Errorf("infinite loop detected at station %s while routing to %s", currentStation, toStation)
 		}
 		visited[currentStation] = true
 
 		toMap, exists := n.paths[currentStation]
 		if !exists {
-			req.ResponseCh <- PathResponse{
-				Segments: nil,
-				Error:    fmt.Errorf("dead end: no outgoing paths found from station %s", currentStation),
-			}
-			return
+			return nil, fmt.Errorf("dead end: no outgoing paths found from station %s", currentStation)
 		}
 
-		segmentID, exists := toMap[req.ToStation]
+		segmentID, exists := toMap[toStation]
 		if !exists {
-			req.ResponseCh <- PathResponse{
-				Segments: nil,
-				Error:    fmt.Errorf("routing error: no path from %s knows how to reach %s", currentStation, req.ToStation),
-			}
-			return
+			return nil, fmt.Errorf("routing error: no path from %s knows how to reach %s", currentStation, toStation)
 		}
 
 		segment, exists := n.segments[segmentID]
 		if !exists {
-			req.ResponseCh <- PathResponse{
-				Segments: nil,
-				Error:    fmt.Errorf("data integrity error: segment %s (from %s to %s) is missing from segment database", segmentID, currentStation, req.ToStation),
-			}
-			return
+			return nil, fmt.Errorf("data integrity error: segment %s (from %s to %s) is missing from segment database", segmentID, currentStation, toStation)
 		}
 
-		segments = append(segments, SegmentInfo{
-			ID:       segment.ID(),
-			Length:   segment.Length(),
-			MaxSpeed: segment.MaxSpeed(),
-		})
+		path = append(path, segment)
 
 		// Move to the next station in the chain
 		currentStation = segment.ToStationID()
 	}
 
-	req.ResponseCh <- PathResponse{
-		Segments: nil,
-		Error:    fmt.Errorf("path too long: exceeded %d segments from %s to %s", maxIterations, req.FromStation, req.ToStation),
+	return nil, fmt.Errorf("path too long: exceeded %d segments from %s to %s", maxIterations, fromStation, toStation)
+}
+
+func (n *NavigationService) crossesClosedSegment(path []*segments.Segment, avoid map[string]bool) bool {
+	for _, segment := range path {
+		if n.closed[segment.ID()] || avoid[segment.ID()] {
+			return true
+		}
+	}
+	return false
+}
+
+// SegmentStatusNotification tells the navigation service that a segment was
+// closed or reopened.
+type SegmentStatusNotification struct {
+	SegmentID string
+	Closed    bool
+}
+
+func (SegmentStatusNotification) isMessage() {}
+
+func (n *NavigationService) handleSegmentStatus(notif SegmentStatusNotification) {
+	if notif.Closed {
+		n.closed[notif.SegmentID] = true
+	} else {
+		delete(n.closed, notif.SegmentID)
 	}
 }
@@ -1,7 +1,9 @@
 package navigation
 
 import (
+	"ai30-project/internal/messaging"
 	"ai30-project/internal/segments"
+	"context"
 	"fmt"
 )
 
@@ -10,6 +12,8 @@ type Paths map[string]map[string]string
 type NavigationService struct {
 	paths    Paths
 	segments map[string]*segments.Segment
+	graph    map[string][]*segments.Segment // station ID -> outgoing segments
+	closed   map[string]bool
 	inbox    chan NavigationMessage
 }
 
@@ -17,6 +21,7 @@ func NewNavigationService(paths Paths, segments map[string]*segments.Segment) *N
 	return &NavigationService{
 		paths:    paths,
 		segments: segments,
+		closed:   make(map[string]bool),
 		inbox:    make(chan NavigationMessage, 100),
 	}
 }
@@ -25,18 +30,22 @@ func (n *NavigationService) Inbox() chan NavigationMessage {
 	return n.inbox
 }
 
-func (n *NavigationService) Run() {
-	for {
-		msg, ok := <-n.inbox
-		if !ok {
-			return
-		}
-
-		switch m := msg.(type) {
-		case PathRequest:
-			n.handlePathRequest(m)
-		default:
-			fmt.Printf("  [Navigation] ERROR: Unknown message type\n")
-		}
+func (n *NavigationService) Run(ctx context.Context) {
+	messaging.Serve(ctx, n.inbox, n.handle)
+}
+
+// Drain handles the messages already in the inbox and returns how many.
+func (n *NavigationService) Drain() int {
+	return messaging.Drain(n.inbox, n.handle)
+}
+
+func (n *NavigationService) handle(msg NavigationMessage) {
+	switch m := msg.(type) {
+	case PathRequest:
+		n.handlePathRequest(m)
+	case SegmentStatusNotification:
+		n.handleSegmentStatus(m)
+	default:
+		fmt.Printf("  [Navigation] ERROR: Unknown message type\n")
 	}
 }
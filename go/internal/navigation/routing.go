@@ -0,0 +1,100 @@
+package navigation
+
+import (
+	"ai30-project/internal/segments"
+	"container/heap"
+	"fmt"
+	"time"
+)
+
+// runningTime estimates the time needed to run a segment at line speed.
+func runningTime(segment *segments.Segment) time.Duration {
+	if segment.MaxSpeed() <= 0 {
+		return 0
+	}
+	return time.Duration(segment.Length() / segment.MaxSpeed() * float64(time.Minute)) // maxSpeed in m/min
+}
+
+func (n *NavigationService) outgoing(stationID string) []*segments.Segment {
+	if n.graph == nil {
+		n.graph = make(map[string][]*segments.Segment)
+		for _, segment := range n.segments {
+			n.graph[segment.FromStationID()] = append(n.graph[segment.FromStationID()], segment)
+		}
+	}
+	return n.graph[stationID]
+}
+
+type routeNode struct {
+	stationID string
+	cost      time.Duration
+	index     int
+}
+
+type routeQueue []*routeNode
+
+func (q routeQueue) Len() int { return len(q) }
+func (q routeQueue) Less(i, j int) bool {
+	if q[i].cost != q[j].cost {
+		return q[i].cost < q[j].cost
+	}
+	return q[i].stationID < q[j].stationID
+}
+func (q routeQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+func (q *routeQueue) Push(x any) {
+	node := x.(*routeNode)
+	node.index = len(*q)
+	*q = append(*q, node)
+}
+func (q *routeQueue) Pop() any {
+	old := *q
+	node := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return node
+}
+
+// detour finds the fastest path between two stations using open segments
+// only, and none of those to avoid.
+func (n *NavigationService) detour(fromStation, toStation string, avoid map[string]bool) ([]*segments.Segment, error) {
+	best := map[string]time.Duration{fromStation: 0}
+	via := make(map[string]*segments.Segment)
+	queue := &routeQueue{{stationID: fromStation}}
+
+	for queue.Len() > 0 {
+		node := heap.Pop(queue).(*routeNode)
+		if node.cost > best[node.stationID] {
+			continue
+		}
+		if node.stationID == toStation {
+			break
+		}
+
+		for _, segment := range n.outgoing(node.stationID) {
+			if n.closed[segment.ID()] || avoid[segment.ID()] {
+				continue
+			}
+			next := segment.ToStationID()
+			cost := node.cost + runningTime(segment)
+			if known, seen := best[next]; seen && known <= cost {
+				continue
+			}
+			best[next] = cost
+			via[next] = segment
+			heap.Push(queue, &routeNode{stationID: next, cost: cost})
+		}
+	}
+
+	if _, reached := best[toStation]; !reached {
+		return nil, fmt.Errorf("no open path from %s to %s", fromStation, toStation)
+	}
+
+	var path []*segments.Segment
+	for station := toStation; station != fromStation; station = via[station].FromStationID() {
+		path = append([]*segments.Segment{via[station]}, path...)
+	}
+	return path, nil
+}
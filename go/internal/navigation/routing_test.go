@@ -0,0 +1,66 @@
+package navigation
+
+import (
+	"testing"
+	"time"
+
+	"ai30-project/internal/constants"
+	"ai30-project/internal/segments"
+)
+
+// detourService leads from A to B directly or by X, every link 30 km long.
+func detourService() *NavigationService {
+	network := make(map[string]*segments.Segment)
+	for _, id := range []string{"A-B", "A-X", "X-B"} {
+		network[id] = segments.NewSegment(id, id[:1], id[2:], 30000, 160*constants.KmHToMPerMin)
+	}
+	paths := Paths{
+		"A": {"B": "A-B", "X": "A-X"},
+		"X": {"B": "X-B"},
+	}
+	return NewNavigationService(paths, network)
+}
+
+func path(n *NavigationService, avoid ...string) PathResponse {
+	responseCh := make(chan PathResponse, 1)
+	n.Inbox() <- PathRequest{FromStation: "A", ToStation: "B", Avoid: avoid, ResponseCh: responseCh}
+	n.Drain()
+	return <-responseCh
+}
+
+func segmentIDs(response PathResponse) []string {
+	var ids []string
+	for _, segment := range response.Segments {
+		ids = append(ids, segment.ID)
+	}
+	return ids
+}
+
+func TestDetour(t *testing.T) {
+	n := detourService()
+	if got := path(n); got.Diverted || len(got.Segments) != 1 || got.Error != nil {
+		t.Errorf("usual path %v (diverted: %v, error: %v), want A-B", segmentIDs(got), got.Diverted, got.Error)
+	}
+
+	n.Inbox() <- SegmentStatusNotification{SegmentID: "A-B", Closed: true}
+	// The detour is one link longer, run in 675 s at 160 km/h
+	got := path(n)
+	if ids := segmentIDs(got); !got.Diverted || len(ids) != 2 || ids[0] != "A-X" || ids[1] != "X-B" {
+		t.Errorf("path %v around the closed A-B (diverted: %v), want A-X, X-B", ids, got.Diverted)
+	}
+	if got.AddedRunningTime != 675*time.Second {
+		t.Errorf("detour adds %v, want 11m15s", got.AddedRunningTime)
+	}
+
+	if got := path(n, "X-B"); got.Error == nil {
+		t.Errorf("path %v with every way to B closed or avoided, want an error", segmentIDs(got))
+	}
+
+	n.Inbox() <- SegmentStatusNotification{SegmentID: "A-B", Closed: false}
+	if got := path(n); got.Diverted {
+		t.Errorf("path %v diverted once A-B reopened", segmentIDs(got))
+	}
+	if got := path(n, "A-B"); !got.Diverted {
+		t.Errorf("path %v crosses the avoided A-B", segmentIDs(got))
+	}
+}
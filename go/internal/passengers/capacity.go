@@ -0,0 +1,82 @@
+package passengers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"ai30-project/internal/constants"
+)
+
+// Capacity is how many passengers a train carries, seated and standing.
+type Capacity struct {
+	Seats    int `json:"seats"`
+	Standing int `json:"standing"`
+}
+
+func (c Capacity) check() error {
+	if c.Seats < 0 || c.Standing < 0 {
+		return fmt.Errorf("capacity cannot be negative")
+	}
+	return nil
+}
+
+// Capacities give the capacity of every train: the one listed for it in
+// Trains, Default otherwise.
+type Capacities struct {
+	Default Capacity
+	Trains  map[string]Capacity // train ID -> capacity
+}
+
+// DefaultCapacities give every train the default capacity of the rolling stock.
+func DefaultCapacities() *Capacities {
+	return &Capacities{
+		Default: Capacity{Seats: constants.DefaultSeatCapacity, Standing: constants.DefaultStandingCapacity},
+		Trains:  map[string]Capacity{},
+	}
+}
+
+// Of returns the capacity of a train.
+func (c *Capacities) Of(trainID string) Capacity {
+	if capacity, ok := c.Trains[trainID]; ok {
+		return capacity
+	}
+	return c.Default
+}
+
+type capacityFile struct {
+	Default *Capacity           `json:"default"`
+	Trains  map[string]Capacity `json:"trains"`
+}
+
+// LoadCapacities reads the capacity of the trains from a JSON file. Trains
+// not listed take the default, itself the default rolling stock when left out:
+//
+//	{"default": {"seats": 500, "standing": 100}, "trains": {"<train>": {"seats": 556, "standing": 80}}}
+func LoadCapacities(path string) (*Capacities, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading capacity file: %w", err)
+	}
+
+	var file capacityFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("parsing capacity file: %w", err)
+	}
+
+	capacities := DefaultCapacities()
+	if file.Default != nil {
+		if err := file.Default.check(); err != nil {
+			return nil, fmt.Errorf("default: %w", err)
+		}
+		capacities.Default = *file.Default
+	}
+	for trainID, capacity := range file.Trains {
+		if err := capacity.check(); err != nil {
+			return nil, fmt.Errorf("train %s: %w", trainID, err)
+		}
+		capacities.Trains[trainID] = capacity
+	}
+
+	return capacities, nil
+}
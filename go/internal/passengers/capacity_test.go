@@ -0,0 +1,50 @@
+package passengers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ai30-project/internal/constants"
+)
+
+func loadCapacities(t *testing.T, content string) (*Capacities, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "capacity.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadCapacities(path)
+}
+
+func TestLoadCapacities(t *testing.T) {
+	capacities, err := loadCapacities(t, `{"default": {"seats": 300, "standing": 20}, "trains": {"T1": {"seats": 556, "standing": 80}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := capacities.Of("T1"), (Capacity{Seats: 556, Standing: 80}); got != want {
+		t.Errorf("T1 carries %+v, want %+v", got, want)
+	}
+	if got, want := capacities.Of("T2"), (Capacity{Seats: 300, Standing: 20}); got != want {
+		t.Errorf("T2 carries %+v, want the default %+v", got, want)
+	}
+
+	// Without a default, the other trains keep the default rolling stock
+	capacities, err = loadCapacities(t, `{"trains": {"T1": {"seats": 556, "standing": 80}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := capacities.Of("T2"), (Capacity{Seats: constants.DefaultSeatCapacity, Standing: constants.DefaultStandingCapacity}); got != want {
+		t.Errorf("T2 carries %+v, want %+v", got, want)
+	}
+
+	for _, invalid := range []string{
+		`{"default": {"seats": -1, "standing": 20}}`,
+		`{"trains": {"T1": {"seats": 556, "standing": -80}}}`,
+		`{"trains": []}`,
+	} {
+		if _, err := loadCapacities(t, invalid); err == nil {
+			t.Errorf("capacity file %s accepted", invalid)
+		}
+	}
+}
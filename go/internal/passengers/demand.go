@@ -0,0 +1,157 @@
+package passengers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"ai30-project/internal/clock"
+)
+
+// Window holds the number of passengers travelling between each origin and
+// destination during [Start, End).
+type Window struct {
+	Start time.Duration
+	End   time.Duration
+	Flows map[string]map[string]int // origin -> destination -> passengers
+}
+
+type Demand struct {
+	Windows []Window
+
+	indexOnce   sync.Once
+	appearances []time.Duration // of every passenger, sorted
+}
+
+type demandFile struct {
+	Windows []struct {
+		Start string                    `json:"start"`
+		End   string                    `json:"end"`
+		Flows map[string]map[string]int `json:"flows"`
+	} `json:"windows"`
+}
+
+// LoadDemand reads an origin-destination matrix per time window from a JSON file:
+//
+//	{"windows": [{"start": "06:00", "end": "07:00", "flows": {"<origin>": {"<destination>": 120}}}]}
+func LoadDemand(path string) (*Demand, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading demand file: %w", err)
+	}
+
+	var file demandFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("parsing demand file: %w", err)
+	}
+
+	demand := &Demand{}
+	for i, w := range file.Windows {
+		start, err := clock.Parse(w.Start)
+		if err != nil {
+			return nil, fmt.Errorf("window %d: %w", i, err)
+		}
+		end, err := clock.Parse(w.End)
+		if err != nil {
+			return nil, fmt.Errorf("window %d: %w", i, err)
+		}
+		if end <= start {
+			return nil, fmt.Errorf("window %d: end %s is not after start %s", i, w.End, w.Start)
+		}
+
+		demand.Windows = append(demand.Windows, Window{Start: start, End: end, Flows: w.Flows})
+	}
+
+	return demand, nil
+}
+
+// appearance returns when the k-th of n passengers of a window shows up at
+// the station. Passengers are spread evenly so runs stay reproducible.
+func (w Window) appearance(k, n int) time.Duration {
+	length := w.End - w.Start
+	return w.Start + time.Duration(float64(length)*(float64(k)+0.5)/float64(n))
+}
+
+// CountUntil returns how many passengers have appeared anywhere by currentTime.
+func (d *Demand) CountUntil(currentTime time.Duration) int {
+	if d == nil {
+		return 0
+	}
+
+	d.indexOnce.Do(d.index)
+	return sort.Search(len(d.appearances), func(i int) bool {
+		return d.appearances[i] > currentTime
+	})
+}
+
+// index sorts the appearances of every passenger, so that counting them is a
+// binary search.
+func (d *Demand) index() {
+	for _, w := range d.Windows {
+		for _, flows := range w.Flows {
+			for _, n := range flows {
+				for k := range n {
+					d.appearances = append(d.appearances, w.appearance(k, n))
+				}
+			}
+		}
+	}
+	sort.Slice(d.appearances, func(i, j int) bool {
+		return d.appearances[i] < d.appearances[j]
+	})
+}
+
+// Source releases the passengers of one origin station as time passes.
+type Source struct {
+	origin   string
+	demand   *Demand
+	released map[int]map[string]int // window index -> destination -> passengers released
+}
+
+func NewSource(origin string, demand *Demand) *Source {
+	return &Source{
+		origin:   origin,
+		demand:   demand,
+		released: make(map[int]map[string]int),
+	}
+}
+
+// Release returns the passengers that appeared since the previous call, up to
+// currentTime, ordered by appearance.
+func (s *Source) Release(currentTime time.Duration) []*Passenger {
+	var appeared []*Passenger
+
+	for i, w := range s.demand.Windows {
+		flows := w.Flows[s.origin]
+		if len(flows) == 0 || w.Start > currentTime {
+			continue
+		}
+		if s.released[i] == nil {
+			s.released[i] = make(map[string]int)
+		}
+
+		for destination, n := range flows {
+			k := s.released[i][destination]
+			for ; k < n && w.appearance(k, n) <= currentTime; k++ {
+				appeared = append(appeared, &Passenger{
+					ID:          fmt.Sprintf("%s>%s#%d.%d", s.origin, destination, i, k),
+					Origin:      s.origin,
+					Destination: destination,
+					AppearedAt:  w.appearance(k, n),
+				})
+			}
+			s.released[i][destination] = k
+		}
+	}
+
+	sort.Slice(appeared, func(i, j int) bool {
+		if appeared[i].AppearedAt != appeared[j].AppearedAt {
+			return appeared[i].AppearedAt < appeared[j].AppearedAt
+		}
+		return appeared[i].ID < appeared[j].ID
+	})
+	return appeared
+}
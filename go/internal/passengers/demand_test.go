@@ -0,0 +1,99 @@
+package passengers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCountUntil(t *testing.T) {
+	demand := &Demand{Windows: []Window{
+		{Start: 6 * time.Hour, End: 7 * time.Hour, Flows: map[string]map[string]int{"A": {"B": 4, "C": 2}}},
+		{Start: 6*time.Hour + 30*time.Minute, End: 7 * time.Hour, Flows: map[string]map[string]int{"B": {"A": 3}}},
+	}}
+
+	// A>B every 15 minutes from 6:07:30, A>C every 30 minutes from 6:15,
+	// B>A every 10 minutes from 6:35
+	counts := map[time.Duration]int{
+		5 * time.Hour:                0,
+		6*time.Hour + 7*time.Minute:  0,
+		6*time.Hour + 15*time.Minute: 2,
+		6*time.Hour + 35*time.Minute: 4,
+		6*time.Hour + 50*time.Minute: 7,
+		8 * time.Hour:                9,
+	}
+	for currentTime, want := range counts {
+		if got := demand.CountUntil(currentTime); got != want {
+			t.Errorf("%d passengers by %v, want %d", got, currentTime, want)
+		}
+	}
+
+	if got := (*Demand)(nil).CountUntil(8 * time.Hour); got != 0 {
+		t.Errorf("%d passengers without demand", got)
+	}
+}
+
+func TestSourceRelease(t *testing.T) {
+	demand := &Demand{Windows: []Window{
+		{Start: 6 * time.Hour, End: 7 * time.Hour, Flows: map[string]map[string]int{"A": {"B": 4, "C": 2}}},
+	}}
+	source := NewSource("A", demand)
+
+	ids := func(appeared []*Passenger) []string {
+		var ids []string
+		for _, p := range appeared {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+	releases := []struct {
+		at   time.Duration
+		want []string
+	}{
+		{5 * time.Hour, nil},
+		{6*time.Hour + 20*time.Minute, []string{"A>B#0.0", "A>C#0.0"}},
+		{6*time.Hour + 20*time.Minute, nil},
+		{8 * time.Hour, []string{"A>B#0.1", "A>B#0.2", "A>C#0.1", "A>B#0.3"}},
+	}
+	for _, r := range releases {
+		if got := ids(source.Release(r.at)); !reflect.DeepEqual(got, r.want) {
+			t.Errorf("released %v by %v, want %v", got, r.at, r.want)
+		}
+	}
+
+	if appeared := NewSource("B", demand).Release(8 * time.Hour); len(appeared) != 0 {
+		t.Errorf("%d passengers released at B, which has no demand", len(appeared))
+	}
+}
+
+func TestLoadDemand(t *testing.T) {
+	load := func(content string) (*Demand, error) {
+		path := filepath.Join(t.TempDir(), "demand.json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return LoadDemand(path)
+	}
+
+	demand, err := load(`{"windows": [{"start": "06:00", "end": "07:00", "flows": {"A": {"B": 120}}}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Window{{Start: 6 * time.Hour, End: 7 * time.Hour, Flows: map[string]map[string]int{"A": {"B": 120}}}}
+	if !reflect.DeepEqual(demand.Windows, want) {
+		t.Errorf("windows %+v, want %+v", demand.Windows, want)
+	}
+
+	for _, invalid := range []string{
+		`{"windows": [{"start": "07:00", "end": "06:00", "flows": {}}]}`,
+		`{"windows": [{"start": "07:00", "end": "07:00", "flows": {}}]}`,
+		`{"windows": [{"start": "dawn", "end": "07:00", "flows": {}}]}`,
+		`{"windows": {}}`,
+	} {
+		if _, err := load(invalid); err == nil {
+			t.Errorf("demand %s accepted", invalid)
+		}
+	}
+}
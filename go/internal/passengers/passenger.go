@@ -0,0 +1,55 @@
+package passengers
+
+import "time"
+
+type Passenger struct {
+	ID          string
+	Origin      string
+	Destination string
+	AppearedAt  time.Duration
+
+	// PlannedArrival is the scheduled arrival at Destination of the first
+	// train that offered the passenger a ride. Delay is measured against it,
+	// so denied boardings and late trains both count.
+	PlannedArrival *time.Duration
+	BoardedAt      *time.Duration
+	ArrivedAt      *time.Duration
+	TrainID        string
+
+	DeniedBoardings int
+}
+
+func (p *Passenger) Offer(scheduledArrival time.Duration) {
+	if p.PlannedArrival == nil {
+		p.PlannedArrival = &scheduledArrival
+	}
+}
+
+func (p *Passenger) Board(trainID string, boardedAt time.Duration) {
+	p.TrainID = trainID
+	p.BoardedAt = &boardedAt
+}
+
+// Unboard puts a passenger back on the platform, e.g. when its train is cancelled.
+func (p *Passenger) Unboard() {
+	p.TrainID = ""
+	p.BoardedAt = nil
+}
+
+func (p *Passenger) Alight(arrivedAt time.Duration) {
+	p.ArrivedAt = &arrivedAt
+}
+
+// Delay returns how late the passenger reached its destination. It is false
+// until the passenger has arrived.
+func (p *Passenger) Delay() (time.Duration, bool) {
+	if p.ArrivedAt == nil || p.PlannedArrival == nil {
+		return 0, false
+	}
+
+	delay := *p.ArrivedAt - *p.PlannedArrival
+	if delay < 0 {
+		delay = 0
+	}
+	return delay, true
+}
@@ -0,0 +1,92 @@
+package passengers
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// LegLoad is the occupancy of a train between two consecutive stops.
+type LegLoad struct {
+	From       string
+	To         string
+	DepartedAt time.Duration
+	OnBoard    int
+	Capacity   int
+}
+
+func (l LegLoad) LoadFactor() float64 {
+	if l.Capacity == 0 {
+		return 0
+	}
+	return float64(l.OnBoard) / float64(l.Capacity)
+}
+
+func (l LegLoad) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"from":       l.From,
+		"to":         l.To,
+		"departedAt": l.DepartedAt,
+		"onBoard":    l.OnBoard,
+		"capacity":   l.Capacity,
+		"loadFactor": l.LoadFactor(),
+	})
+}
+
+type Report struct {
+	total           int
+	delivered       int
+	onBoard         int
+	waiting         int
+	deniedBoardings int
+	delay           time.Duration // summed over delivered passengers
+}
+
+// NewReport aggregates the passengers known to the trains and stations. total
+// may be larger than len(passengers): passengers that appeared at a station no
+// train has visited since are counted as waiting.
+func NewReport(total int, passengers []*Passenger) Report {
+	r := Report{total: total}
+
+	for _, p := range passengers {
+		r.deniedBoardings += p.DeniedBoardings
+
+		switch {
+		case p.ArrivedAt != nil:
+			r.delivered++
+			if delay, ok := p.Delay(); ok {
+				r.delay += delay
+			}
+		case p.BoardedAt != nil:
+			r.onBoard++
+		}
+	}
+
+	r.waiting = r.total - r.delivered - r.onBoard
+	if r.waiting < 0 {
+		r.waiting = 0
+	}
+	return r
+}
+
+func (r Report) DelayMinutes() float64 {
+	return r.delay.Minutes()
+}
+
+func (r Report) AverageDelay() time.Duration {
+	if r.delivered == 0 {
+		return 0
+	}
+	return r.delay / time.Duration(r.delivered)
+}
+
+func (r Report) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"total":           r.total,
+		"delivered":       r.delivered,
+		"onBoard":         r.onBoard,
+		"waiting":         r.waiting,
+		"deniedBoardings": r.deniedBoardings,
+		"delayMinutes":    r.DelayMinutes(),
+		"averageDelay":    r.AverageDelay(),
+	})
+}
@@ -0,0 +1,29 @@
+package passengers
+
+import (
+	"testing"
+	"time"
+)
+
+func at(d time.Duration) *time.Duration { return &d }
+
+// TestNewReport checks delivered passengers are late against the first train
+// offered to them, and that passengers no train has met yet count as waiting.
+func TestNewReport(t *testing.T) {
+	all := []*Passenger{
+		// Denied by a train due at 9:00, delivered by the next at 9:30
+		{ID: "late", PlannedArrival: at(9 * time.Hour), BoardedAt: at(8*time.Hour + 30*time.Minute), ArrivedAt: at(9*time.Hour + 30*time.Minute), DeniedBoardings: 1},
+		{ID: "early", PlannedArrival: at(9 * time.Hour), BoardedAt: at(8 * time.Hour), ArrivedAt: at(8*time.Hour + 58*time.Minute)},
+		{ID: "riding", PlannedArrival: at(10 * time.Hour), BoardedAt: at(9 * time.Hour)},
+		{ID: "platform", PlannedArrival: at(10 * time.Hour), DeniedBoardings: 2},
+	}
+	report := NewReport(6, all)
+
+	want := Report{total: 6, delivered: 2, onBoard: 1, waiting: 3, deniedBoardings: 3, delay: 30 * time.Minute}
+	if report != want {
+		t.Errorf("report %+v, want %+v", report, want)
+	}
+	if got := report.AverageDelay(); got != 15*time.Minute {
+		t.Errorf("average delay %v, want 15m", got)
+	}
+}
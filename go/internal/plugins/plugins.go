@@ -0,0 +1,211 @@
+// Package plugins registers the named behaviours a simulation can be set up
+// with, such as drivers and station strategies, with the parameters they take.
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ParamType string
+
+const (
+	Number ParamType = "number"
+	String ParamType = "string"
+	Bool   ParamType = "bool"
+)
+
+// Param is a parameter of a plugin. Numbers must lie within [Min, Max].
+// Required parameters have no default and must be given.
+type Param struct {
+	Name        string
+	Type        ParamType
+	Description string
+	Default     any
+	Min, Max    float64
+	Required    bool
+}
+
+// Plugin builds a behaviour from its parameters, checked and completed with
+// their defaults.
+type Plugin[T any] struct {
+	Name        string
+	Description string
+	Params      []Param
+	New         func(params Values) (T, error)
+}
+
+// Info describes a plugin, for the command line and the web UI.
+type Info struct {
+	Name        string
+	Description string
+	Params      []Param
+}
+
+// Values are the parameters a plugin is built with, by name.
+type Values map[string]any
+
+func (v Values) Number(name string) float64 {
+	value, _ := v[name].(float64)
+	return value
+}
+
+func (v Values) String(name string) string {
+	value, _ := v[name].(string)
+	return value
+}
+
+func (v Values) Bool(name string) bool {
+	value, _ := v[name].(bool)
+	return value
+}
+
+// Registry holds the plugins of a kind, in registration order.
+type Registry[T any] struct {
+	kind    string
+	names   []string
+	plugins map[string]Plugin[T]
+}
+
+func NewRegistry[T any](kind string) *Registry[T] {
+	return &Registry[T]{kind: kind, plugins: make(map[string]Plugin[T])}
+}
+
+// Register adds a plugin. Registering a name twice is a programming error.
+func (r *Registry[T]) Register(plugin Plugin[T]) {
+	if _, ok := r.plugins[plugin.Name]; ok {
+		panic(fmt.Sprintf("%s %q registered twice", r.kind, plugin.Name))
+	}
+	r.names = append(r.names, plugin.Name)
+	r.plugins[plugin.Name] = plugin
+}
+
+func (r *Registry[T]) List() []Info {
+	infos := make([]Info, 0, len(r.names))
+	for _, name := range r.names {
+		plugin := r.plugins[name]
+		infos = append(infos, Info{Name: plugin.Name, Description: plugin.Description, Params: plugin.Params})
+	}
+	return infos
+}
+
+// Names are the registered names, for help messages.
+func (r *Registry[T]) Names() []string {
+	return append([]string(nil), r.names...)
+}
+
+// New builds the named plugin. Parameters left out take their default;
+// required ones left out, unknown ones and values of the wrong type or out of
+// bounds are errors.
+// Numbers and booleans may be given as strings, as on the command line.
+func (r *Registry[T]) New(name string, params map[string]any) (T, error) {
+	var zero T
+	plugin, ok := r.plugins[name]
+	if !ok {
+		return zero, fmt.Errorf("unknown %s %q (known: %s)", r.kind, name, strings.Join(r.names, ", "))
+	}
+
+	known := make(map[string]bool, len(plugin.Params))
+	values := make(Values, len(plugin.Params))
+	for _, param := range plugin.Params {
+		known[param.Name] = true
+		raw, given := params[param.Name]
+		if !given && param.Required {
+			return zero, fmt.Errorf("%s %s: parameter %s is required", r.kind, name, param.Name)
+		}
+		if !given {
+			values[param.Name] = param.Default
+			continue
+		}
+		value, err := param.check(raw)
+		if err != nil {
+			return zero, fmt.Errorf("%s %s: %w", r.kind, name, err)
+		}
+		values[param.Name] = value
+	}
+
+	for _, paramName := range sortedNames(params) {
+		if !known[paramName] {
+			return zero, fmt.Errorf("%s %s: unknown parameter %q", r.kind, name, paramName)
+		}
+	}
+
+	built, err := plugin.New(values)
+	if err != nil {
+		return zero, fmt.Errorf("%s %s: %w", r.kind, name, err)
+	}
+	return built, nil
+}
+
+func (p Param) check(raw any) (any, error) {
+	switch p.Type {
+	case Number:
+		value, ok := raw.(float64)
+		if text, isText := raw.(string); isText {
+			parsed, err := strconv.ParseFloat(text, 64)
+			value, ok = parsed, err == nil
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s must be a number", p.Name)
+		}
+		if value < p.Min || value > p.Max {
+			return nil, fmt.Errorf("%s must be between %g and %g", p.Name, p.Min, p.Max)
+		}
+		return value, nil
+	case Bool:
+		value, ok := raw.(bool)
+		if text, isText := raw.(string); isText {
+			parsed, err := strconv.ParseBool(text)
+			value, ok = parsed, err == nil
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s must be true or false", p.Name)
+		}
+		return value, nil
+	default:
+		value, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string", p.Name)
+		}
+		return value, nil
+	}
+}
+
+func sortedNames(params map[string]any) []string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (i Info) MarshalJSON() ([]byte, error) {
+	params := i.Params
+	if params == nil {
+		params = []Param{}
+	}
+	return json.Marshal(map[string]any{
+		"name":        i.Name,
+		"description": i.Description,
+		"params":      params,
+	})
+}
+
+func (p Param) MarshalJSON() ([]byte, error) {
+	data := map[string]any{
+		"name":        p.Name,
+		"type":        p.Type,
+		"description": p.Description,
+		"default":     p.Default,
+		"required":    p.Required,
+	}
+	if p.Type == Number {
+		data["min"] = p.Min
+		data["max"] = p.Max
+	}
+	return json.Marshal(data)
+}
@@ -0,0 +1,143 @@
+package power
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+type PowerMessage interface {
+	isMessage()
+}
+
+// DemandNotification is the power a train would draw during the tick with
+// its full tractive effort.
+type DemandNotification struct {
+	TrainID   string
+	SegmentID string
+	Power     float64 // W
+	Time      time.Duration
+}
+
+func (DemandNotification) isMessage() {}
+
+func (p *Supply) handleDemand(notif DemandNotification) {
+	if section, fed := p.sections[notif.SegmentID]; fed {
+		p.demands[section.ID] += notif.Power
+	}
+}
+
+// ShareRequest asks which share of its tractive effort a train on a segment
+// may use during the tick.
+type ShareRequest struct {
+	TrainID    string
+	SegmentID  string
+	Time       time.Duration
+	ResponseCh chan ShareResponse
+}
+
+func (ShareRequest) isMessage() {}
+
+type ShareResponse struct {
+	Share float64 // 1 when the supply is enough
+	Error error
+}
+
+func (p *Supply) handleShareRequest(req ShareRequest) {
+	share := 1.0
+	if section, fed := p.sections[req.SegmentID]; fed {
+		if limited, ok := p.shares[section.ID]; ok {
+			share = limited
+			p.limited++
+		}
+	}
+
+	req.ResponseCh <- ShareResponse{Share: share, Error: nil}
+}
+
+// BalanceRequest closes the tick: the demands reported so far set the shares
+// of the next one.
+type BalanceRequest struct {
+	Time       time.Duration
+	ResponseCh chan BalanceResponse
+}
+
+func (BalanceRequest) isMessage() {}
+
+type BalanceResponse struct {
+	Overloaded int // substations asked more than their limit
+	Error      error
+}
+
+func (p *Supply) handleBalanceRequest(req BalanceRequest) {
+	req.ResponseCh <- BalanceResponse{Overloaded: p.balance(req.Time), Error: nil}
+}
+
+type ReportRequest struct {
+	ResponseCh chan Report
+}
+
+func (ReportRequest) isMessage() {}
+
+// Report gives the load of every substation over the run.
+type Report struct {
+	Substations         map[string]SubstationReport
+	LimitedTrainMinutes int
+}
+
+type SubstationReport struct {
+	MaxPower          float64 // W
+	Peak              float64 // W asked
+	PeakAt            time.Duration
+	OverloadedMinutes int
+	Loads             []Load // ticks with a demand, in order
+}
+
+func (p *Supply) handleReportRequest(req ReportRequest) {
+	report := Report{Substations: make(map[string]SubstationReport), LimitedTrainMinutes: p.limited}
+
+	for _, substation := range p.network.Substations {
+		r := SubstationReport{MaxPower: substation.MaxPower, Loads: append([]Load(nil), p.loads[substation.ID]...)}
+		for _, load := range r.Loads {
+			if load.Demand > r.Peak {
+				r.Peak, r.PeakAt = load.Demand, load.Time
+			}
+			if load.Demand > substation.MaxPower {
+				r.OverloadedMinutes++
+			}
+		}
+		report.Substations[substation.ID] = r
+	}
+
+	req.ResponseCh <- report
+}
+
+func (r Report) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"substations":         r.Substations,
+		"limitedTrainMinutes": r.LimitedTrainMinutes,
+	})
+}
+
+func (r SubstationReport) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"maxPowerMW":        megawatts(r.MaxPower),
+		"peakMW":            megawatts(r.Peak),
+		"peakAt":            r.PeakAt,
+		"overloadedMinutes": r.OverloadedMinutes,
+		"loads":             r.Loads,
+	})
+}
+
+func (l Load) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"time":       l.Time,
+		"demandMW":   megawatts(l.Demand),
+		"suppliedMW": megawatts(l.Supplied),
+	})
+}
+
+// megawatts rounds to a hundredth of a megawatt.
+func megawatts(watts float64) float64 {
+	return math.Round(watts/wattsPerMW*100) / 100
+}
@@ -0,0 +1,97 @@
+package power
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+const wattsPerMW = 1e6
+
+// Section is a feeding section of the catenary, covering some segments. Past
+// MaxPower the voltage on the section falls below its limit.
+type Section struct {
+	ID       string
+	MaxPower float64 // W, 0 when only the substation limits it
+	Segments []string
+}
+
+// Substation feeds one or more sections, within MaxPower.
+type Substation struct {
+	ID       string
+	MaxPower float64 // W
+	Sections []Section
+}
+
+type Network struct {
+	Substations []Substation
+}
+
+type networkFile struct {
+	Substations []struct {
+		ID         string  `json:"id"`
+		MaxPowerMW float64 `json:"maxPowerMW"`
+		Sections   []struct {
+			ID         string   `json:"id"`
+			MaxPowerMW float64  `json:"maxPowerMW"`
+			Segments   []string `json:"segments"`
+		} `json:"sections"`
+	} `json:"substations"`
+}
+
+// LoadNetwork reads the power supply from a JSON file. Segments outside every
+// section are not electrified, or not limited by their supply:
+//
+//	{"substations": [{"id": "SS1", "maxPowerMW": 12, "sections": [
+//	  {"id": "S1", "maxPowerMW": 8, "segments": ["<segment>", "<segment>"]}]}]}
+func LoadNetwork(path string) (*Network, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading power supply file: %w", err)
+	}
+
+	var file networkFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("parsing power supply file: %w", err)
+	}
+
+	network := &Network{}
+	fed := make(map[string]string) // segment ID -> section ID
+	sections := make(map[string]bool)
+	for i, ss := range file.Substations {
+		if ss.ID == "" {
+			return nil, fmt.Errorf("substation %d: id is required", i)
+		}
+		if ss.MaxPowerMW <= 0 {
+			return nil, fmt.Errorf("substation %s: maxPowerMW must be positive", ss.ID)
+		}
+
+		substation := Substation{ID: ss.ID, MaxPower: ss.MaxPowerMW * wattsPerMW}
+		for j, sec := range ss.Sections {
+			if sec.ID == "" {
+				return nil, fmt.Errorf("substation %s: section %d: id is required", ss.ID, j)
+			}
+			if sec.MaxPowerMW < 0 {
+				return nil, fmt.Errorf("section %s: maxPowerMW cannot be negative", sec.ID)
+			}
+			if sections[sec.ID] {
+				return nil, fmt.Errorf("section %s: id is not unique", sec.ID)
+			}
+			sections[sec.ID] = true
+			for _, segmentID := range sec.Segments {
+				if other, ok := fed[segmentID]; ok {
+					return nil, fmt.Errorf("section %s: segment %s is already fed by section %s", sec.ID, segmentID, other)
+				}
+				fed[segmentID] = sec.ID
+			}
+			substation.Sections = append(substation.Sections, Section{
+				ID:       sec.ID,
+				MaxPower: sec.MaxPowerMW * wattsPerMW,
+				Segments: sec.Segments,
+			})
+		}
+		network.Substations = append(network.Substations, substation)
+	}
+
+	return network, nil
+}
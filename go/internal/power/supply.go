@@ -0,0 +1,123 @@
+// Package power models the supply of electrified lines: substations feeding
+// sections of catenary, which cannot provide more than their power limits to
+// the trains accelerating on them.
+package power
+
+import (
+	"ai30-project/internal/messaging"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Supply gathers the power asked by the trains on each feeding section during
+// a tick. Once the tick is over, it shares what the substations can provide:
+// the trains on an overloaded section get a share of their tractive effort
+// during the next tick.
+type Supply struct {
+	network  *Network
+	sections map[string]*Section // segment ID -> feeding section
+
+	demands map[string]float64 // section ID -> W asked during the tick
+	shares  map[string]float64 // section ID -> share of the tractive effort, when below 1
+	loads   map[string][]Load  // substation ID -> load of every tick with a demand
+	limited int                // train-minutes running on a short supply
+
+	inbox chan PowerMessage
+}
+
+// Load is the power asked of a substation during a tick and what it provided.
+type Load struct {
+	Time     time.Duration
+	Demand   float64 // W
+	Supplied float64 // W
+}
+
+func NewSupply(network *Network) *Supply {
+	p := &Supply{
+		network:  network,
+		sections: make(map[string]*Section),
+		demands:  make(map[string]float64),
+		shares:   make(map[string]float64),
+		loads:    make(map[string][]Load),
+		inbox:    make(chan PowerMessage, 1000),
+	}
+
+	for i := range network.Substations {
+		substation := &network.Substations[i]
+		for j := range substation.Sections {
+			section := &substation.Sections[j]
+			for _, segmentID := range section.Segments {
+				p.sections[segmentID] = section
+			}
+		}
+	}
+
+	return p
+}
+
+func (p *Supply) Inbox() chan PowerMessage {
+	return p.inbox
+}
+
+func (p *Supply) Run(ctx context.Context) {
+	messaging.Serve(ctx, p.inbox, p.handle)
+}
+
+// Drain handles the messages already in the inbox and returns how many.
+func (p *Supply) Drain() int {
+	return messaging.Drain(p.inbox, p.handle)
+}
+
+func (p *Supply) handle(msg PowerMessage) {
+	switch m := msg.(type) {
+	case DemandNotification:
+		p.handleDemand(m)
+	case ShareRequest:
+		p.handleShareRequest(m)
+	case BalanceRequest:
+		p.handleBalanceRequest(m)
+	case ReportRequest:
+		p.handleReportRequest(m)
+	default:
+		fmt.Printf("  [Power] ERROR: Unknown message type\n")
+	}
+}
+
+// balance shares the power of each substation between the sections it feeds,
+// from the demands of the tick, and records its load.
+func (p *Supply) balance(currentTime time.Duration) (overloaded int) {
+	clear(p.shares)
+
+	for _, substation := range p.network.Substations {
+		demand := 0.0
+		for _, section := range substation.Sections {
+			demand += p.demands[section.ID]
+		}
+		if demand == 0 {
+			continue
+		}
+
+		supplied := min(demand, substation.MaxPower)
+		p.loads[substation.ID] = append(p.loads[substation.ID], Load{Time: currentTime, Demand: demand, Supplied: supplied})
+
+		for _, section := range substation.Sections {
+			share := supplied / demand
+			if section.MaxPower > 0 && p.demands[section.ID] > section.MaxPower {
+				share = min(share, section.MaxPower/p.demands[section.ID])
+			}
+			if share < 1 {
+				p.shares[section.ID] = share
+			}
+		}
+
+		if demand > substation.MaxPower {
+			overloaded++
+			fmt.Printf("  [Power] Substation %s overloaded: %.1f MW asked for %.1f MW at %v\n",
+				substation.ID, demand/wattsPerMW, substation.MaxPower/wattsPerMW, currentTime)
+		}
+	}
+
+	clear(p.demands)
+	return overloaded
+}
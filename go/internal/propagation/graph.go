@@ -0,0 +1,143 @@
+package propagation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rootConflict names knock-on minutes caused by a train that was not delayed
+// itself: a plain conflict between two paths.
+const rootConflict = "conflict"
+
+// buildReport attributes the waits in time order, so that a knock-on minute
+// inherits the roots the blocking train had accumulated by then.
+func buildReport(waits []Wait, finished map[string]time.Duration) Report {
+	ordered := make([]Wait, len(waits))
+	copy(ordered, waits)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Time < ordered[j].Time })
+
+	report := Report{Waits: len(waits), Trains: make(map[string]Attribution)}
+	edges := make(map[[2]string]*Edge)
+
+	attribution := func(trainID string) Attribution {
+		a, ok := report.Trains[trainID]
+		if !ok {
+			a = Attribution{Roots: make(map[string]time.Duration)}
+		}
+		return a
+	}
+
+	for _, w := range ordered {
+		a := attribution(w.TrainID)
+
+		switch {
+		case w.Reason.IsPrimary():
+			a.Primary += time.Minute
+			a.Roots[w.root()] += time.Minute
+		case w.BlockingTrainID == "":
+			a.Secondary += time.Minute
+			a.Roots[w.root()] += time.Minute
+		default:
+			a.Secondary += time.Minute
+			inheritRoots(a.Roots, report.Trains[w.BlockingTrainID].Roots, time.Minute)
+
+			key := [2]string{w.BlockingTrainID, w.TrainID}
+			edge, ok := edges[key]
+			if !ok {
+				edge = &Edge{From: w.BlockingTrainID, To: w.TrainID, Reasons: make(map[Reason]time.Duration)}
+				edges[key] = edge
+			}
+			edge.Delay += time.Minute
+			edge.Reasons[w.Reason] += time.Minute
+		}
+
+		report.Trains[w.TrainID] = a
+	}
+
+	for trainID, delay := range finished {
+		a := attribution(trainID)
+		a.FinalDelay = &delay
+		a.Unexplained = delay - a.Primary - a.Secondary
+		report.Trains[trainID] = a
+	}
+
+	report.Edges = make([]Edge, 0, len(edges))
+	for _, edge := range edges {
+		report.Edges = append(report.Edges, *edge)
+	}
+	sort.Slice(report.Edges, func(i, j int) bool {
+		if report.Edges[i].From != report.Edges[j].From {
+			return report.Edges[i].From < report.Edges[j].From
+		}
+		return report.Edges[i].To < report.Edges[j].To
+	})
+
+	return report
+}
+
+// inheritRoots spreads amount over the roots of the blocking train, in
+// proportion to how much each one delayed it.
+func inheritRoots(roots, blockingRoots map[string]time.Duration, amount time.Duration) {
+	var total time.Duration
+	for _, d := range blockingRoots {
+		total += d
+	}
+	if total == 0 {
+		roots[rootConflict] += amount
+		return
+	}
+
+	for root, d := range blockingRoots {
+		roots[root] += time.Duration(float64(amount) * float64(d) / float64(total))
+	}
+}
+
+// DOT renders the propagation graph in Graphviz format: an edge from a train
+// to another means the first one made the second one wait. Trains delayed by
+// primary causes are filled.
+func (r Report) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph delays {\n")
+	b.WriteString("  rankdir=LR;\n  node [shape=box, fontsize=10];\n")
+
+	ids := make([]string, 0, len(r.Trains))
+	for id := range r.Trains {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		a := r.Trains[id]
+		label := shortID(id)
+		if a.FinalDelay != nil {
+			label += fmt.Sprintf("\\n+%.0f min", a.FinalDelay.Minutes())
+		}
+		label += fmt.Sprintf("\\nprimary %.0f / knock-on %.0f", a.Primary.Minutes(), a.Secondary.Minutes())
+
+		style := ""
+		if a.Primary > 0 {
+			style = ", style=filled, fillcolor=lightsalmon"
+		}
+		fmt.Fprintf(&b, "  %q [label=\"%s\"%s];\n", id, label, style)
+	}
+
+	for _, e := range r.Edges {
+		reasons := make([]string, 0, len(e.Reasons))
+		for reason := range e.Reasons {
+			reasons = append(reasons, string(reason))
+		}
+		sort.Strings(reasons)
+		fmt.Fprintf(&b, "  %q -> %q [label=\"%.0f min (%s)\"];\n", e.From, e.To, e.Delay.Minutes(), strings.Join(reasons, ", "))
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// shortID keeps the service number of an SNCF train ID.
+func shortID(trainID string) string {
+	id, _, _ := strings.Cut(trainID, ":")
+	return id
+}
@@ -0,0 +1,92 @@
+package propagation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func minutes(m float64) time.Duration {
+	return time.Duration(m * float64(time.Minute))
+}
+
+func at(hour, minute int) time.Duration {
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute
+}
+
+// TestBuildReport follows T1, stopped by a delay event then a closure, which
+// holds up T2, itself holding up T3. T4 waits for T5, which was not delayed.
+func TestBuildReport(t *testing.T) {
+	waits := []Wait{
+		// Listed out of order: T2 only inherits what T1 had lost by then
+		{TrainID: "T2", Time: at(8, 3), Reason: ReasonTrainAhead, BlockingTrainID: "T1"},
+		{TrainID: "T2", Time: at(8, 4), Reason: ReasonTrainAhead, BlockingTrainID: "T1"},
+		{TrainID: "T1", Time: at(8, 0), Reason: ReasonDelayEvent, Cause: "external"},
+		{TrainID: "T1", Time: at(8, 1), Reason: ReasonDelayEvent, Cause: "external"},
+		{TrainID: "T1", Time: at(8, 2), Reason: ReasonClosure},
+		{TrainID: "T1", Time: at(8, 10), Reason: ReasonIncident, Cause: "infrastructure"},
+		{TrainID: "T3", Time: at(8, 5), Reason: ReasonStationFull, BlockingTrainID: "T2"},
+		{TrainID: "T2", Time: at(8, 6), Reason: ReasonDispatcher},
+		{TrainID: "T4", Time: at(8, 0), Reason: ReasonTrainAhead, BlockingTrainID: "T5"},
+	}
+	report := buildReport(waits, map[string]time.Duration{"T2": minutes(5)})
+
+	if report.Waits != len(waits) {
+		t.Errorf("%d waits, want %d", report.Waits, len(waits))
+	}
+
+	finalDelay := minutes(5)
+	want := map[string]Attribution{
+		"T1": {Primary: minutes(4), Roots: map[string]time.Duration{
+			"delay_event:external": minutes(2), "closure": minutes(1), "incident:infrastructure": minutes(1),
+		}},
+		"T2": {FinalDelay: &finalDelay, Secondary: minutes(3), Unexplained: minutes(2), Roots: map[string]time.Duration{
+			"delay_event:external": minutes(4.0 / 3), "closure": minutes(2.0 / 3), "dispatcher": minutes(1),
+		}},
+		// T3 waits before T2 waits for the dispatcher
+		"T3": {Secondary: minutes(1), Roots: map[string]time.Duration{
+			"delay_event:external": minutes(2.0 / 3), "closure": minutes(1.0 / 3),
+		}},
+		"T4": {Secondary: minutes(1), Roots: map[string]time.Duration{"conflict": minutes(1)}},
+	}
+	if len(report.Trains) != len(want) {
+		t.Errorf("attributions for %d trains, want %d", len(report.Trains), len(want))
+	}
+	for trainID, w := range want {
+		got := report.Trains[trainID]
+		if got.Primary != w.Primary || got.Secondary != w.Secondary || got.Unexplained != w.Unexplained ||
+			(got.FinalDelay == nil) != (w.FinalDelay == nil) || (got.FinalDelay != nil && *got.FinalDelay != *w.FinalDelay) {
+			t.Errorf("%s: %+v, want %+v", trainID, got, w)
+		}
+		for root, d := range w.Roots {
+			if diff := got.Roots[root] - d; diff < -time.Microsecond || diff > time.Microsecond {
+				t.Errorf("%s: %v lost to %s, want %v", trainID, got.Roots[root], root, d)
+			}
+		}
+		if len(got.Roots) != len(w.Roots) {
+			t.Errorf("%s: roots %v, want %v", trainID, got.Roots, w.Roots)
+		}
+	}
+
+	wantEdges := []Edge{
+		{From: "T1", To: "T2", Delay: minutes(2), Reasons: map[Reason]time.Duration{ReasonTrainAhead: minutes(2)}},
+		{From: "T2", To: "T3", Delay: minutes(1), Reasons: map[Reason]time.Duration{ReasonStationFull: minutes(1)}},
+		{From: "T5", To: "T4", Delay: minutes(1), Reasons: map[Reason]time.Duration{ReasonTrainAhead: minutes(1)}},
+	}
+	if !reflect.DeepEqual(report.Edges, wantEdges) {
+		t.Errorf("edges %+v, want %+v", report.Edges, wantEdges)
+	}
+
+	totals := report.Totals()
+	if totals.Trains != 4 || totals.Primary != minutes(4) || totals.Secondary != minutes(5) {
+		t.Errorf("totals %+v, want 4 trains, 4m primary and 5m knock-on", totals)
+	}
+
+	dot := report.DOT()
+	for _, line := range []string{`"T1" -> "T2" [label="2 min (train_ahead)"]`, `"T1" [label="T1\nprimary 4 / knock-on 0", style=filled`} {
+		if !strings.Contains(dot, line) {
+			t.Errorf("graph lacks %s:\n%s", line, dot)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package propagation
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type TracerMessage interface {
+	isMessage()
+}
+
+type WaitNotification struct {
+	Wait Wait
+}
+
+func (WaitNotification) isMessage() {}
+
+func (t *Tracer) handleWait(notif WaitNotification) {
+	t.waits = append(t.waits, notif.Wait)
+}
+
+// FinishNotification reports the arrival delay of a train at its terminus.
+type FinishNotification struct {
+	TrainID string
+	Delay   time.Duration
+}
+
+func (FinishNotification) isMessage() {}
+
+func (t *Tracer) handleFinish(notif FinishNotification) {
+	t.finished[notif.TrainID] = notif.Delay
+}
+
+type ReportRequest struct {
+	ResponseCh chan Report
+}
+
+func (ReportRequest) isMessage() {}
+
+func (t *Tracer) handleReportRequest(req ReportRequest) {
+	req.ResponseCh <- buildReport(t.waits, t.finished)
+}
+
+// Attribution splits the delay of a train. Primary is the time lost to its
+// own events and to the infrastructure, Secondary the time lost waiting for
+// other trains or the dispatcher. Roots traces every lost minute, knock-on
+// ones included, back to the primary causes it originates from.
+type Attribution struct {
+	FinalDelay  *time.Duration // nil until the train reached its terminus
+	Primary     time.Duration
+	Secondary   time.Duration
+	Unexplained time.Duration // running time lost or gained, not spent waiting
+	Roots       map[string]time.Duration
+}
+
+func (a Attribution) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"finalDelay":  a.FinalDelay,
+		"primary":     a.Primary,
+		"secondary":   a.Secondary,
+		"unexplained": a.Unexplained,
+		"roots":       a.Roots,
+	})
+}
+
+// Edge aggregates the minutes a train made another one wait.
+type Edge struct {
+	From    string // blocking train
+	To      string // waiting train
+	Delay   time.Duration
+	Reasons map[Reason]time.Duration
+}
+
+func (e Edge) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"from":    e.From,
+		"to":      e.To,
+		"delay":   e.Delay,
+		"reasons": e.Reasons,
+	})
+}
+
+type Report struct {
+	Waits  int
+	Trains map[string]Attribution
+	Edges  []Edge
+}
+
+func (r Report) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"waits":  r.Waits,
+		"trains": r.Trains,
+		"edges":  r.Edges,
+	})
+}
+
+// Totals sums the attribution over every train.
+type Totals struct {
+	Trains    int
+	Primary   time.Duration
+	Secondary time.Duration
+	Roots     map[string]time.Duration
+}
+
+func (r Report) Totals() Totals {
+	totals := Totals{Trains: len(r.Trains), Roots: make(map[string]time.Duration)}
+	for _, a := range r.Trains {
+		totals.Primary += a.Primary
+		totals.Secondary += a.Secondary
+		for root, d := range a.Roots {
+			totals.Roots[root] += d
+		}
+	}
+	return totals
+}
+
+func (t Totals) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"trains":    t.Trains,
+		"primary":   t.Primary,
+		"secondary": t.Secondary,
+		"roots":     t.Roots,
+	})
+}
@@ -0,0 +1,47 @@
+package propagation
+
+import (
+	"ai30-project/internal/messaging"
+	"context"
+	"time"
+)
+
+// Tracer records every wait of every train to explain where their delays
+// come from.
+type Tracer struct {
+	waits    []Wait
+	finished map[string]time.Duration // train ID -> arrival delay at the terminus
+
+	inbox chan TracerMessage
+}
+
+func NewTracer() *Tracer {
+	return &Tracer{
+		finished: make(map[string]time.Duration),
+		inbox:    make(chan TracerMessage, 100),
+	}
+}
+
+func (t *Tracer) Inbox() chan TracerMessage {
+	return t.inbox
+}
+
+func (t *Tracer) Run(ctx context.Context) {
+	messaging.Serve(ctx, t.inbox, t.handle)
+}
+
+// Drain handles the messages already in the inbox and returns how many.
+func (t *Tracer) Drain() int {
+	return messaging.Drain(t.inbox, t.handle)
+}
+
+func (t *Tracer) handle(msg TracerMessage) {
+	switch m := msg.(type) {
+	case WaitNotification:
+		t.handleWait(m)
+	case FinishNotification:
+		t.handleFinish(m)
+	case ReportRequest:
+		t.handleReportRequest(m)
+	}
+}
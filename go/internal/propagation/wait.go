@@ -0,0 +1,45 @@
+package propagation
+
+import "time"
+
+// Reason is why a train lost a minute.
+type Reason string
+
+const (
+	// Primary reasons: the train is hindered by its own events or by the
+	// infrastructure.
+	ReasonDelayEvent Reason = "delay_event"
+	ReasonIncident   Reason = "incident"
+	ReasonClosure    Reason = "closure"
+
+	// Secondary (knock-on) reasons: the train waits for another train or for
+	// a decision of the dispatcher.
+	ReasonTrainAhead  Reason = "train_ahead"
+	ReasonStationFull Reason = "station_full"
+	ReasonConnection  Reason = "connection"
+	ReasonTurnaround  Reason = "turnaround"
+	ReasonCrew        Reason = "crew"
+	ReasonDispatcher  Reason = "dispatcher"
+)
+
+func (r Reason) IsPrimary() bool {
+	return r == ReasonDelayEvent || r == ReasonIncident || r == ReasonClosure
+}
+
+// Wait is one minute a train spent stopped, past its schedule.
+type Wait struct {
+	TrainID         string
+	Time            time.Duration
+	Reason          Reason
+	Cause           string // delay cause of delay events and incidents
+	BlockingTrainID string // train waited for, if any
+	Location        string // station or segment ID
+}
+
+// root names the origin a minute of waiting is attributed to.
+func (w Wait) root() string {
+	if w.Cause != "" {
+		return string(w.Reason) + ":" + w.Cause
+	}
+	return string(w.Reason)
+}
@@ -19,27 +19,37 @@ type EntryRequest struct {
 func (EntryRequest) isMessage() {}
 
 type EntryResponse struct {
-	Allowed bool
-	Error   error
+	Allowed         bool
+	Closed          bool
+	BlockingTrainID string // train too close to the entry when denied
+	Error           error
 }
 
 func (s *Segment) handleEntryRequest(req EntryRequest) {
-	allowed := true
-	if len(s.trainsOnSegment) > 0 {
-		for _, info := range s.trainsOnSegment {
-			if info.position < constants.SafetyDistance {
-				allowed = false
-				break
-			}
+	if s.closed {
+		fmt.Printf("  [Segment %s] Train %s entry request DENIED (segment closed) at %v\n",
+			s.id, req.TrainID, req.Time)
+		req.ResponseCh <- EntryResponse{Allowed: false, Closed: true, Error: nil}
+		return
+	}
+
+	// The blocking train is the one closest to the entry
+	blockingTrainID := ""
+	blockingPosition := constants.SafetyDistance
+	for trainID, info := range s.trainsOnSegment {
+		if info.position < blockingPosition {
+			blockingTrainID = trainID
+			blockingPosition = info.position
 		}
 	}
 
-	if !allowed {
+	if blockingTrainID != "" {
 		fmt.Printf("  [Segment %s] Train %s entry request DENIED (too close to another train) at %v\n",
 			s.id, req.TrainID, req.Time)
 		req.ResponseCh <- EntryResponse{
-			Allowed: false,
-			Error:   fmt.Errorf("Segment %s: Train %s cannot enter, too close to another train", s.id, req.TrainID),
+			Allowed:         false,
+			BlockingTrainID: blockingTrainID,
+			Error:           fmt.Errorf("Segment %s: Train %s cannot enter, too close to another train", s.id, req.TrainID),
 		}
 		return
 	}
@@ -62,6 +72,7 @@ func (s *Segment) handleEntryRequest(req EntryRequest) {
 type GetTrainAheadRequest struct {
 	TrainID    string
 	Position   float64 // meters
+	Time       time.Duration
 	ResponseCh chan GetTrainAheadResponse
 }
 
@@ -71,6 +82,8 @@ type GetTrainAheadResponse struct {
 	HasTrainAhead bool
 	Position      float64 // meters
 	Speed         float64 // m/s
+	SpeedLimit    float64 // m/s, temporary speed restriction or 0 when none
+	Incident      bool    // an infrastructure incident stops every train on the segment
 	Error         error
 }
 
@@ -96,6 +109,8 @@ func (s *Segment) handleGetTrainAhead(req GetTrainAheadRequest) {
 			HasTrainAhead: true,
 			Position:      minDist,
 			Speed:         closestTrain.speed,
+			SpeedLimit:    s.speedRestriction,
+			Incident:      s.hasIncident(req.Time),
 			Error:         nil,
 		}
 	} else {
@@ -103,6 +118,8 @@ func (s *Segment) handleGetTrainAhead(req GetTrainAheadRequest) {
 			HasTrainAhead: false,
 			Position:      0,
 			Speed:         0,
+			SpeedLimit:    s.speedRestriction,
+			Incident:      s.hasIncident(req.Time),
 			Error:         nil,
 		}
 	}
@@ -136,3 +153,64 @@ func (s *Segment) handleExit(notif ExitNotification) {
 			s.id, notif.TrainID, len(s.trainsOnSegment))
 	}
 }
+
+// StatusChange closes or reopens the segment. Trains already on a closed
+// segment may run to its end, but no train may enter it.
+type StatusChange struct {
+	Closed bool
+}
+
+func (StatusChange) isMessage() {}
+
+func (s *Segment) handleStatusChange(change StatusChange) {
+	s.closed = change.Closed
+	if s.closed {
+		fmt.Printf("  [Segment %s] CLOSED\n", s.id)
+	} else {
+		fmt.Printf("  [Segment %s] REOPENED\n", s.id)
+	}
+}
+
+// SpeedRestrictionChange sets a temporary speed restriction on the segment.
+// A MaxSpeed of zero lifts it.
+type SpeedRestrictionChange struct {
+	MaxSpeed float64 // m/s
+}
+
+func (SpeedRestrictionChange) isMessage() {}
+
+func (s *Segment) handleSpeedRestrictionChange(change SpeedRestrictionChange) {
+	s.speedRestriction = change.MaxSpeed
+	if s.speedRestriction > 0 {
+		fmt.Printf("  [Segment %s] Speed restricted to %.1f m/s\n", s.id, s.speedRestriction)
+	} else {
+		fmt.Printf("  [Segment %s] Speed restriction lifted\n", s.id)
+	}
+}
+
+// StateRequest asks for a snapshot of the segment, consistent with every
+// message sent before it.
+type StateRequest struct {
+	ResponseCh chan State
+}
+
+func (StateRequest) isMessage() {}
+
+type TrainPosition struct {
+	Position float64 // meters
+	Speed    float64 // m/s
+}
+
+type State struct {
+	SegmentID string
+	Length    float64 // meters
+	Trains    map[string]TrainPosition
+}
+
+func (s *Segment) handleStateRequest(req StateRequest) {
+	state := State{SegmentID: s.id, Length: s.length, Trains: make(map[string]TrainPosition, len(s.trainsOnSegment))}
+	for trainID, info := range s.trainsOnSegment {
+		state.Trains[trainID] = TrainPosition{Position: info.position, Speed: info.speed}
+	}
+	req.ResponseCh <- state
+}
@@ -1,9 +1,13 @@
 package segments
 
 import (
+	"ai30-project/internal/messaging"
+	"context"
 	"encoding/json"
 	"fmt"
 	"time"
+
+	"ai30-project/internal/events"
 )
 
 type Segment struct {
@@ -12,6 +16,10 @@ type Segment struct {
 	toStationID   string
 	length        float64 // meters
 	maxSpeed      float64 // m/min
+	closed        bool
+
+	speedRestriction float64 // m/s, 0 when none
+	incidents        []events.DelayEvent
 
 	trainsOnSegment map[string]*trainInfo
 
@@ -30,45 +38,72 @@ func NewSegment(id, fromStationID, toStationID string, length, maxSpeed float64)
 	}
 }
 
-func (s *Segment) ID() string          { return s.id }
-func (s *Segment) ToStationID() string { return s.toStationID }
-func (s *Segment) Length() float64     { return s.length }
-func (s *Segment) MaxSpeed() float64   { return s.maxSpeed }
+func (s *Segment) ID() string            { return s.id }
+func (s *Segment) FromStationID() string { return s.fromStationID }
+func (s *Segment) ToStationID() string   { return s.toStationID }
+func (s *Segment) Length() float64       { return s.length }
+func (s *Segment) MaxSpeed() float64     { return s.maxSpeed }
+
+// SetIncidents schedules infrastructure incidents, each stopping every train
+// on the segment while it lasts. It must be called before Run.
+func (s *Segment) SetIncidents(incidents []events.DelayEvent) {
+	s.incidents = incidents
+}
+
+func (s *Segment) hasIncident(currentTime time.Duration) bool {
+	for _, incident := range s.incidents {
+		if incident.IsActive(currentTime) {
+			return true
+		}
+	}
+	return false
+}
 
 func (s *Segment) Inbox() chan SegmentMessage {
 	return s.inbox
 }
 
-func (s *Segment) Run() {
-	for {
-		msg, ok := <-s.inbox
-		if !ok {
-			return
-		}
+func (s *Segment) Run(ctx context.Context) {
+	messaging.Serve(ctx, s.inbox, s.handle)
+}
 
-		switch m := msg.(type) {
-		case EntryRequest:
-			s.handleEntryRequest(m)
-		case GetTrainAheadRequest:
-			s.handleGetTrainAhead(m)
-		case UpdatePositionNotification:
-			s.handleUpdatePosition(m)
-		case ExitNotification:
-			s.handleExit(m)
-		default:
-			fmt.Printf("  [Segment %s] ERROR: Unknown message type\n", s.ID())
-		}
+// Drain handles the messages already in the inbox and returns how many.
+func (s *Segment) Drain() int {
+	return messaging.Drain(s.inbox, s.handle)
+}
+
+func (s *Segment) handle(msg SegmentMessage) {
+	switch m := msg.(type) {
+	case EntryRequest:
+		s.handleEntryRequest(m)
+	case GetTrainAheadRequest:
+		s.handleGetTrainAhead(m)
+	case UpdatePositionNotification:
+		s.handleUpdatePosition(m)
+	case ExitNotification:
+		s.handleExit(m)
+	case StatusChange:
+		s.handleStatusChange(m)
+	case SpeedRestrictionChange:
+		s.handleSpeedRestrictionChange(m)
+	case StateRequest:
+		s.handleStateRequest(m)
+	default:
+		fmt.Printf("  [Segment %s] ERROR: Unknown message type\n", s.ID())
 	}
 }
 
 func (s *Segment) MarshalJSON() ([]byte, error) {
 	return json.Marshal(map[string]any{
-		"id":              s.id,
-		"fromStationId":   s.fromStationID,
-		"toStationId":     s.toStationID,
-		"length":          s.length,
-		"maxSpeed":        s.maxSpeed,
-		"trainsOnSegment": s.trainsOnSegment,
+		"id":               s.id,
+		"fromStationId":    s.fromStationID,
+		"toStationId":      s.toStationID,
+		"length":           s.length,
+		"maxSpeed":         s.maxSpeed,
+		"closed":           s.closed,
+		"speedRestriction": s.speedRestriction,
+		"incidents":        s.incidents,
+		"trainsOnSegment":  s.trainsOnSegment,
 	})
 }
 
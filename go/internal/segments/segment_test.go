@@ -0,0 +1,32 @@
+package segments
+
+import (
+	"testing"
+	"time"
+
+	"ai30-project/internal/events"
+)
+
+// TestIncidentStopsEveryTrain checks an incident anchored on the segment is
+// reported to every train on it while active, whoever it was drawn for.
+func TestIncidentStopsEveryTrain(t *testing.T) {
+	segment := NewSegment("A-B", "A", "B", 30000, 2000)
+	segment.SetIncidents([]events.DelayEvent{
+		{Cause: events.DelayCauseInfrastructure, Duration: 10 * time.Minute, StartTime: 8 * time.Hour, SegmentID: "A-B"},
+	})
+	incident := func(trainID string, position float64, at time.Duration) bool {
+		responseCh := make(chan GetTrainAheadResponse, 1)
+		segment.Inbox() <- GetTrainAheadRequest{TrainID: trainID, Position: position, Time: at, ResponseCh: responseCh}
+		segment.Drain()
+		return (<-responseCh).Incident
+	}
+
+	for _, trainID := range []string{"T1", "T2"} {
+		if !incident(trainID, 5000, 8*time.Hour+5*time.Minute) {
+			t.Errorf("%s not stopped during the incident", trainID)
+		}
+		if incident(trainID, 5000, 7*time.Hour+59*time.Minute) || incident(trainID, 5000, 8*time.Hour+10*time.Minute) {
+			t.Errorf("%s stopped outside the incident", trainID)
+		}
+	}
+}
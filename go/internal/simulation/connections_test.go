@@ -0,0 +1,100 @@
+package simulation_test
+
+import (
+	"testing"
+	"time"
+
+	"ai30-project/internal/connections"
+	"ai30-project/internal/simulation"
+	"ai30-project/internal/trains"
+)
+
+// transferAtB has the connecting train due to leave B at 08:12, while its
+// feeder, timed faster than it can run, reaches B too late for its passengers
+// to transfer by then.
+func transferAtB(t testing.TB) []*trains.Train {
+	return []*trains.Train{
+		train(t, "FEEDER:OUI:FR:Line::AB", stop{"A", "08:00", "08:00"}, stop{"B", "08:10", "08:10"}),
+		train(t, "CONNECTING:OUI:FR:Line::DC", stop{"D", "07:30", "07:30"}, stop{"B", "08:00", "08:12"}, stop{"C", "08:45", "08:45"}),
+	}
+}
+
+var connectionAtB = connections.Connection{
+	FeederTrainID:     "FEEDER:OUI:FR:Line::AB",
+	ConnectingTrainID: "CONNECTING:OUI:FR:Line::DC",
+	StationID:         "B",
+	MinTransferTime:   5 * time.Minute,
+	MaxWait:           15 * time.Minute,
+	Passengers:        40,
+}
+
+func TestSetConnectionsRejectsInvalid(t *testing.T) {
+	stationList, segmentList, paths := network()
+	scenario := simulation.Scenario{Trains: transferAtB(t), Stations: stationList, Segments: segmentList, Paths: paths}
+	sim, err := simulation.NewScenarioSimulation(scenario, "eco", "no_sort")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := map[string]func(c *connections.Connection){
+		"unknown feeder":          func(c *connections.Connection) { c.FeederTrainID = "GHOST:OUI:FR:Line::AB" },
+		"unknown connecting":      func(c *connections.Connection) { c.ConnectingTrainID = "GHOST:OUI:FR:Line::DC" },
+		"station off both routes": func(c *connections.Connection) { c.StationID = "Z" },
+		"feeder starts there":     func(c *connections.Connection) { c.StationID = "A" },
+		"connecting ends there":   func(c *connections.Connection) { c.StationID = "C" },
+	}
+	for name, change := range invalid {
+		conn := connectionAtB
+		change(&conn)
+		if err := sim.SetConnections([]connections.Connection{conn}, "wait", connections.WaitUpToMax{}); err == nil {
+			t.Errorf("%s: connection accepted", name)
+		}
+	}
+
+	if err := sim.SetConnections([]connections.Connection{connectionAtB}, "wait", connections.WaitUpToMax{}); err != nil {
+		t.Errorf("valid connection rejected: %v", err)
+	}
+}
+
+// TestDelayManagement checks the connecting train leaves on time without
+// waiting, and waits for the passengers of its late feeder otherwise.
+func TestDelayManagement(t *testing.T) {
+	departure := func(policyName string, policy connections.Policy) (feederArrival, departure time.Duration) {
+		stationList, segmentList, paths := network()
+		timetable := transferAtB(t)
+		scenario := simulation.Scenario{Trains: timetable, Stations: stationList, Segments: segmentList, Paths: paths}
+		sim, err := simulation.NewScenarioSimulation(scenario, "eco", "no_sort")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sim.SetEventModels(noEvents)
+		sim.SetSeed(seed)
+		sim.SetMaxTime(maxTime)
+		if err := sim.SetConnections([]connections.Connection{connectionAtB}, policyName, policy); err != nil {
+			t.Fatal(err)
+		}
+
+		sim.Start()
+		for !sim.IsFinished() {
+			sim.Tick()
+		}
+		sim.Stop()
+
+		feederArrival, _ = timetable[0].EndStop().ArrivedAt()
+		departure, _ = timetable[1].Stops()[1].DepartedAt()
+		return feederArrival, departure
+	}
+
+	arrival, left := departure("no_wait", connections.NoWait{})
+	if arrival+connectionAtB.MinTransferTime <= at(t, "08:12") {
+		t.Fatalf("feeder arrived at %v, want it too late to transfer by 08:12", arrival)
+	}
+	if left != at(t, "08:12") {
+		t.Errorf("connecting train left at %v without waiting, want 08:12", left)
+	}
+
+	arrival, left = departure("wait", connections.WaitUpToMax{})
+	if left < arrival+connectionAtB.MinTransferTime || left > at(t, "08:12")+connectionAtB.MaxWait {
+		t.Errorf("connecting train left at %v for a feeder arrived at %v, want it to wait for the transfer", left, arrival)
+	}
+}
@@ -0,0 +1,60 @@
+package simulation_test
+
+import (
+	"testing"
+	"time"
+
+	"ai30-project/internal/crew"
+	"ai30-project/internal/simulation"
+	"ai30-project/internal/trains"
+)
+
+// TestRosterHoldsDeparture has the driver of a train timed faster than it can
+// run from A to B take over another train at B, due to leave at 08:15.
+func TestRosterHoldsDeparture(t *testing.T) {
+	departure := func(roster *crew.Roster) (inboundArrival, departure time.Duration) {
+		stationList, segmentList, paths := network()
+		timetable := []*trains.Train{
+			train(t, "IN:OUI:FR:Line::AB", stop{"A", "08:00", "08:00"}, stop{"B", "08:10", "08:10"}),
+			train(t, "OUT:OUI:FR:Line::BC", stop{"B", "08:15", "08:15"}, stop{"C", "08:45", "08:45"}),
+		}
+		sim, err := simulation.NewScenarioSimulation(simulation.Scenario{Trains: timetable, Stations: stationList, Segments: segmentList, Paths: paths}, "eco", "no_sort")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sim.SetEventModels(noEvents)
+		sim.SetSeed(seed)
+		sim.SetMaxTime(maxTime)
+		if roster != nil {
+			sim.SetRoster(roster)
+		}
+
+		sim.Start()
+		for !sim.IsFinished() {
+			sim.Tick()
+		}
+		sim.Stop()
+
+		inboundArrival, _ = timetable[0].EndStop().ArrivedAt()
+		departure, _ = timetable[1].Stops()[0].DepartedAt()
+		return inboundArrival, departure
+	}
+
+	if _, left := departure(nil); left != at(t, "08:15") {
+		t.Fatalf("train left B at %v without a roster, want 08:15", left)
+	}
+
+	roster := &crew.Roster{Duties: []crew.Duty{{
+		ID:            "D1",
+		Role:          "driver",
+		MinChangeover: 10 * time.Minute,
+		Legs: []crew.Leg{
+			{TrainID: "IN:OUI:FR:Line::AB", FromStationID: "A", ToStationID: "B"},
+			{TrainID: "OUT:OUI:FR:Line::BC", FromStationID: "B", ToStationID: "C"},
+		},
+	}}}
+	arrival, left := departure(roster)
+	if left < arrival+10*time.Minute || left <= at(t, "08:15") {
+		t.Errorf("train left B at %v for a driver arrived at %v, want it held for the changeover", left, arrival)
+	}
+}
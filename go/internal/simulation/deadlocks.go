@@ -0,0 +1,143 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"ai30-project/internal/deadlock"
+	"ai30-project/internal/dispatcher"
+	"ai30-project/internal/trains"
+)
+
+// Deadlock resolution policies
+const (
+	DeadlockReport = "report" // log the deadlock and let the run go on
+	DeadlockAbort  = "abort"  // stop the run
+	DeadlockCancel = "cancel" // take one train of the cycle out of service
+)
+
+type deadlockDetection struct {
+	policy     string
+	blockedFor map[string]int  // train -> consecutive ticks blocked
+	active     map[string]bool // deadlocks already reported and not yet cleared
+	deadlocks  []deadlock.Deadlock
+}
+
+func (d *deadlockDetection) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"policy":    d.policy,
+		"deadlocks": d.deadlocks,
+	})
+}
+
+// SetDeadlockDetection looks for trains waiting on each other in a circle
+// after each tick, and resolves them with the named policy: report, abort or
+// cancel.
+func (s *Simulation) SetDeadlockDetection(policy string) error {
+	switch policy {
+	case DeadlockReport, DeadlockAbort, DeadlockCancel:
+	default:
+		return fmt.Errorf("unknown deadlock policy %q", policy)
+	}
+
+	s.deadlocks = &deadlockDetection{
+		policy:     policy,
+		blockedFor: make(map[string]int),
+		active:     make(map[string]bool),
+	}
+	return nil
+}
+
+// SetMaxTime aborts the run if trains are still running at limit, so that a
+// stalled scenario cannot tick forever. Zero disables the cutoff.
+func (s *Simulation) SetMaxTime(limit time.Duration) {
+	s.maxTime = limit
+}
+
+// Deadlocks returns every deadlock found so far.
+func (s *Simulation) Deadlocks() []deadlock.Deadlock {
+	if s.deadlocks == nil {
+		return nil
+	}
+	return s.deadlocks.deadlocks
+}
+
+// detectDeadlocks builds the wait-for graph of the trains that have been
+// blocked for a while. It runs between ticks, when train state is stable.
+func (s *Simulation) detectDeadlocks() {
+	d := s.deadlocks
+
+	waits := make(map[string]deadlock.Wait)
+	for id, train := range s.trains {
+		blocked := train.Blocked()
+		if blocked == nil {
+			delete(d.blockedFor, id)
+			continue
+		}
+		d.blockedFor[id]++
+		if d.blockedFor[id] >= deadlock.Patience {
+			waits[id] = deadlock.Wait{ResourceID: blocked.ResourceID, Holders: blocked.Holders}
+		}
+	}
+
+	found := deadlock.Detect(waits)
+	active := make(map[string]bool, len(found))
+	for _, dl := range found {
+		key := dl.Key()
+		active[key] = true
+		if d.active[key] {
+			continue
+		}
+
+		dl.Tick = s.currentTime
+		dl.Resolution = d.policy
+		switch d.policy {
+		case DeadlockAbort:
+			s.failure = fmt.Errorf("deadlock at %v: %s", s.currentTime, describeCycle(dl.Cycle))
+		case DeadlockCancel:
+			victim := s.deadlockVictim(dl.Cycle)
+			notify[dispatcher.Order](s, "train "+victim.ID(), victim.Orders(), dispatcher.CancelOrder{})
+			dl.Resolution = "cancel " + victim.ID()
+		}
+
+		d.deadlocks = append(d.deadlocks, dl)
+		fmt.Printf("[Deadlock] %s, resolution: %s\n", describeCycle(dl.Cycle), dl.Resolution)
+	}
+	d.active = active
+
+	if s.failure != nil {
+		fmt.Printf("[Simulation] Aborted: %v\n", s.failure)
+	}
+}
+
+// deadlockVictim picks the train of the cycle that was scheduled to start
+// last, as it disturbs the fewest passengers already on their way.
+func (s *Simulation) deadlockVictim(cycle []deadlock.Link) *trains.Train {
+	var victim *trains.Train
+	for _, link := range cycle {
+		train := s.trains[link.TrainID]
+		if victim == nil || train.StartStop().Departure() > victim.StartStop().Departure() ||
+			(train.StartStop().Departure() == victim.StartStop().Departure() && train.ID() > victim.ID()) {
+			victim = train
+		}
+	}
+	return victim
+}
+
+// checkMaxTime aborts the run once the time cutoff is reached.
+func (s *Simulation) checkMaxTime() {
+	if s.maxTime > 0 && s.currentTime >= s.maxTime && s.activeTrains > 0 {
+		s.failure = fmt.Errorf("time limit %v reached with %d trains still running", s.maxTime, s.activeTrains)
+		fmt.Printf("[Simulation] Aborted: %v\n", s.failure)
+	}
+}
+
+func describeCycle(cycle []deadlock.Link) string {
+	parts := make([]string, len(cycle))
+	for i, link := range cycle {
+		parts[i] = fmt.Sprintf("%s waits for %s (held by %s)", link.TrainID, link.ResourceID, link.HolderID)
+	}
+	return strings.Join(parts, ", ")
+}
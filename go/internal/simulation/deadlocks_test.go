@@ -0,0 +1,128 @@
+package simulation_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"ai30-project/internal/constants"
+	"ai30-project/internal/deadlock"
+	"ai30-project/internal/navigation"
+	"ai30-project/internal/segments"
+	"ai30-project/internal/simulation"
+	"ai30-project/internal/stations"
+	"ai30-project/internal/trains"
+)
+
+// gridlockNetwork joins X and Y, single-platform stations, by a short link
+// each way, shorter than the safety distance, and feeds X from A and Y from
+// B.
+//
+//	A --- X ==== Y --- B
+func gridlockNetwork() ([]*stations.Station, []*segments.Segment, navigation.Paths) {
+	stationList := []*stations.Station{
+		stations.NewStation("A", "Station A", 2),
+		stations.NewStation("X", "Station X", 1),
+		stations.NewStation("Y", "Station Y", 1),
+		stations.NewStation("B", "Station B", 2),
+	}
+	segmentList := []*segments.Segment{
+		segments.NewSegment("A-X", "A", "X", 30000, 160*constants.KmHToMPerMin),
+		segments.NewSegment("B-Y", "B", "Y", 30000, 160*constants.KmHToMPerMin),
+		segments.NewSegment("X-Y", "X", "Y", constants.SafetyDistance/2, 160*constants.KmHToMPerMin),
+		segments.NewSegment("Y-X", "Y", "X", constants.SafetyDistance/2, 160*constants.KmHToMPerMin),
+	}
+	paths := navigation.Paths{
+		"A": {"X": "A-X", "Y": "A-X"},
+		"B": {"Y": "B-Y", "X": "B-Y"},
+		"X": {"Y": "X-Y"},
+		"Y": {"X": "Y-X"},
+	}
+	return stationList, segmentList, paths
+}
+
+// gridlock has XY and YX call at X and Y, then swap places at 08:20, while
+// AY and BX, running through X and Y, stop at the end of the short links in
+// front of the occupied stations: each of the four trains waits for another.
+func gridlock(t testing.TB) []*trains.Train {
+	return []*trains.Train{
+		train(t, "XY:OUI:FR:Line::AXY", stop{"A", "07:30", "07:30"}, stop{"X", "07:50", "08:20"}, stop{"Y", "08:30", "08:30"}),
+		train(t, "YX:OUI:FR:Line::BYX", stop{"B", "07:30", "07:30"}, stop{"Y", "07:50", "08:20"}, stop{"X", "08:30", "08:30"}),
+		train(t, "AY:OUI:FR:Line::AY", stop{"A", "08:00", "08:00"}, stop{"Y", "08:15", "08:15"}),
+		train(t, "BX:OUI:FR:Line::BX", stop{"B", "08:00", "08:00"}, stop{"X", "08:15", "08:15"}),
+	}
+}
+
+func runGridlock(t *testing.T, policy string) (*simulation.Simulation, []*trains.Train) {
+	stationList, segmentList, paths := gridlockNetwork()
+	timetable := gridlock(t)
+	sim, err := simulation.NewScenarioSimulation(simulation.Scenario{Trains: timetable, Stations: stationList, Segments: segmentList, Paths: paths}, "eco", "no_sort")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.SetEventModels(noEvents)
+	sim.SetSeed(seed)
+	sim.SetMaxTime(maxTime)
+	if err := sim.SetDeadlockDetection(policy); err != nil {
+		t.Fatal(err)
+	}
+
+	sim.Start()
+	for !sim.IsFinished() {
+		sim.Tick()
+	}
+	sim.Stop()
+	return sim, timetable
+}
+
+func TestGridlockDetected(t *testing.T) {
+	sim, _ := runGridlock(t, simulation.DeadlockReport)
+
+	found := sim.Deadlocks()
+	if len(found) != 1 {
+		t.Fatalf("%d deadlocks, want the gridlock of the four trains", len(found))
+	}
+	dl := found[0]
+	if want := "AY:OUI:FR:Line::AY|BX:OUI:FR:Line::BX|XY:OUI:FR:Line::AXY|YX:OUI:FR:Line::BYX"; dl.Key() != want {
+		t.Errorf("deadlock of %s, want %s", dl.Key(), want)
+	}
+	if dl.Tick < at(t, "08:20")+(deadlock.Patience-1)*time.Minute || dl.Resolution != simulation.DeadlockReport {
+		t.Errorf("deadlock found at %v and resolved by %q, want reported once the trains waited", dl.Tick, dl.Resolution)
+	}
+	// Reported, the gridlock lasts until the time limit
+	if err := sim.Failure(); err == nil || !strings.Contains(err.Error(), "time limit") {
+		t.Errorf("run ended with %v, want the time limit reached", err)
+	}
+}
+
+func TestGridlockAborts(t *testing.T) {
+	sim, _ := runGridlock(t, simulation.DeadlockAbort)
+
+	if err := sim.Failure(); err == nil || !strings.Contains(err.Error(), "deadlock") {
+		t.Errorf("run ended with %v, want it aborted on the deadlock", err)
+	}
+	if len(sim.Deadlocks()) != 1 {
+		t.Errorf("%d deadlocks, want 1", len(sim.Deadlocks()))
+	}
+}
+
+// TestGridlockCancels checks the train scheduled to start last, BX as it
+// starts with AY but sorts after it, is cancelled, which lets the other three
+// finish.
+func TestGridlockCancels(t *testing.T) {
+	sim, timetable := runGridlock(t, simulation.DeadlockCancel)
+
+	if err := sim.Failure(); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	found := sim.Deadlocks()
+	if len(found) != 1 || found[0].Resolution != "cancel BX:OUI:FR:Line::BX" {
+		t.Fatalf("deadlocks %+v, want the gridlock resolved by cancelling BX", found)
+	}
+	for _, train := range timetable {
+		_, arrived := train.EndStop().ArrivedAt()
+		if cancelled := train.ID() == "BX:OUI:FR:Line::BX"; arrived == cancelled {
+			t.Errorf("%s arrived: %v, want only the cancelled train not to", train.ID(), arrived)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package simulation_test
+
+import (
+	"testing"
+	"time"
+
+	"ai30-project/internal/constants"
+	"ai30-project/internal/dispatcher"
+	"ai30-project/internal/disruptions"
+	"ai30-project/internal/navigation"
+	"ai30-project/internal/segments"
+	"ai30-project/internal/simulation"
+	"ai30-project/internal/stations"
+	"ai30-project/internal/trains"
+)
+
+// detourNetwork leads from Z to B through A, either directly or by X. Every
+// link is 30 km long.
+//
+//	Z --- A ------- B
+//	       \       /
+//	        \- X -/
+func detourNetwork() ([]*stations.Station, []*segments.Segment, navigation.Paths) {
+	stationList := []*stations.Station{
+		stations.NewStation("Z", "Station Z", 2),
+		stations.NewStation("A", "Station A", 2),
+		stations.NewStation("X", "Station X", 2),
+		stations.NewStation("B", "Station B", 2),
+	}
+
+	var segmentList []*segments.Segment
+	for _, id := range []string{"Z-A", "A-B", "A-X", "X-B"} {
+		segmentList = append(segmentList, segments.NewSegment(id, id[:1], id[2:], 30000, 160*constants.KmHToMPerMin))
+	}
+	paths := navigation.Paths{
+		"Z": {"A": "Z-A", "B": "Z-A"},
+		"A": {"B": "A-B", "X": "A-X"},
+		"X": {"B": "X-B"},
+	}
+	return stationList, segmentList, paths
+}
+
+// runDetour runs a train through A while another crawls away from A on A-B,
+// under a 1 m/s restriction for an hour, and returns the first one.
+func runDetour(t *testing.T, planner dispatcher.Planner) *trains.Train {
+	stationList, segmentList, paths := detourNetwork()
+	through := train(t, "THROUGH:OUI:FR:Line::ZB", stop{"Z", "08:00", "08:00"}, stop{"B", "08:40", "08:40"})
+	crawler := train(t, "CRAWLER:OUI:FR:Line::AB", stop{"A", "08:00", "08:00"}, stop{"B", "09:30", "09:30"})
+
+	scenario := simulation.Scenario{Trains: []*trains.Train{through, crawler}, Stations: stationList, Segments: segmentList, Paths: paths}
+	sim, err := simulation.NewScenarioSimulation(scenario, "eco", "no_sort")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.SetEventModels(noEvents)
+	sim.SetSeed(seed)
+	sim.SetMaxTime(maxTime)
+	sim.SetInvariantChecks(true)
+	err = sim.AddDisruption(disruptions.Disruption{Kind: disruptions.SpeedRestriction, Target: "A-B", Start: at(t, "08:00"), End: at(t, "09:00"), MaxSpeed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if planner != nil {
+		if err := sim.SetDispatcher(planner, 5*time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sim.Start()
+	for !sim.IsFinished() {
+		sim.Tick()
+	}
+	sim.Stop()
+	if err := sim.Failure(); err != nil {
+		t.Fatal(err)
+	}
+	return through
+}
+
+// TestDispatcherDivertsBlockedTrain checks a train kept off A-B by the train
+// crawling ahead waits for it on its own, and takes the detour by X once the
+// dispatcher orders it.
+func TestDispatcherDivertsBlockedTrain(t *testing.T) {
+	if diversions, _ := runDetour(t, nil).Diversions(); diversions != 0 {
+		t.Errorf("%d diversions without a dispatcher, want none", diversions)
+	}
+
+	through := runDetour(t, dispatcher.ConflictPlanner{})
+	diversions, added := through.Diversions()
+	// The detour is one link longer, run in 675 s at 160 km/h
+	if diversions != 1 || added != 675*time.Second {
+		t.Errorf("%d diversions adding %v with the dispatcher, want one adding 11m15s", diversions, added)
+	}
+	arrivedAt, ok := through.EndStop().ArrivedAt()
+	if !ok || arrivedAt >= at(t, "09:00") {
+		t.Errorf("diverted train arrived at B at %v (arrived: %v), want before the restriction ends at 09:00", arrivedAt, ok)
+	}
+}
+
+func TestSetDispatcherRejectsHorizon(t *testing.T) {
+	stationList, segmentList, paths := network()
+	scenario := simulation.Scenario{Trains: followingTrains(t), Stations: stationList, Segments: segmentList, Paths: paths}
+	sim, err := simulation.NewScenarioSimulation(scenario, "eco", "no_sort")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, horizon := range []time.Duration{0, -time.Minute} {
+		if err := sim.SetDispatcher(dispatcher.ConflictPlanner{}, horizon); err == nil {
+			t.Errorf("horizon %v accepted", horizon)
+		}
+	}
+}
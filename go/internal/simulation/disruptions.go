@@ -0,0 +1,138 @@
+package simulation
+
+import (
+	"fmt"
+
+	"ai30-project/internal/dispatcher"
+	"ai30-project/internal/disruptions"
+	"ai30-project/internal/navigation"
+	"ai30-project/internal/segments"
+	"ai30-project/internal/stations"
+)
+
+// appliedDisruptions is the infrastructure state last sent to the agents, so
+// that only changes are notified.
+type appliedDisruptions struct {
+	closedSegments    map[string]bool
+	stationCapacities map[string]int
+	speedRestrictions map[string]float64
+}
+
+func newAppliedDisruptions() appliedDisruptions {
+	return appliedDisruptions{
+		closedSegments:    make(map[string]bool),
+		stationCapacities: make(map[string]int),
+		speedRestrictions: make(map[string]float64),
+	}
+}
+
+// AddDisruption schedules disruptions. It may be called before Start or
+// between ticks; a disruption whose window has already begun applies at once.
+// It rejects them all if one is invalid or targets an unknown segment or
+// station.
+func (s *Simulation) AddDisruption(ds ...disruptions.Disruption) error {
+	if s.isStopped {
+		return fmt.Errorf("simulation is stopped")
+	}
+
+	for _, d := range ds {
+		if err := d.Validate(); err != nil {
+			return fmt.Errorf("disruption on %s: %w", d.Target, err)
+		}
+		switch d.Kind {
+		case disruptions.SegmentBlocked, disruptions.SpeedRestriction:
+			if _, ok := s.segmentInboxes[d.Target]; !ok {
+				return fmt.Errorf("unknown segment %s", d.Target)
+			}
+		case disruptions.StationCapacity:
+			if _, ok := s.stationInboxes[d.Target]; !ok {
+				return fmt.Errorf("unknown station %s", d.Target)
+			}
+		}
+	}
+
+	s.disruptions = append(s.disruptions, ds...)
+	if s.isStarted {
+		s.applyDisruptions()
+	}
+	return nil
+}
+
+// applyDisruptions derives the infrastructure state from manual closures and
+// the disruptions active at the current time, and notifies the segments,
+// stations, navigation service and trains of what changed. Overlapping
+// disruptions combine to the most restrictive one.
+func (s *Simulation) applyDisruptions() {
+	closed := make(map[string]bool)
+	for segmentID := range s.manualClosures {
+		closed[segmentID] = true
+	}
+	capacities := make(map[string]int)
+	restrictions := make(map[string]float64)
+
+	for _, d := range s.disruptions {
+		if !d.IsActive(s.currentTime) {
+			continue
+		}
+		switch d.Kind {
+		case disruptions.SegmentBlocked:
+			closed[d.Target] = true
+		case disruptions.StationCapacity:
+			if c, ok := capacities[d.Target]; !ok || d.Capacity < c {
+				capacities[d.Target] = d.Capacity
+			}
+		case disruptions.SpeedRestriction:
+			if v, ok := restrictions[d.Target]; !ok || d.MaxSpeed < v {
+				restrictions[d.Target] = d.MaxSpeed
+			}
+		}
+	}
+
+	for segmentID := range s.segmentInboxes {
+		if closed[segmentID] != s.applied.closedSegments[segmentID] {
+			notify[segments.SegmentMessage](s, "segment "+segmentID, s.segmentInboxes[segmentID], segments.StatusChange{Closed: closed[segmentID]})
+			notify[navigation.NavigationMessage](s, "navigation", s.navigationService.Inbox(), navigation.SegmentStatusNotification{SegmentID: segmentID, Closed: closed[segmentID]})
+			if closed[segmentID] {
+				s.announceClosure(segmentID)
+			}
+		}
+		if restrictions[segmentID] != s.applied.speedRestrictions[segmentID] {
+			notify[segments.SegmentMessage](s, "segment "+segmentID, s.segmentInboxes[segmentID], segments.SpeedRestrictionChange{MaxSpeed: restrictions[segmentID]})
+		}
+	}
+
+	for stationID, inbox := range s.stationInboxes {
+		c, restricted := capacities[stationID]
+		prev, wasRestricted := s.applied.stationCapacities[stationID]
+		if restricted != wasRestricted || c != prev {
+			notify[stations.StationMessage](s, "station "+stationID, inbox, stations.CapacityRestriction{Capacity: c, Lifted: !restricted})
+		}
+	}
+
+	s.applied = appliedDisruptions{
+		closedSegments:    closed,
+		stationCapacities: capacities,
+		speedRestrictions: restrictions,
+	}
+}
+
+// announceClosure tells the running trains that a segment closed, for those
+// whose path crosses it to take another one from their next station.
+func (s *Simulation) announceClosure(segmentID string) {
+	for _, train := range s.trains {
+		if _, running := train.Status(s.currentTime); running {
+			notify[dispatcher.Order](s, "train "+train.ID(), train.Orders(), dispatcher.ClosureNotice{SegmentID: segmentID})
+		}
+	}
+}
+
+func (s *Simulation) disruptionsJSON() []map[string]any {
+	list := make([]map[string]any, 0, len(s.disruptions))
+	for _, d := range s.disruptions {
+		list = append(list, map[string]any{
+			"disruption": d,
+			"active":     d.IsActive(s.currentTime),
+		})
+	}
+	return list
+}
@@ -0,0 +1,99 @@
+package simulation_test
+
+import (
+	"testing"
+	"time"
+
+	"ai30-project/internal/disruptions"
+	"ai30-project/internal/simulation"
+	"ai30-project/internal/trains"
+)
+
+func TestAddDisruptionRejectsInvalid(t *testing.T) {
+	stationList, segmentList, paths := network()
+	scenario := simulation.Scenario{Trains: throughTrains(t), Stations: stationList, Segments: segmentList, Paths: paths}
+	sim, err := simulation.NewScenarioSimulation(scenario, "eco", "no_sort")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := disruptions.Disruption{Kind: disruptions.SpeedRestriction, Target: "A-B", Start: at(t, "08:00"), End: at(t, "09:00"), MaxSpeed: 20}
+	invalid := map[string]func(d *disruptions.Disruption){
+		"unknown segment":     func(d *disruptions.Disruption) { d.Target = "A-C" },
+		"unknown station":     func(d *disruptions.Disruption) { d.Kind, d.Target = disruptions.StationCapacity, "Z" },
+		"unknown kind":        func(d *disruptions.Disruption) { d.Kind = "flood" },
+		"ends when it starts": func(d *disruptions.Disruption) { d.End = d.Start },
+		"ends before":         func(d *disruptions.Disruption) { d.End = at(t, "07:00") },
+		"no speed":            func(d *disruptions.Disruption) { d.MaxSpeed = 0 },
+		"negative speed":      func(d *disruptions.Disruption) { d.MaxSpeed = -20 },
+		"negative capacity":   func(d *disruptions.Disruption) { d.Kind, d.Target, d.Capacity = disruptions.StationCapacity, "B", -1 },
+	}
+	for name, change := range invalid {
+		d := valid
+		change(&d)
+		if err := sim.AddDisruption(valid, d); err == nil {
+			t.Errorf("%s: disruption %+v accepted", name, d)
+		}
+	}
+
+	untilEnd := valid
+	untilEnd.End = 0
+	if err := sim.AddDisruption(valid, untilEnd); err != nil {
+		t.Errorf("valid disruptions rejected: %v", err)
+	}
+}
+
+// runDisruption runs a train from A to B, due at 08:30, under the given
+// disruptions and returns its departure from A and arrival at B.
+func runDisruption(t *testing.T, ds ...disruptions.Disruption) (departure, arrival time.Duration) {
+	stationList, segmentList, paths := network()
+	ab := train(t, "AB:OUI:FR:Line::AB", stop{"A", "08:00", "08:00"}, stop{"B", "08:30", "08:30"})
+	scenario := simulation.Scenario{Trains: []*trains.Train{ab}, Stations: stationList, Segments: segmentList, Paths: paths}
+	sim, err := simulation.NewScenarioSimulation(scenario, "eco", "no_sort")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.SetEventModels(noEvents)
+	sim.SetSeed(seed)
+	sim.SetMaxTime(maxTime)
+	sim.SetInvariantChecks(true)
+	if err := sim.AddDisruption(ds...); err != nil {
+		t.Fatal(err)
+	}
+
+	sim.Start()
+	for !sim.IsFinished() {
+		sim.Tick()
+	}
+	sim.Stop()
+	if err := sim.Failure(); err != nil {
+		t.Fatal(err)
+	}
+
+	departure, _ = ab.StartStop().DepartedAt()
+	arrival, _ = ab.EndStop().ArrivedAt()
+	return departure, arrival
+}
+
+func TestDisruptions(t *testing.T) {
+	_, onTime := runDisruption(t)
+	if onTime > at(t, "08:30") {
+		t.Fatalf("train arrived at %v undisrupted, want on time", onTime)
+	}
+
+	departure, arrival := runDisruption(t, disruptions.Disruption{Kind: disruptions.SegmentBlocked, Target: "A-B", Start: at(t, "07:00"), End: at(t, "08:20")})
+	if departure < at(t, "08:20") || arrival <= onTime {
+		t.Errorf("train left A at %v and reached B at %v with A-B blocked until 08:20, want it kept at A", departure, arrival)
+	}
+
+	// 30 km at 10 m/s take 50 minutes
+	_, arrival = runDisruption(t, disruptions.Disruption{Kind: disruptions.SpeedRestriction, Target: "A-B", Start: at(t, "07:00"), MaxSpeed: 10})
+	if arrival < at(t, "08:50") {
+		t.Errorf("train reached B at %v under a 10 m/s restriction, want after 08:50", arrival)
+	}
+
+	_, arrival = runDisruption(t, disruptions.Disruption{Kind: disruptions.StationCapacity, Target: "B", Start: at(t, "07:00"), End: at(t, "09:00"), Capacity: 0})
+	if arrival < at(t, "09:00") {
+		t.Errorf("train entered B at %v while closed until 09:00", arrival)
+	}
+}
@@ -0,0 +1,80 @@
+package simulation_test
+
+import (
+	"testing"
+	"time"
+
+	"ai30-project/internal/simulation"
+	"ai30-project/internal/trains"
+)
+
+// runClosure runs a train from Z to B, due at 08:40, while segmentID closes at
+// closeAt, or before the start when zero, and returns the train.
+func runClosure(t *testing.T, segmentID string, closeAt time.Duration) *trains.Train {
+	stationList, segmentList, paths := detourNetwork()
+	through := train(t, "THROUGH:OUI:FR:Line::ZB", stop{"Z", "08:00", "08:00"}, stop{"B", "08:40", "08:40"})
+
+	scenario := simulation.Scenario{Trains: []*trains.Train{through}, Stations: stationList, Segments: segmentList, Paths: paths}
+	sim, err := simulation.NewScenarioSimulation(scenario, "eco", "no_sort")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.SetEventModels(noEvents)
+	sim.SetSeed(seed)
+	sim.SetMaxTime(maxTime)
+	sim.SetInvariantChecks(true)
+
+	closeSegment := func() {
+		if err := sim.CloseSegment(segmentID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if closeAt == 0 {
+		closeSegment()
+	}
+	sim.Start()
+	for !sim.IsFinished() {
+		if closeAt != 0 && sim.CurrentTime() == closeAt {
+			closeSegment()
+		}
+		sim.Tick()
+	}
+	sim.Stop()
+	if err := sim.Failure(); err != nil {
+		t.Fatal(err)
+	}
+	return through
+}
+
+func TestClosureDivertsTrain(t *testing.T) {
+	cases := []struct {
+		name       string
+		segmentID  string
+		closeAt    string
+		diversions int
+	}{
+		{"off the path", "X-B", "08:05", 0},
+		{"before departure", "A-B", "", 1},
+		// The train, on Z-A, takes the detour from A without stopping
+		{"en route", "A-B", "08:05", 1},
+	}
+	for _, c := range cases {
+		closeAt := time.Duration(0)
+		if c.closeAt != "" {
+			closeAt = at(t, c.closeAt)
+		}
+		through := runClosure(t, c.segmentID, closeAt)
+
+		diversions, added := through.Diversions()
+		if diversions != c.diversions {
+			t.Errorf("%s: %d diversions, want %d", c.name, diversions, c.diversions)
+		}
+		// The detour is one link longer, run in 675 s at 160 km/h
+		if diversions > 0 && added != 675*time.Second {
+			t.Errorf("%s: diversion added %v, want 11m15s", c.name, added)
+		}
+		if arrivedAt, ok := through.EndStop().ArrivedAt(); !ok || arrivedAt > at(t, "08:40") {
+			t.Errorf("%s: train arrived at B at %v (arrived: %v), want on time", c.name, arrivedAt, ok)
+		}
+	}
+}
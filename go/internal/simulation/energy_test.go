@@ -0,0 +1,57 @@
+package simulation_test
+
+import (
+	"testing"
+
+	"ai30-project/internal/energy"
+	"ai30-project/internal/simulation"
+	"ai30-project/internal/trains"
+)
+
+// runEnergy runs a train from A to C through B with the driver and returns
+// the energy it used, by segment. The train is timed faster than it can run,
+// so that every driver runs as fast as it cares to.
+func runEnergy(t *testing.T, driver string) map[string]energy.Usage {
+	stationList, segmentList, paths := network()
+	ac := train(t, "AC:OUI:FR:Line::AC", stop{"A", "08:00", "08:00"}, stop{"B", "08:10", "08:15"}, stop{"C", "08:25", "08:25"})
+	scenario := simulation.Scenario{Trains: []*trains.Train{ac}, Stations: stationList, Segments: segmentList, Paths: paths}
+	sim, err := simulation.NewScenarioSimulation(scenario, driver, "no_sort")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.SetEventModels(noEvents)
+	sim.SetSeed(seed)
+	sim.SetMaxTime(maxTime)
+
+	sim.Start()
+	for !sim.IsFinished() {
+		sim.Tick()
+	}
+	sim.Stop()
+	if err := sim.Failure(); err != nil {
+		t.Fatal(err)
+	}
+	return ac.Energy()
+}
+
+// TestEnergyByDriver checks the crazy driver, running at line speed, uses more
+// energy than the eco driver, capped to 70% of it.
+func TestEnergyByDriver(t *testing.T) {
+	net := make(map[string]float64)
+	for _, driver := range []string{"eco", "crazy"} {
+		bySegment := runEnergy(t, driver)
+		if len(bySegment) != 2 {
+			t.Errorf("%s: energy booked on %d segments, want A-B and B-C", driver, len(bySegment))
+		}
+		for segmentID, usage := range bySegment {
+			if usage.Traction <= 0 || usage.Regenerated <= 0 || usage.Regenerated >= usage.Traction {
+				t.Errorf("%s: %+v on %s, want traction and less regenerated when braking for the stop", driver, usage, segmentID)
+			}
+			net[driver] += usage.Net()
+		}
+	}
+
+	if net["crazy"] <= net["eco"] {
+		t.Errorf("crazy driver used %.1f kWh, want more than the %.1f kWh of the eco driver", energy.KWh(net["crazy"]), energy.KWh(net["eco"]))
+	}
+}
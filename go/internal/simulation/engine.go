@@ -1,12 +1,26 @@
 package simulation
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"math/rand"
+	"sync"
 	"time"
 
+	"ai30-project/internal/circulation"
+	"ai30-project/internal/connections"
+	"ai30-project/internal/crew"
 	"ai30-project/internal/data"
+	"ai30-project/internal/dispatcher"
+	"ai30-project/internal/disruptions"
+	"ai30-project/internal/energy"
+	"ai30-project/internal/events"
+	"ai30-project/internal/messaging"
 	"ai30-project/internal/navigation"
+	"ai30-project/internal/passengers"
+	"ai30-project/internal/power"
+	"ai30-project/internal/propagation"
 	"ai30-project/internal/segments"
 	"ai30-project/internal/stations"
 	"ai30-project/internal/trains"
@@ -14,15 +28,44 @@ import (
 
 type Simulation struct {
 	isStarted       bool
+	isStopped       bool
 	currentTime     time.Duration
 	activeTrains    int
 	driverBehavior  string
 	stationStrategy string
+	seed            int64
+	eventModels     *events.ModelSet
 
 	trains            map[string]*trains.Train
 	stations          map[string]*stations.Station
 	segments          map[string]*segments.Segment
 	navigationService *navigation.NavigationService
+	demand            *passengers.Demand
+	connectionService *connections.ConnectionService
+	circulation       *circulation.CirculationService
+	crewService       *crew.CrewService
+	dispatcher        *dispatcher.Dispatcher
+	powerSupply       *power.Supply
+	tracer            *propagation.Tracer
+	tap               *messaging.Tap
+	invariants        *invariantChecks
+	deadlocks         *deadlockDetection
+	maxTime           time.Duration
+	failure           error
+
+	disruptions    []disruptions.Disruption
+	manualClosures map[string]bool
+	applied        appliedDisruptions
+
+	// Agent lifecycle
+	ctx            context.Context
+	cancel         context.CancelFunc
+	agents         sync.WaitGroup
+	supervisor     *supervisor
+	requestTimeout time.Duration
+	scheduler      string
+	sequential     *sequentialScheduler // nil when every agent has its own goroutine
+	finalState     *finalState          // report kept once the agents are stopped
 
 	tickChan       chan time.Duration
 	doneChan       chan bool
@@ -30,11 +73,51 @@ type Simulation struct {
 	segmentInboxes map[string]chan segments.SegmentMessage
 }
 
-func NewSimulation(driverBehaviorName, stationStrategyName string) *Simulation {
-	trainsData := data.GetTrainsData()
-	stationsData := data.GetStationsData()
-	segmentsData := data.GetSegmentsData()
-	pathsData := data.GetPathsData()
+// Scenario is the network and timetable a simulation runs on.
+type Scenario struct {
+	Trains   []*trains.Train
+	Stations []*stations.Station
+	Segments []*segments.Segment
+	Paths    navigation.Paths
+}
+
+// DefaultScenario is the national network with its timetable.
+func DefaultScenario() Scenario {
+	return Scenario{
+		Trains:   data.GetTrainsData(),
+		Stations: data.GetStationsData(),
+		Segments: data.GetSegmentsData(),
+		Paths:    data.GetPathsData(),
+	}
+}
+
+func NewSimulation(driverBehaviorName, stationStrategyName string) (*Simulation, error) {
+	return NewScenarioSimulation(DefaultScenario(), driverBehaviorName, stationStrategyName)
+}
+
+// NewScenarioSimulation runs the trains of a scenario on its network, with a
+// registered driver and station strategy taking their default parameters.
+// Events are drawn with a random seed until SetSeed is called.
+func NewScenarioSimulation(scenario Scenario, driverBehaviorName, stationStrategyName string) (*Simulation, error) {
+	return NewScenarioSimulationWithParams(scenario, driverBehaviorName, nil, stationStrategyName, nil)
+}
+
+// NewScenarioSimulationWithParams is NewScenarioSimulation with parameters
+// for the driver and the station strategy, as given to their registries.
+func NewScenarioSimulationWithParams(scenario Scenario, driverBehaviorName string, driverParams map[string]any, stationStrategyName string, strategyParams map[string]any) (*Simulation, error) {
+	driverBehavior, err := trains.NewDriverBehavior(driverBehaviorName, driverParams)
+	if err != nil {
+		return nil, err
+	}
+	stationStrategy, err := stations.NewStationStrategy(stationStrategyName, strategyParams)
+	if err != nil {
+		return nil, err
+	}
+
+	trainsData := scenario.Trains
+	stationsData := scenario.Stations
+	segmentsData := scenario.Segments
+	pathsData := scenario.Paths
 
 	earliestDeparture := trainsData[0].StartStop().Departure()
 	for _, train := range trainsData[1:] {
@@ -48,6 +131,8 @@ func NewSimulation(driverBehaviorName, stationStrategyName string) *Simulation {
 		activeTrains:    len(trainsData),
 		driverBehavior:  driverBehaviorName,
 		stationStrategy: stationStrategyName,
+		seed:            time.Now().UnixNano(),
+		eventModels:     events.DefaultModelSet(),
 		trains:          make(map[string]*trains.Train),
 		stations:        make(map[string]*stations.Station),
 		segments:        make(map[string]*segments.Segment),
@@ -55,13 +140,16 @@ func NewSimulation(driverBehaviorName, stationStrategyName string) *Simulation {
 		doneChan:        make(chan bool),
 		stationInboxes:  make(map[string]chan stations.StationMessage),
 		segmentInboxes:  make(map[string]chan segments.SegmentMessage),
+		manualClosures:  make(map[string]bool),
+		applied:         newAppliedDisruptions(),
+		supervisor:      newSupervisor(),
+		requestTimeout:  messaging.DefaultTimeout,
+		scheduler:       SchedulerConcurrent,
 	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
 
 	s.navigationService = navigation.NewNavigationService(pathsData, s.segments)
 
-	driverBehavior := trains.NewDriverBehavior(driverBehaviorName)
-	stationStrategy := stations.NewStationStrategy(stationStrategyName)
-
 	for _, segment := range segmentsData {
 		s.segments[segment.ID()] = segment
 		s.segmentInboxes[segment.ID()] = segment.Inbox()
@@ -79,38 +167,353 @@ func NewSimulation(driverBehaviorName, stationStrategyName string) *Simulation {
 		train.SetChannels(s.tickChan, s.doneChan, s.stationInboxes, s.segmentInboxes, s.navigationService.Inbox())
 	}
 
-	return s
+	s.generateEvents()
+
+	return s, nil
+}
+
+// SetEventModels draws the train events again from fitted models. It must be
+// called before Start.
+func (s *Simulation) SetEventModels(models *events.ModelSet) {
+	s.eventModels = models
+	s.generateEvents()
+}
+
+// SetSeed draws the train events again from seed, so that runs with the same
+// seed meet the same events. It must be called before Start.
+func (s *Simulation) SetSeed(seed int64) {
+	s.seed = seed
+	s.generateEvents()
+}
+
+// generateEvents draws the timeline of every train, in ID order so that the
+// draws only depend on the seed, and hands infrastructure incidents over to
+// their segments.
+func (s *Simulation) generateEvents() {
+	rng := rand.New(rand.NewSource(s.seed))
+
+	incidents := make(map[string][]events.DelayEvent)
+	for _, id := range sortedKeys(s.trains) {
+		train := s.trains[id]
+		train.GenerateEvents(s.eventModels, rng)
+		for _, incident := range train.AnchorIncidents(s.navigationService.ScheduledPath, rng) {
+			incidents[incident.SegmentID] = append(incidents[incident.SegmentID], incident)
+		}
+	}
+
+	for id, segment := range s.segments {
+		segment.SetIncidents(incidents[id])
+	}
+}
+
+// SetDriverBehavior hands every train to a driver built outside the named
+// ones, such as a parametric driver loaded from a file. It must be called
+// before Start.
+func (s *Simulation) SetDriverBehavior(name string, driver trains.DriverBehavior) {
+	s.driverBehavior = name
+	for _, train := range s.trains {
+		train.SetDriver(driver)
+	}
+}
+
+// SetStationStrategy has every station admit trains with a strategy built
+// outside the defaults, such as one with parameters. It must be called
+// before Start.
+func (s *Simulation) SetStationStrategy(name string, strategy stations.StationStrategy) {
+	s.stationStrategy = name
+	for _, station := range s.stations {
+		station.SetStrategy(strategy)
+	}
+}
+
+// SetEnergyModel sets the traction of every train, such as how much of the
+// braking energy is regenerated. It must be called before Start.
+func (s *Simulation) SetEnergyModel(model energy.Model) {
+	for _, train := range s.trains {
+		train.SetEnergyModel(model)
+	}
+}
+
+// SetDemand enables passenger flows. It must be called before Start.
+func (s *Simulation) SetDemand(demand *passengers.Demand) {
+	s.demand = demand
+	for _, station := range s.stations {
+		station.SetDemand(demand)
+	}
+}
+
+// SetConnections enables transfer connections between trains, managed by the
+// named delay-management policy. Both trains must be in the timetable, the
+// feeder arriving at the station of the connection and the connecting train
+// leaving from it. It must be called before Start.
+func (s *Simulation) SetConnections(conns []connections.Connection, policyName string, policy connections.Policy) error {
+	for i, conn := range conns {
+		feeder, ok := s.trains[conn.FeederTrainID]
+		if !ok {
+			return fmt.Errorf("connection %d: unknown feeder train %s", i, conn.FeederTrainID)
+		}
+		connecting, ok := s.trains[conn.ConnectingTrainID]
+		if !ok {
+			return fmt.Errorf("connection %d: unknown connecting train %s", i, conn.ConnectingTrainID)
+		}
+		if stops := feeder.Stops(); len(stops) == 0 || !callsAt(stops[1:], conn.StationID) {
+			return fmt.Errorf("connection %d: feeder train %s does not arrive at %s", i, conn.FeederTrainID, conn.StationID)
+		}
+		if stops := connecting.Stops(); len(stops) == 0 || !callsAt(stops[:len(stops)-1], conn.StationID) {
+			return fmt.Errorf("connection %d: connecting train %s does not leave from %s", i, conn.ConnectingTrainID, conn.StationID)
+		}
+	}
+
+	s.connectionService = connections.NewConnectionService(conns, policyName, policy)
+	for _, train := range s.trains {
+		train.SetConnectionInbox(s.connectionService.Inbox())
+	}
+	return nil
+}
+
+// callsAt reports whether one of stops is at stationID.
+func callsAt(stops []*trains.TrainStop, stationID string) bool {
+	for _, stop := range stops {
+		if stop.StationID() == stationID {
+			return true
+		}
+	}
+	return false
+}
+
+// SetCapacities gives every train its capacity. Trains keep the default
+// capacity of the rolling stock otherwise. Every train listed must be in the
+// timetable. It must be called before Start.
+func (s *Simulation) SetCapacities(capacities *passengers.Capacities) error {
+	for _, trainID := range sortedKeys(capacities.Trains) {
+		if _, ok := s.trains[trainID]; !ok {
+			return fmt.Errorf("capacity: unknown train %s", trainID)
+		}
+	}
+
+	for id, train := range s.trains {
+		capacity := capacities.Of(id)
+		train.SetCapacity(capacity.Seats, capacity.Standing)
+	}
+	return nil
+}
+
+// SetRotations links trips operated by the same trainset so that late
+// arrivals at a terminus delay the next trip. Every trip must be a train of
+// the timetable, leaving from the terminus of the previous trip after it is
+// scheduled to arrive there. It must be called before Start.
+func (s *Simulation) SetRotations(rotations []circulation.Rotation) error {
+	for _, rotation := range rotations {
+		for i, trainID := range rotation.Trips {
+			train, ok := s.trains[trainID]
+			if !ok {
+				return fmt.Errorf("rotation %s: unknown train %s", rotation.ID, trainID)
+			}
+			if i == 0 {
+				continue
+			}
+
+			previous := s.trains[rotation.Trips[i-1]].EndStop()
+			if start := train.StartStop(); start.StationID() != previous.StationID() {
+				return fmt.Errorf("rotation %s: train %s leaves from %s, not from %s where train %s ends",
+					rotation.ID, trainID, start.StationID(), previous.StationID(), rotation.Trips[i-1])
+			} else if start.Departure() < previous.Arrival() {
+				return fmt.Errorf("rotation %s: train %s leaves %s before train %s arrives there",
+					rotation.ID, trainID, start.StationID(), rotation.Trips[i-1])
+			}
+		}
+	}
+
+	s.circulation = circulation.NewCirculationService(rotations)
+	for _, train := range s.trains {
+		train.SetCirculationInbox(s.circulation.Inbox())
+	}
+	return nil
+}
+
+// SetRoster makes departures from relief points wait for the incoming crew.
+// It must be called before Start.
+func (s *Simulation) SetRoster(roster *crew.Roster) {
+	s.crewService = crew.NewCrewService(roster)
+	for _, train := range s.trains {
+		train.SetCrewInbox(s.crewService.Inbox())
+	}
+}
+
+// SetDispatcher hands control to a central dispatcher that predicts conflicts
+// within horizon, which must be positive, and issues orders to trains and
+// stations as planner decides. Without it, control stays decentralised. It
+// must be called before Start.
+func (s *Simulation) SetDispatcher(planner dispatcher.Planner, horizon time.Duration) error {
+	d, err := dispatcher.NewDispatcher(planner, horizon, s.stations)
+	if err != nil {
+		return err
+	}
+
+	s.dispatcher = d
+	s.dispatcher.SetRequestTimeout(s.requestTimeout)
+	for _, train := range s.trains {
+		s.dispatcher.RegisterTrain(train.ID(), train.Orders())
+		train.SetDispatcherInbox(s.dispatcher.Inbox())
+	}
+	return nil
+}
+
+// SetPowerSupply limits the tractive effort of the trains on the feeding
+// sections of network to what their substations provide. It must be called
+// before Start.
+func (s *Simulation) SetPowerSupply(network *power.Network) {
+	s.powerSupply = power.NewSupply(network)
+	for _, train := range s.trains {
+		train.SetPowerInbox(s.powerSupply.Inbox())
+	}
+}
+
+// SetTracing records every wait of every train to attribute delays to their
+// primary and knock-on causes. It must be called before Start.
+func (s *Simulation) SetTracing() {
+	s.tracer = propagation.NewTracer()
+	for _, train := range s.trains {
+		train.SetTracerInbox(s.tracer.Inbox())
+	}
+}
+
+// CloseSegment closes a segment to new trains; the navigation service then
+// routes trains around it. It may be called between ticks.
+func (s *Simulation) CloseSegment(segmentID string) error {
+	return s.setSegmentClosed(segmentID, true)
+}
+
+// OpenSegment reopens a segment closed by CloseSegment. A segment blocked by
+// an active disruption stays closed until the disruption ends.
+func (s *Simulation) OpenSegment(segmentID string) error {
+	return s.setSegmentClosed(segmentID, false)
+}
+
+// OrderAdmission has a station admit the listed trains first, in this order,
+// as the dispatcher would. An empty order gives the station back to its
+// strategy. It may be called between ticks.
+func (s *Simulation) OrderAdmission(stationID string, trainIDs []string) error {
+	if !s.isStarted || s.isStopped {
+		return fmt.Errorf("simulation is not running")
+	}
+	inbox, ok := s.stationInboxes[stationID]
+	if !ok {
+		return fmt.Errorf("unknown station %s", stationID)
+	}
+
+	notify[stations.StationMessage](s, "station "+stationID, inbox, stations.PriorityOrder{TrainIDs: trainIDs})
+	return nil
+}
+
+// StationStates returns a snapshot of every station, by ID. It may be called
+// between ticks.
+func (s *Simulation) StationStates() ([]stations.State, error) {
+	if !s.isStarted || s.isStopped {
+		return nil, fmt.Errorf("simulation is not running")
+	}
+
+	states := make([]stations.State, 0, len(s.stationInboxes))
+	for _, id := range sortedKeys(s.stationInboxes) {
+		responseCh := make(chan stations.State, 1)
+		state, err := request[stations.StationMessage](s, "station "+id, s.stationInboxes[id],
+			stations.StateRequest{ResponseCh: responseCh}, responseCh)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+func (s *Simulation) setSegmentClosed(segmentID string, closed bool) error {
+	if s.isStopped {
+		return fmt.Errorf("simulation is stopped")
+	}
+	if _, ok := s.segmentInboxes[segmentID]; !ok {
+		return fmt.Errorf("unknown segment %s", segmentID)
+	}
+
+	if closed {
+		s.manualClosures[segmentID] = true
+	} else {
+		delete(s.manualClosures, segmentID)
+	}
+	s.applyDisruptions()
+	return nil
+}
+
+// CurrentTime is the time of the last tick.
+func (s *Simulation) CurrentTime() time.Duration {
+	return s.currentTime
 }
 
 func (s *Simulation) IsStarted() bool {
 	return s.isStarted
 }
 
+// IsFinished reports whether every train completed its journey or the run
+// was aborted or stopped.
 func (s *Simulation) IsFinished() bool {
-	return s.activeTrains == 0
+	return s.activeTrains == 0 || s.failure != nil || s.isStopped
 }
 
+// Start launches every agent. A stopped simulation cannot be started again.
 func (s *Simulation) Start() {
-	if s.IsStarted() {
+	if s.IsStarted() || s.isStopped {
 		return
 	}
 
-	fmt.Printf("[Simulation] Starting simulation with %d trains, %d stations and %d segments\n",
-		len(s.trains), len(s.stations), len(s.segments))
+	fmt.Printf("[Simulation] Starting simulation with %d trains, %d stations and %d segments (%s scheduler)\n",
+		len(s.trains), len(s.stations), len(s.segments), s.scheduler)
 
-	for _, train := range s.trains {
-		go train.Run()
+	if s.tap != nil {
+		s.connectTap()
 	}
 
-	for _, station := range s.stations {
-		go station.Run()
+	if s.sequential != nil {
+		s.startSequential()
+		s.isStarted = true
+		return
+	}
+
+	for id, train := range s.trains {
+		s.spawn("train "+id, train.Run)
+	}
+
+	for id, station := range s.stations {
+		s.spawn("station "+id, station.Run)
+	}
+
+	for id, segment := range s.segments {
+		s.spawn("segment "+id, segment.Run)
+	}
+
+	s.spawn("navigation", s.navigationService.Run)
+
+	if s.connectionService != nil {
+		s.spawn("connections", s.connectionService.Run)
+	}
+
+	if s.circulation != nil {
+		s.spawn("circulation", s.circulation.Run)
+	}
+
+	if s.crewService != nil {
+		s.spawn("crew", s.crewService.Run)
+	}
+
+	if s.dispatcher != nil {
+		s.spawn("dispatcher", s.dispatcher.Run)
 	}
 
-	for _, segment := range s.segments {
-		go segment.Run()
+	if s.powerSupply != nil {
+		s.spawn("power", s.powerSupply.Run)
 	}
 
-	go s.navigationService.Run()
+	if s.tracer != nil {
+		s.spawn("tracer", s.tracer.Run)
+	}
 
 	s.isStarted = true
 }
@@ -121,31 +524,56 @@ func (s *Simulation) Tick() {
 	}
 
 	s.currentTime += time.Minute
+	s.tap.SetTime(s.currentTime)
 	fmt.Printf("[Simulation] Tick: %v\n", s.currentTime)
 
-	// Phase 1: percept + deliberate
-	for range s.activeTrains {
-		s.tickChan <- s.currentTime
+	s.applyDisruptions()
+
+	if s.sequential != nil {
+		s.activeTrains = s.stepTrains()
+	} else {
+		s.activeTrains = s.tickTrains()
 	}
-	for range s.activeTrains {
-		<-s.doneChan
+	if s.activeTrains == 0 {
+		fmt.Printf("[Simulation] All trains have completed their journeys\n")
 	}
 
-	// Phase 2: act
-	for range s.activeTrains {
-		s.tickChan <- s.currentTime
+	// Phase 3: the power supply shares out the demands of the tick
+	if s.powerSupply != nil {
+		responseCh := make(chan power.BalanceResponse, 1)
+		if _, err := request[power.PowerMessage](s, "power", s.powerSupply.Inbox(),
+			power.BalanceRequest{Time: s.currentTime, ResponseCh: responseCh}, responseCh); err != nil {
+			fmt.Printf("[Simulation] ERROR: Balancing power %v\n", err)
+		}
 	}
 
-	stillActive := 0
-	for range s.activeTrains {
-		if !<-s.doneChan {
-			stillActive++
+	// The dispatcher plans the next tick from the reported statuses
+	if s.dispatcher != nil {
+		responseCh := make(chan dispatcher.PlanResponse, 1)
+		if _, err := request[dispatcher.DispatcherMessage](s, "dispatcher", s.dispatcher.Inbox(),
+			dispatcher.PlanRequest{Time: s.currentTime, ResponseCh: responseCh}, responseCh); err != nil {
+			fmt.Printf("[Simulation] ERROR: Planning %v\n", err)
 		}
 	}
 
-	s.activeTrains = stillActive
-	if s.activeTrains == 0 {
-		fmt.Printf("[Simulation] All trains have completed their journeys\n")
+	// Agents stepped in place settle the orders of the dispatcher and the
+	// notifications sent between ticks before the checks
+	if s.sequential != nil {
+		s.deliverAll()
+	}
+
+	s.checkCrashes()
+
+	if s.invariants != nil {
+		s.checkInvariants()
+	}
+
+	if s.deadlocks != nil && s.failure == nil {
+		s.detectDeadlocks()
+	}
+
+	if s.failure == nil {
+		s.checkMaxTime()
 	}
 }
 
@@ -159,5 +587,15 @@ func (s *Simulation) MarshalJSON() ([]byte, error) {
 		"trains":          s.trains,
 		"stations":        s.stations,
 		"segments":        s.segments,
+		"disruptions":     s.disruptionsJSON(),
+		"report":          s.Report(),
+		"failure":         errorString(s.failure),
 	})
 }
+
+func errorString(err error) any {
+	if err == nil {
+		return nil
+	}
+	return err.Error()
+}
@@ -0,0 +1,155 @@
+package simulation_test
+
+import (
+	"reflect"
+	"testing"
+
+	"ai30-project/internal/gym"
+	"ai30-project/internal/simulation"
+	"ai30-project/internal/trains"
+)
+
+func contentionEnv(t *testing.T, reward gym.Reward) *gym.Env {
+	env := gym.NewEnv(gym.Config{
+		Scenario: func() simulation.Scenario {
+			stationList, segmentList, paths := network()
+			return simulation.Scenario{Trains: stationContention(t), Stations: stationList, Segments: segmentList, Paths: paths}
+		},
+		DriverBehavior:  "eco",
+		StationStrategy: "no_sort",
+		Reward:          reward,
+		Setup: func(sim *simulation.Simulation) error {
+			sim.SetEventModels(noEvents)
+			sim.SetMaxTime(maxTime)
+			closeStation(t, sim)
+			return nil
+		},
+	})
+	t.Cleanup(env.Close)
+	return env
+}
+
+// episode plays the policy until the end and returns the observations and
+// the total reward.
+func episode(t *testing.T, env *gym.Env, policy func(gym.Observation) gym.Actions) ([]gym.Observation, float64) {
+	t.Helper()
+	observation, err := env.Reset(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	observations := []gym.Observation{observation}
+	total := 0.0
+	for done := false; !done; {
+		var reward float64
+		observation, reward, done, err = env.Step(policy(observation))
+		if err != nil {
+			t.Fatal(err)
+		}
+		observations = append(observations, observation)
+		total += reward
+	}
+	return observations, total
+}
+
+func idle(gym.Observation) gym.Actions { return gym.Actions{} }
+
+// arrivalsAtB gives the trains in the order they reached B.
+func arrivalsAtB(observations []gym.Observation) []string {
+	var order []string
+	seen := map[string]bool{}
+	for _, observation := range observations {
+		for _, t := range observation.Trains {
+			if t.AtStation && t.Location == "B" && !seen[t.ID] {
+				seen[t.ID] = true
+				order = append(order, t.ID)
+			}
+		}
+	}
+	return order
+}
+
+func TestEnvReplaysEpisodes(t *testing.T) {
+	env := contentionEnv(t, gym.DelayReward)
+
+	first, reward := episode(t, env, idle)
+	if reward >= 0 {
+		t.Errorf("reward %.1f, want a penalty for the late train", reward)
+	}
+	second, _ := episode(t, env, idle)
+	if !reflect.DeepEqual(first, second) {
+		t.Error("the same seed gave two different episodes")
+	}
+
+	want := len(first[0].Trains)*gym.TrainFeatures + len(first[0].Stations)*gym.StationFeatures
+	for _, observation := range first {
+		if got := len(observation.Vector()); got != want {
+			t.Fatalf("vector of %d features at %v, want %d", got, observation.Time, want)
+		}
+	}
+}
+
+func TestEnvAppliesActions(t *testing.T) {
+	env := contentionEnv(t, gym.DelayReward)
+
+	// LATE asks for B first: admitting ONTIME first reverses the order
+	observations, _ := episode(t, env, idle)
+	if got := arrivalsAtB(observations); len(got) != 2 || got[0] != "LATE:OUI:FR:Line::AC" {
+		t.Fatalf("arrivals at B %v without orders, want LATE first", got)
+	}
+	observations, _ = episode(t, env, func(gym.Observation) gym.Actions {
+		return gym.Actions{Admissions: map[string][]string{"B": {"ONTIME:OGO:FR:Line::DC"}}}
+	})
+	if got := arrivalsAtB(observations); len(got) != 2 || got[0] != "ONTIME:OGO:FR:Line::DC" {
+		t.Errorf("arrivals at B %v with ONTIME ordered first", got)
+	}
+
+	// A sluggish driver, accelerating at a sixth of the rate of the eco driver,
+	// is slower and behind the uncommanded run five minutes into A-B
+	slow := trains.DriverCommand{DesiredSpeed: 0.3, DesiredAccel: 0.1, DesiredDecel: 0.5}
+	baseline := lateOnAB(t, env, nil)
+	commanded := lateOnAB(t, env, &slow)
+	if commanded.Speed >= baseline.Speed || commanded.Position >= baseline.Position {
+		t.Errorf("commanded train at %.0f m and %.1f m/s, want behind %.0f m and below %.1f m/s without a command",
+			commanded.Position, commanded.Speed, baseline.Position, baseline.Speed)
+	}
+
+	if _, _, _, err := env.Step(gym.Actions{Admissions: map[string][]string{"Z": nil}}); err == nil {
+		t.Error("an order for an unknown station was accepted")
+	}
+	if _, _, _, err := env.Step(gym.Actions{Drivers: map[string]trains.DriverCommand{"GHOST": slow}}); err == nil {
+		t.Error("a command for an unknown train was accepted")
+	}
+}
+
+// lateOnAB plays an episode with the LATE train driven by command, or by its
+// driver when nil, and observes it ten minutes after it leaves A.
+func lateOnAB(t *testing.T, env *gym.Env, command *trains.DriverCommand) gym.TrainObservation {
+	t.Helper()
+	if _, err := env.Reset(seed); err != nil {
+		t.Fatal(err)
+	}
+
+	actions := gym.Actions{}
+	if command != nil {
+		actions.Drivers = map[string]trains.DriverCommand{"LATE:OUI:FR:Line::AC": *command}
+	}
+	onAB := 0
+	for {
+		observation, _, done, err := env.Step(actions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tr := range observation.Trains {
+			if tr.ID != "LATE:OUI:FR:Line::AC" || tr.Location != "A-B" {
+				continue
+			}
+			if onAB++; onAB > 5 {
+				return tr
+			}
+		}
+		if done {
+			t.Fatal("episode ended before LATE ran five minutes on A-B")
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ai30-project/internal/invariants"
+	"ai30-project/internal/messaging"
+	"ai30-project/internal/segments"
+	"ai30-project/internal/stations"
+)
+
+type invariantChecks struct {
+	strict     bool
+	violations []invariants.Violation
+}
+
+func (c *invariantChecks) MarshalJSON() ([]byte, error) {
+	byRule := make(map[invariants.Rule]int)
+	for _, v := range c.violations {
+		byRule[v.Rule]++
+	}
+	return json.Marshal(map[string]any{
+		"strict":     c.strict,
+		"violations": len(c.violations),
+		"byRule":     byRule,
+	})
+}
+
+// SetInvariantChecks validates safety and consistency rules across every
+// agent after each tick. In strict mode, the first violation stops the run.
+func (s *Simulation) SetInvariantChecks(strict bool) {
+	s.invariants = &invariantChecks{strict: strict}
+}
+
+// Violations returns every invariant violation found so far.
+func (s *Simulation) Violations() []invariants.Violation {
+	if s.invariants == nil {
+		return nil
+	}
+	return s.invariants.violations
+}
+
+// Failure returns why the run was aborted, or nil.
+func (s *Simulation) Failure() error {
+	return s.failure
+}
+
+// checkInvariants takes a snapshot of every segment and station through their
+// inboxes, so that the notifications of the tick are processed first.
+func (s *Simulation) checkInvariants() {
+	segmentCh := make(chan segments.State, len(s.segmentInboxes))
+	for id, inbox := range s.segmentInboxes {
+		notify[segments.SegmentMessage](s, "segment "+id, inbox, segments.StateRequest{ResponseCh: segmentCh})
+	}
+	stationCh := make(chan stations.State, len(s.stationInboxes))
+	for id, inbox := range s.stationInboxes {
+		notify[stations.StationMessage](s, "station "+id, inbox, stations.StateRequest{ResponseCh: stationCh})
+	}
+
+	// A crashed agent never answers: skip the check rather than stall the tick
+	timeout := time.After(s.requestTimeout)
+	if s.sequential != nil {
+		s.deliverAll()
+		if len(segmentCh) < len(s.segmentInboxes) || len(stationCh) < len(s.stationInboxes) {
+			timeout = noWait()
+		}
+	}
+	segmentStates := make([]segments.State, 0, len(s.segmentInboxes))
+	stationStates := make([]stations.State, 0, len(s.stationInboxes))
+	for len(segmentStates) < len(s.segmentInboxes) || len(stationStates) < len(s.stationInboxes) {
+		select {
+		case state := <-segmentCh:
+			segmentStates = append(segmentStates, state)
+		case state := <-stationCh:
+			stationStates = append(stationStates, state)
+		case <-timeout:
+			fmt.Printf("[Invariants] ERROR: Skipping checks at %v: %v\n", s.currentTime, messaging.ErrTimeout)
+			return
+		}
+	}
+
+	violations := invariants.Check(s.currentTime, segmentStates, stationStates)
+	for _, v := range violations {
+		fmt.Printf("[Invariants] %v\n", v.Error())
+	}
+	s.invariants.violations = append(s.invariants.violations, violations...)
+
+	if s.invariants.strict && len(violations) > 0 {
+		s.failure = fmt.Errorf("strict invariant checks: %w", violations[0])
+		fmt.Printf("[Simulation] Aborted: %v\n", s.failure)
+	}
+}
@@ -0,0 +1,56 @@
+package simulation
+
+import (
+	"fmt"
+	"time"
+
+	"ai30-project/internal/propagation"
+)
+
+// finalState is what the stopped agents can no longer be asked for.
+type finalState struct {
+	report Report
+	trace  *propagation.Report
+}
+
+// Stop terminates every agent and returns once they all have, so that many
+// simulations can run one after another in the same process. The report of
+// the run stays available. It must not be called during Tick; calling it again
+// has no effect.
+func (s *Simulation) Stop() {
+	if s.isStopped {
+		return
+	}
+
+	if s.isStarted {
+		final := &finalState{report: s.Report()}
+		if trace, ok := s.DelayPropagation(); ok {
+			final.trace = &trace
+		}
+
+		s.cancel()
+		s.waitAgents()
+		s.finalState = final
+		fmt.Printf("[Simulation] Stopped at %v\n", s.currentTime)
+	}
+
+	s.cancel()
+	s.isStopped = true
+}
+
+// waitAgents waits for the agents to return. An agent stuck in a handler
+// cannot be interrupted, so it is reported and left behind after the request
+// timeout.
+func (s *Simulation) waitAgents() {
+	done := make(chan struct{})
+	go func() {
+		s.agents.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(s.requestTimeout):
+		fmt.Printf("[Simulation] ERROR: Some agents did not stop within %v\n", s.requestTimeout)
+	}
+}
@@ -0,0 +1,116 @@
+package simulation_test
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"ai30-project/internal/simulation"
+	"ai30-project/internal/stations"
+)
+
+// TestStopReleasesAgents runs simulations to the end and stops others halfway
+// through, and checks that no agent goroutine outlives them.
+func TestStopReleasesAgents(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	for _, c := range scenarioCases {
+		run(t, c)
+
+		sim, _ := newSimulation(t, c)
+		sim.Start()
+		for range 20 {
+			sim.Tick()
+		}
+		sim.Stop()
+		sim.Stop()
+	}
+
+	// Agents signal they are done just before their goroutine exits
+	after := runtime.NumGoroutine()
+	for deadline := time.Now().Add(time.Second); after > before && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		after = runtime.NumGoroutine()
+	}
+	if after > before {
+		t.Errorf("%d goroutines left running after Stop", after-before)
+	}
+}
+
+// crashStation makes station B panic on the first message it handles.
+func crashStation(stationList []*stations.Station) {
+	responseCh := make(chan stations.State, 1)
+	close(responseCh)
+	for _, station := range stationList {
+		if station.ID() == "B" {
+			station.Inbox() <- stations.StateRequest{ResponseCh: responseCh}
+		}
+	}
+}
+
+func TestCrashedAgentIsRestarted(t *testing.T) {
+	for _, scheduler := range schedulers {
+		t.Run(scheduler, func(t *testing.T) {
+			c := scenarioCases[1]
+			c.scheduler = scheduler
+			sim, scenario := newSimulation(t, c)
+			crashStation(scenario.Stations)
+
+			sim.Start()
+			for !sim.IsFinished() {
+				sim.Tick()
+			}
+			sim.Stop()
+
+			if err := sim.Failure(); err != nil {
+				t.Fatalf("run failed: %v", err)
+			}
+			crashes := sim.Crashes()
+			if len(crashes) != 1 || crashes[0].Agent != "station B" || !crashes[0].Restarted {
+				t.Errorf("want one restart of station B, got %+v", crashes)
+			}
+		})
+	}
+}
+
+func TestCrashedAgentFailsRun(t *testing.T) {
+	c := scenarioCases[1]
+	sim, scenario := newSimulation(t, c)
+	if err := sim.SetSupervision(simulation.SupervisionFail); err != nil {
+		t.Fatal(err)
+	}
+	crashStation(scenario.Stations)
+
+	sim.Start()
+	sim.Tick()
+	sim.Stop()
+
+	if err := sim.Failure(); err == nil || !strings.Contains(err.Error(), "station B") {
+		t.Errorf("want the run to fail on station B, got %v", err)
+	}
+}
+
+func TestStuckAgentTimesOut(t *testing.T) {
+	c := scenarioCases[1]
+	sim, scenario := newSimulation(t, c)
+	sim.SetRequestTimeout(20 * time.Millisecond)
+	sim.SetMaxTime(at(t, "08:45"))
+
+	// Station B blocks forever on a nil response channel
+	for _, station := range scenario.Stations {
+		if station.ID() == "B" {
+			station.Inbox() <- stations.StateRequest{}
+		}
+	}
+
+	sim.Start()
+	for !sim.IsFinished() {
+		sim.Tick()
+	}
+	sim.Stop()
+
+	if err := sim.Failure(); err == nil || !strings.Contains(err.Error(), "time limit") {
+		t.Errorf("want the run to reach the time limit, got %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package simulation
+
+import "ai30-project/internal/messaging"
+
+// SetMessageTap records every message sent to an agent by the trains, the
+// dispatcher and the simulation, with its response. It must be called before
+// Start.
+func (s *Simulation) SetMessageTap() {
+	s.tap = messaging.NewTap()
+}
+
+// Messages returns the recorded messages, if the tap is enabled.
+func (s *Simulation) Messages() []messaging.Record {
+	return s.tap.Records()
+}
+
+// connectTap hands the tap to the agents sending messages.
+func (s *Simulation) connectTap() {
+	for _, train := range s.trains {
+		train.SetTap(s.tap)
+	}
+	if s.dispatcher != nil {
+		s.dispatcher.SetTap(s.tap)
+	}
+}
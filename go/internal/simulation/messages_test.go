@@ -0,0 +1,53 @@
+package simulation_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ai30-project/internal/messaging"
+	"ai30-project/internal/simulation"
+)
+
+// TestMessageDiagrams records the messages of the follower catching up with
+// the leader and compares its sequence diagrams with testdata/messages.*.
+func TestMessageDiagrams(t *testing.T) {
+	c := scenarioCases[0]
+	c.scheduler = simulation.SchedulerSequential
+	sim, _ := newSimulation(t, c)
+	sim.SetMessageTap()
+
+	sim.Start()
+	for !sim.IsFinished() {
+		sim.Tick()
+	}
+	sim.Stop()
+
+	filter := messaging.Filter{Agent: "train FOLLOWER:OUI:FR:Line::AB", From: at(t, "08:30"), Until: at(t, "08:33")}
+	records := filter.Apply(sim.Messages())
+	if len(records) == 0 {
+		t.Fatal("no message recorded")
+	}
+
+	for name, got := range map[string]string{
+		"messages.puml": messaging.PlantUML(records),
+		"messages.mmd":  messaging.Mermaid(records),
+	} {
+		golden := filepath.Join("testdata", name)
+		if *update {
+			if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("reading golden file (run with -update to create it): %v", err)
+		}
+		if !bytes.Equal([]byte(got), want) {
+			t.Errorf("diagram differs from %s (run with -update to accept it):\n%s", golden, got)
+		}
+	}
+}
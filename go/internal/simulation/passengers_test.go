@@ -0,0 +1,49 @@
+package simulation_test
+
+import (
+	"testing"
+
+	"ai30-project/internal/constants"
+	"ai30-project/internal/passengers"
+	"ai30-project/internal/simulation"
+)
+
+func TestSetCapacities(t *testing.T) {
+	stationList, segmentList, paths := network()
+	timetable := throughTrains(t)
+	sim, err := simulation.NewScenarioSimulation(simulation.Scenario{Trains: timetable, Stations: stationList, Segments: segmentList, Paths: paths}, "eco", "no_sort")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Trains carry the default rolling stock until told otherwise
+	for _, tr := range timetable {
+		if got, want := tr.Capacity(), constants.DefaultSeatCapacity+constants.DefaultStandingCapacity; got != want {
+			t.Errorf("train %s carries %d passengers, want %d", tr.ID(), got, want)
+		}
+	}
+
+	unknown := passengers.DefaultCapacities()
+	unknown.Trains["GHOST:OUI:FR:Line::AC"] = passengers.Capacity{Seats: 300}
+	if err := sim.SetCapacities(unknown); err == nil {
+		t.Error("capacity of an unknown train accepted")
+	}
+
+	capacities := &passengers.Capacities{
+		Default: passengers.Capacity{Seats: 400, Standing: 50},
+		Trains:  map[string]passengers.Capacity{"MIDDAY:OUI:FR:Line::AC": {Seats: 556, Standing: 80}},
+	}
+	if err := sim.SetCapacities(capacities); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{
+		"MORNING:OUI:FR:Line::AC": 450,
+		"MIDDAY:OUI:FR:Line::AC":  636,
+		"EVENING:OGO:FR:Line::CD": 450,
+	}
+	for _, tr := range timetable {
+		if got := tr.Capacity(); got != want[tr.ID()] {
+			t.Errorf("train %s carries %d passengers, want %d", tr.ID(), got, want[tr.ID()])
+		}
+	}
+}
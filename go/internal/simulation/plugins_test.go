@@ -0,0 +1,75 @@
+package simulation_test
+
+import (
+	"slices"
+	"testing"
+
+	"ai30-project/internal/plugins"
+	"ai30-project/internal/simulation"
+	"ai30-project/internal/stations"
+	"ai30-project/internal/trains"
+)
+
+func TestPluginsRejectInvalidSetups(t *testing.T) {
+	stationList, segmentList, paths := network()
+	scenario := simulation.Scenario{Trains: followingTrains(t), Stations: stationList, Segments: segmentList, Paths: paths}
+
+	if _, err := simulation.NewScenarioSimulation(scenario, "reckless", "no_sort"); err == nil {
+		t.Error("unknown driver accepted")
+	}
+	if _, err := simulation.NewScenarioSimulation(scenario, "eco", "random"); err == nil {
+		t.Error("unknown station strategy accepted")
+	}
+
+	invalid := []map[string]any{
+		{"thresholdMin": -5.0},
+		{"thresholdMin": "soon"},
+		{"threshold": 10.0},
+	}
+	for _, params := range invalid {
+		if _, err := stations.NewStationStrategy("delay_asc_with_threshold", params); err == nil {
+			t.Errorf("parameters %v accepted", params)
+		}
+	}
+
+	// Scripts are checked before the run
+	scripts := []string{
+		"",
+		"delay >",
+		"speed",
+		`category + 1`,
+		`delay > 5 ? "late" : 0`,
+		"round(delay)",
+		`passengers, category == 'OUI'`,
+	}
+	for _, script := range scripts {
+		_, err := stations.NewStationStrategy("script", map[string]any{"expression": script})
+		if valid := script == `passengers, category == 'OUI'`; valid != (err == nil) {
+			t.Errorf("script %q: error %v", script, err)
+		}
+	}
+
+	// Parameters may come as text from the command line
+	strategy, err := stations.NewStationStrategy("delay_asc_with_threshold", map[string]any{"thresholdMin": "10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strategy.(stations.DelayAscWithThreshold).Threshold.Minutes(); got != 10 {
+		t.Errorf("threshold %v min, want 10", got)
+	}
+
+	for _, info := range trains.Drivers.List() {
+		required := slices.ContainsFunc(info.Params, func(param plugins.Param) bool { return param.Required })
+		if _, err := trains.NewDriverBehavior(info.Name, nil); (err == nil) == required {
+			t.Errorf("driver %s with its defaults: error %v", info.Name, err)
+		}
+	}
+
+	// The file driver is set up by name like the others
+	if _, err := simulation.NewScenarioSimulationWithParams(scenario, "file", map[string]any{"path": "testdata/missing.json"}, "no_sort", nil); err == nil {
+		t.Error("missing driver file accepted")
+	}
+	if _, err := simulation.NewScenarioSimulationWithParams(scenario, "file", map[string]any{"path": "../../examples/driver.json"}, "no_sort", nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,210 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ai30-project/internal/circulation"
+	"ai30-project/internal/connections"
+	"ai30-project/internal/crew"
+	"ai30-project/internal/dispatcher"
+	"ai30-project/internal/energy"
+	"ai30-project/internal/passengers"
+	"ai30-project/internal/power"
+	"ai30-project/internal/propagation"
+	"ai30-project/internal/stations"
+)
+
+// Report gathers the KPIs of the run so far.
+type Report struct {
+	holds       map[string]time.Duration // reason -> time trains were held at stations
+	diversions  diversionReport
+	energy      energy.Report
+	passengers  *passengers.Report
+	connections *connections.Report
+	circulation *circulation.Report
+	crew        *crew.Report
+	dispatcher  *dispatcher.Report
+	power       *power.Report
+	propagation *propagation.Totals
+	invariants  *invariantChecks
+	deadlocks   *deadlockDetection
+	crashes     []Crash
+}
+
+func (s *Simulation) Report() Report {
+	if s.finalState != nil {
+		return s.finalState.report
+	}
+
+	report := Report{
+		holds:      make(map[string]time.Duration),
+		energy:     energy.NewReport(),
+		invariants: s.invariants,
+		deadlocks:  s.deadlocks,
+		crashes:    s.supervisor.crashes,
+	}
+
+	for _, id := range sortedKeys(s.trains) {
+		train := s.trains[id]
+		report.energy.Add(id, s.driverBehavior, train.Energy())
+
+		for reason, held := range train.Holds() {
+			report.holds[reason] += held
+		}
+
+		if count, added := train.Diversions(); count > 0 {
+			report.diversions.trains++
+			report.diversions.count += count
+			report.diversions.addedRunningTime += added
+		}
+	}
+
+	if s.demand != nil {
+		var all []*passengers.Passenger
+		for _, id := range sortedKeys(s.trains) {
+			all = append(all, s.trains[id].Passengers()...)
+		}
+		all = append(all, s.waitingPassengers()...)
+
+		passengersReport := passengers.NewReport(s.demand.CountUntil(s.currentTime), all)
+		report.passengers = &passengersReport
+	}
+
+	if s.connectionService != nil && s.isStarted {
+		responseCh := make(chan connections.Report, 1)
+		connectionsReport, err := request[connections.ConnectionMessage](s, "connections", s.connectionService.Inbox(),
+			connections.ReportRequest{ResponseCh: responseCh}, responseCh)
+		if err != nil {
+			fmt.Printf("[Simulation] ERROR: Reporting %v\n", err)
+		} else {
+			report.connections = &connectionsReport
+		}
+	}
+
+	if s.circulation != nil && s.isStarted {
+		responseCh := make(chan circulation.Report, 1)
+		circulationReport, err := request[circulation.CirculationMessage](s, "circulation", s.circulation.Inbox(),
+			circulation.ReportRequest{ResponseCh: responseCh}, responseCh)
+		if err != nil {
+			fmt.Printf("[Simulation] ERROR: Reporting %v\n", err)
+		} else {
+			report.circulation = &circulationReport
+		}
+	}
+
+	if s.crewService != nil && s.isStarted {
+		responseCh := make(chan crew.Report, 1)
+		crewReport, err := request[crew.CrewMessage](s, "crew", s.crewService.Inbox(),
+			crew.ReportRequest{ResponseCh: responseCh}, responseCh)
+		if err != nil {
+			fmt.Printf("[Simulation] ERROR: Reporting %v\n", err)
+		} else {
+			report.crew = &crewReport
+		}
+	}
+
+	if s.dispatcher != nil && s.isStarted {
+		responseCh := make(chan dispatcher.Report, 1)
+		dispatcherReport, err := request[dispatcher.DispatcherMessage](s, "dispatcher", s.dispatcher.Inbox(),
+			dispatcher.ReportRequest{ResponseCh: responseCh}, responseCh)
+		if err != nil {
+			fmt.Printf("[Simulation] ERROR: Reporting %v\n", err)
+		} else {
+			report.dispatcher = &dispatcherReport
+		}
+	}
+
+	if s.powerSupply != nil && s.isStarted {
+		responseCh := make(chan power.Report, 1)
+		powerReport, err := request[power.PowerMessage](s, "power", s.powerSupply.Inbox(),
+			power.ReportRequest{ResponseCh: responseCh}, responseCh)
+		if err != nil {
+			fmt.Printf("[Simulation] ERROR: Reporting %v\n", err)
+		} else {
+			report.power = &powerReport
+		}
+	}
+
+	if propagationReport, ok := s.DelayPropagation(); ok {
+		totals := propagationReport.Totals()
+		report.propagation = &totals
+	}
+
+	return report
+}
+
+// waitingPassengers asks every station for the passengers waiting there. The
+// stations take in passengers dropped off at any time, so they answer once
+// they have handled the drop-offs sent before.
+func (s *Simulation) waitingPassengers() []*passengers.Passenger {
+	if !s.isStarted {
+		return nil
+	}
+
+	var all []*passengers.Passenger
+	for _, id := range sortedKeys(s.stations) {
+		responseCh := make(chan []*passengers.Passenger, 1)
+		waiting, err := request[stations.StationMessage](s, "station "+id, s.stations[id].Inbox(),
+			stations.PassengersRequest{ResponseCh: responseCh}, responseCh)
+		if err != nil {
+			fmt.Printf("[Simulation] ERROR: Reporting %v\n", err)
+			continue
+		}
+		all = append(all, waiting...)
+	}
+	return all
+}
+
+// DelayPropagation returns the traced waits of every train, attributed to
+// primary and knock-on causes, if tracing is enabled.
+func (s *Simulation) DelayPropagation() (propagation.Report, bool) {
+	if s.finalState != nil && s.finalState.trace != nil {
+		return *s.finalState.trace, true
+	}
+	if s.tracer == nil || !s.isStarted || s.isStopped {
+		return propagation.Report{}, false
+	}
+
+	responseCh := make(chan propagation.Report, 1)
+	trace, err := request[propagation.TracerMessage](s, "tracer", s.tracer.Inbox(),
+		propagation.ReportRequest{ResponseCh: responseCh}, responseCh)
+	if err != nil {
+		fmt.Printf("[Simulation] ERROR: Reporting %v\n", err)
+		return propagation.Report{}, false
+	}
+	return trace, true
+}
+
+func (r Report) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"holds":       r.holds,
+		"diversions":  r.diversions,
+		"energy":      r.energy,
+		"passengers":  r.passengers,
+		"connections": r.connections,
+		"circulation": r.circulation,
+		"crew":        r.crew,
+		"dispatcher":  r.dispatcher,
+		"power":       r.power,
+		"propagation": r.propagation,
+		"invariants":  r.invariants,
+		"deadlocks":   r.deadlocks,
+		"crashes":     r.crashes,
+	})
+}
+
+type diversionReport struct {
+	trains           int
+	count            int
+	addedRunningTime time.Duration
+}
+
+func (r diversionReport) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"trains":           r.trains,
+		"count":            r.count,
+		"addedRunningTime": r.addedRunningTime,
+	})
+}
@@ -0,0 +1,42 @@
+package simulation_test
+
+import (
+	"testing"
+	"time"
+
+	"ai30-project/internal/circulation"
+	"ai30-project/internal/simulation"
+	"ai30-project/internal/trains"
+)
+
+func TestRotationsRejectTripsOutsideTheTimetable(t *testing.T) {
+	stationList, segmentList, paths := network()
+	timetable := []*trains.Train{
+		train(t, "OUT:OUI:FR:Line::AB", stop{"A", "08:00", "08:00"}, stop{"B", "08:20", "08:20"}),
+		train(t, "BACK:OUI:FR:Line::BA", stop{"B", "09:00", "09:00"}, stop{"A", "09:20", "09:20"}),
+		train(t, "EARLY:OUI:FR:Line::BC", stop{"B", "08:10", "08:10"}, stop{"C", "08:30", "08:30"}),
+	}
+	sim, err := simulation.NewScenarioSimulation(simulation.Scenario{Trains: timetable, Stations: stationList, Segments: segmentList, Paths: paths}, "eco", "no_sort")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotation := func(trips ...string) []circulation.Rotation {
+		return []circulation.Rotation{{ID: "TGV-01", Trips: trips, MinTurnaround: 10 * time.Minute}}
+	}
+	invalid := map[string][]circulation.Rotation{
+		"unknown train":       rotation("OUT:OUI:FR:Line::AB", "GHOST:OUI:FR:Line::BA"),
+		"unknown first train": rotation("GHOST:OUI:FR:Line::AB", "BACK:OUI:FR:Line::BA"),
+		"elsewhere":           rotation("BACK:OUI:FR:Line::BA", "EARLY:OUI:FR:Line::BC"),
+		"before it arrives":   rotation("OUT:OUI:FR:Line::AB", "EARLY:OUI:FR:Line::BC"),
+	}
+	for name, rotations := range invalid {
+		if err := sim.SetRotations(rotations); err == nil {
+			t.Errorf("%s: rotation %v accepted", name, rotations[0].Trips)
+		}
+	}
+
+	if err := sim.SetRotations(rotation("OUT:OUI:FR:Line::AB", "BACK:OUI:FR:Line::BA")); err != nil {
+		t.Errorf("valid rotation rejected: %v", err)
+	}
+}
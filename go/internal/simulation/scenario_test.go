@@ -0,0 +1,354 @@
+package simulation_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ai30-project/internal/clock"
+	"ai30-project/internal/constants"
+	"ai30-project/internal/disruptions"
+	"ai30-project/internal/events"
+	"ai30-project/internal/navigation"
+	"ai30-project/internal/passengers"
+	"ai30-project/internal/power"
+	"ai30-project/internal/segments"
+	"ai30-project/internal/simulation"
+	"ai30-project/internal/stations"
+	"ai30-project/internal/trains"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the current results")
+
+// seed makes the train events of every scenario reproducible.
+const seed = 42
+
+// maxTime stops a scenario that does not finish on its own.
+const maxTime = 24 * time.Hour
+
+// noEvents keeps delays and cancellations out of the scenarios that do not
+// test them.
+var noEvents = &events.ModelSet{}
+
+// The test network is a small junction: A and D both lead to B, which leads
+// to C. Every link is double track, 30 km long.
+//
+//	A ---\
+//	      B --- C
+//	D ---/
+func network() ([]*stations.Station, []*segments.Segment, navigation.Paths) {
+	stationList := []*stations.Station{
+		stations.NewStation("A", "Station A", 2),
+		stations.NewStation("B", "Station B", 2),
+		stations.NewStation("C", "Station C", 2),
+		stations.NewStation("D", "Station D", 2),
+	}
+
+	var segmentList []*segments.Segment
+	paths := navigation.Paths{}
+	link := func(from, to string) {
+		id := from + "-" + to
+		segmentList = append(segmentList, segments.NewSegment(id, from, to, 30000, 160*constants.KmHToMPerMin))
+		if paths[from] == nil {
+			paths[from] = map[string]string{}
+		}
+		paths[from][to] = id
+	}
+	link("A", "B")
+	link("B", "A")
+	link("B", "C")
+	link("C", "B")
+	link("D", "B")
+	link("B", "D")
+
+	// Through routes across the junction
+	paths["A"]["C"] = "A-B"
+	paths["D"]["C"] = "D-B"
+	paths["C"]["A"] = "C-B"
+	paths["C"]["D"] = "C-B"
+
+	return stationList, segmentList, paths
+}
+
+func at(t testing.TB, value string) time.Duration {
+	t.Helper()
+	d, err := clock.Parse(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// stop is a scheduled call: station, arrival and departure as "HH:MM".
+type stop struct {
+	station, arrival, departure string
+}
+
+func train(t testing.TB, id string, stops ...stop) *trains.Train {
+	t.Helper()
+	trainStops := make([]*trains.TrainStop, len(stops))
+	for i, s := range stops {
+		trainStops[i] = trains.NewTrainStop(s.station, at(t, s.arrival), at(t, s.departure))
+	}
+	return trains.NewTrain(id, trainStops)
+}
+
+type scenarioCase struct {
+	name     string
+	strategy string
+	driver   string // eco when empty
+	models   *events.ModelSet
+	trains   func(t testing.TB) []*trains.Train
+	setup    func(t testing.TB, sim *simulation.Simulation)
+
+	scheduler string // concurrent when empty
+}
+
+// schedulers must all give the results of the golden files: the scenarios
+// leave no decision to the order in which trains act.
+var schedulers = []string{simulation.SchedulerConcurrent, simulation.SchedulerSequential, simulation.SchedulerShuffled}
+
+func followingTrains(t testing.TB) []*trains.Train {
+	return []*trains.Train{
+		train(t, "LEADER:OUI:FR:Line::AB", stop{"A", "08:00", "08:00"}, stop{"B", "08:40", "08:40"}),
+		// Timed to run faster and catch up with the leader before B
+		train(t, "FOLLOWER:OUI:FR:Line::AB", stop{"A", "08:10", "08:10"}, stop{"B", "08:35", "08:35"}),
+	}
+}
+
+func stationContention(t testing.TB) []*trains.Train {
+	return []*trains.Train{
+		// Scheduled faster than it can run, so it reaches B late
+		train(t, "LATE:OUI:FR:Line::AC", stop{"A", "08:00", "08:00"}, stop{"B", "08:12", "09:30"}, stop{"C", "10:00", "10:00"}),
+		train(t, "ONTIME:OGO:FR:Line::DC", stop{"D", "07:55", "07:55"}, stop{"B", "08:30", "09:20"}, stop{"C", "09:50", "09:50"}),
+	}
+}
+
+// closeStation keeps B closed until 08:40 so that both trains queue for it,
+// then opens a single track until 09:10, when the second train gets in.
+func closeStation(t testing.TB, sim *simulation.Simulation) {
+	err := sim.AddDisruption(
+		disruptions.Disruption{Kind: disruptions.StationCapacity, Target: "B", Start: at(t, "08:00"), End: at(t, "08:40"), Capacity: 0},
+		disruptions.Disruption{Kind: disruptions.StationCapacity, Target: "B", Start: at(t, "08:40"), End: at(t, "09:10"), Capacity: 1},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func throughTrains(t testing.TB) []*trains.Train {
+	return []*trains.Train{
+		train(t, "MORNING:OUI:FR:Line::AC", stop{"A", "08:00", "08:00"}, stop{"B", "08:30", "08:35"}, stop{"C", "09:05", "09:05"}),
+		train(t, "MIDDAY:OUI:FR:Line::AC", stop{"A", "11:00", "11:00"}, stop{"B", "11:30", "11:35"}, stop{"C", "12:05", "12:05"}),
+		train(t, "EVENING:OGO:FR:Line::CD", stop{"C", "17:00", "17:00"}, stop{"B", "17:30", "17:35"}, stop{"D", "18:05", "18:05"}),
+	}
+}
+
+var scenarioCases = []scenarioCase{
+	{name: "following_trains", strategy: "no_sort", models: noEvents, trains: followingTrains},
+	{name: "station_contention_no_sort", strategy: "no_sort", models: noEvents, trains: stationContention, setup: closeStation},
+	{name: "station_contention_entry_time_asc", strategy: "entry_time_asc", models: noEvents, trains: stationContention, setup: closeStation},
+	{name: "station_contention_delay_asc", strategy: "delay_asc", models: noEvents, trains: stationContention, setup: closeStation},
+	{name: "station_contention_delay_asc_with_threshold", strategy: "delay_asc_with_threshold", models: noEvents, trains: stationContention, setup: closeStation},
+	{
+		name:     "cancellation",
+		strategy: "no_sort",
+		models:   &events.ModelSet{Default: events.Model{ProportionCancellation: 1}},
+		trains:   throughTrains,
+	},
+	{name: "delay_events", strategy: "no_sort", models: delayEvents, trains: throughTrains},
+	{name: "adaptive_driver", strategy: "no_sort", driver: "adaptive", models: delayEvents, trains: throughTrains},
+	{name: "das_driver", strategy: "no_sort", driver: "das", models: delayEvents, trains: throughTrains},
+	{name: "power_supply", strategy: "no_sort", driver: "crazy", models: noEvents, trains: followingTrains, setup: weakSubstation},
+	{name: "station_contention_script", strategy: "script", models: noEvents, trains: stationContention, setup: scriptByCategory},
+	{name: "passengers", strategy: "no_sort", models: noEvents, trains: throughTrains, setup: crowdedMorning},
+}
+
+// crowdedMorning brings 350 passengers to A before the morning train, which
+// only has room for 250 of them: the others wait for the midday train.
+func crowdedMorning(t testing.TB, sim *simulation.Simulation) {
+	sim.SetDemand(&passengers.Demand{Windows: []passengers.Window{
+		{Start: at(t, "07:00"), End: at(t, "08:00"), Flows: map[string]map[string]int{"A": {"B": 100, "C": 250}}},
+	}})
+	capacities := passengers.DefaultCapacities()
+	capacities.Trains["MORNING:OUI:FR:Line::AC"] = passengers.Capacity{Seats: 200, Standing: 50}
+	if err := sim.SetCapacities(capacities); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// scriptByCategory closes B like closeStation and admits OGO trains first,
+// then the least delayed.
+func scriptByCategory(t testing.TB, sim *simulation.Simulation) {
+	closeStation(t, sim)
+	strategy, err := stations.NewStationStrategy("script", map[string]any{"expression": `category == "OGO" ? 0 : 1, delay`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.SetStationStrategy("script", strategy)
+}
+
+// weakSubstation feeds A-B with too little power for both trains to
+// accelerate at once.
+func weakSubstation(t testing.TB, sim *simulation.Simulation) {
+	sim.SetPowerSupply(&power.Network{Substations: []power.Substation{{
+		ID:       "SS-AB",
+		MaxPower: 1.5e6,
+		Sections: []power.Section{{ID: "AB", Segments: []string{"A-B"}}},
+	}}})
+}
+
+// delayEvents delays about half of the trips, for every cause.
+var delayEvents = &events.ModelSet{Default: events.Model{
+	ProportionDelay: 0.5,
+	MuDelayed:       2.5,
+	StdDelayed:      0.3,
+	CauseProbabilities: map[events.DelayCause]float64{
+		events.DelayCauseExternal:       1,
+		events.DelayCauseInfrastructure: 1,
+		events.DelayCauseRollingStock:   1,
+		events.DelayCauseStation:        1,
+	},
+}}
+
+type stopResult struct {
+	Station    string `json:"station"`
+	Arrival    string `json:"arrival"`
+	ArrivedAt  string `json:"arrivedAt,omitempty"`
+	Departure  string `json:"departure"`
+	DepartedAt string `json:"departedAt,omitempty"`
+	Skipped    bool   `json:"skipped,omitempty"`
+}
+
+type trainResult struct {
+	ID     string          `json:"id"`
+	Events events.Timeline `json:"events"`
+	Stops  []stopResult    `json:"stops"`
+}
+
+type scenarioResult struct {
+	EndTime string            `json:"endTime"`
+	Failure string            `json:"failure,omitempty"`
+	Trains  []trainResult     `json:"trains"`
+	Report  simulation.Report `json:"report"`
+}
+
+func newSimulation(t testing.TB, c scenarioCase) (*simulation.Simulation, simulation.Scenario) {
+	stationList, segmentList, paths := network()
+	scenario := simulation.Scenario{
+		Trains:   c.trains(t),
+		Stations: stationList,
+		Segments: segmentList,
+		Paths:    paths,
+	}
+
+	driver := "eco"
+	if c.driver != "" {
+		driver = c.driver
+	}
+	sim, err := simulation.NewScenarioSimulation(scenario, driver, c.strategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.SetEventModels(c.models)
+	sim.SetSeed(seed)
+	sim.SetMaxTime(maxTime)
+	sim.SetInvariantChecks(true)
+	if c.scheduler != "" {
+		if err := sim.SetScheduler(c.scheduler); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.setup != nil {
+		c.setup(t, sim)
+	}
+	return sim, scenario
+}
+
+func run(t testing.TB, c scenarioCase) scenarioResult {
+	sim, scenario := newSimulation(t, c)
+
+	sim.Start()
+	for !sim.IsFinished() {
+		sim.Tick()
+	}
+	sim.Stop()
+
+	result := scenarioResult{EndTime: clock.Format(sim.CurrentTime()), Report: sim.Report()}
+	if err := sim.Failure(); err != nil {
+		result.Failure = err.Error()
+	}
+
+	for _, tr := range scenario.Trains {
+		tResult := trainResult{ID: tr.ID(), Events: tr.Events()}
+		for _, s := range tr.Stops() {
+			sResult := stopResult{
+				Station:   s.StationID(),
+				Arrival:   clock.Format(s.Arrival()),
+				Departure: clock.Format(s.Departure()),
+				Skipped:   s.Skipped(),
+			}
+			if arrivedAt, ok := s.ArrivedAt(); ok {
+				sResult.ArrivedAt = clock.Format(arrivedAt)
+			}
+			if departedAt, ok := s.DepartedAt(); ok {
+				sResult.DepartedAt = clock.Format(departedAt)
+			}
+			tResult.Stops = append(tResult.Stops, sResult)
+		}
+		result.Trains = append(result.Trains, tResult)
+	}
+
+	return result
+}
+
+// TestScenarios runs each scenario and compares its stop timestamps and KPIs
+// with testdata/<scenario>.golden.json. Run with -update to accept changes.
+func TestScenarios(t *testing.T) {
+	for _, c := range scenarioCases {
+		for _, scheduler := range schedulers {
+			c.scheduler = scheduler
+			t.Run(c.name+"/"+scheduler, func(t *testing.T) {
+				got, err := json.MarshalIndent(run(t, c), "", "  ")
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, '\n')
+
+				golden := filepath.Join("testdata", c.name+".golden.json")
+				if *update {
+					if err := os.WriteFile(golden, got, 0o644); err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatalf("reading golden file (run with -update to create it): %v", err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("result differs from %s (run with -update to accept it):\n%s", golden, got)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkSchedulers runs the station contention scenario under each
+// scheduler.
+func BenchmarkSchedulers(b *testing.B) {
+	c := scenarioCases[1]
+	for _, scheduler := range schedulers {
+		c.scheduler = scheduler
+		b.Run(scheduler, func(b *testing.B) {
+			for b.Loop() {
+				run(b, c)
+			}
+		})
+	}
+}
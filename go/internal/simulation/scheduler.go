@@ -0,0 +1,233 @@
+package simulation
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"ai30-project/internal/trains"
+)
+
+// Schedulers
+const (
+	SchedulerConcurrent = "concurrent" // one goroutine per agent
+	SchedulerSequential = "sequential" // one goroutine, trains stepped in ID order
+	SchedulerShuffled   = "shuffled"   // one goroutine, trains stepped in a seeded random order
+)
+
+// sequentialScheduler steps every agent on the goroutine calling Tick. Trains
+// take their phases one after another, and the agents handle their messages
+// when a train waits for an answer or at the end of a phase. Runs are then
+// reproducible, and avoid the cost of goroutine switches on small networks.
+type sequentialScheduler struct {
+	shuffle bool
+	rng     *rand.Rand
+
+	trains  []*trains.Train // still running, in ID order
+	agents  []*inPlaceAgent
+	inboxes map[any]*inPlaceAgent
+}
+
+// inPlaceAgent is an agent handling its messages when delivered rather than
+// on its own goroutine.
+type inPlaceAgent struct {
+	name  string
+	drain func() int
+	down  bool
+}
+
+// SetScheduler chooses how agents are run: each on its own goroutine, or all
+// on the goroutine calling Tick, with trains in ID order or in an order drawn
+// each tick from the seed. It must be called before Start.
+func (s *Simulation) SetScheduler(name string) error {
+	switch name {
+	case SchedulerConcurrent:
+		s.sequential = nil
+	case SchedulerSequential, SchedulerShuffled:
+		s.sequential = &sequentialScheduler{
+			shuffle: name == SchedulerShuffled,
+			inboxes: make(map[any]*inPlaceAgent),
+		}
+	default:
+		return fmt.Errorf("unknown scheduler %q", name)
+	}
+	s.scheduler = name
+	return nil
+}
+
+// startSequential registers every agent with the sequential scheduler instead
+// of launching its goroutine.
+func (s *Simulation) startSequential() {
+	sq := s.sequential
+	sq.rng = rand.New(rand.NewSource(s.seed))
+
+	for _, id := range sortedKeys(s.trains) {
+		train := s.trains[id]
+		train.SetDeliver(s.deliver)
+		sq.trains = append(sq.trains, train)
+	}
+
+	for _, id := range sortedKeys(s.stations) {
+		s.register("station "+id, s.stationInboxes[id], s.stations[id].Drain)
+	}
+
+	for _, id := range sortedKeys(s.segments) {
+		s.register("segment "+id, s.segmentInboxes[id], s.segments[id].Drain)
+	}
+
+	s.register("navigation", s.navigationService.Inbox(), s.navigationService.Drain)
+
+	if s.connectionService != nil {
+		s.register("connections", s.connectionService.Inbox(), s.connectionService.Drain)
+	}
+
+	if s.circulation != nil {
+		s.register("circulation", s.circulation.Inbox(), s.circulation.Drain)
+	}
+
+	if s.crewService != nil {
+		s.register("crew", s.crewService.Inbox(), s.crewService.Drain)
+	}
+
+	if s.dispatcher != nil {
+		s.dispatcher.SetDeliver(s.deliver)
+		s.register("dispatcher", s.dispatcher.Inbox(), s.dispatcher.Drain)
+	}
+
+	if s.powerSupply != nil {
+		s.register("power", s.powerSupply.Inbox(), s.powerSupply.Drain)
+	}
+
+	if s.tracer != nil {
+		s.register("tracer", s.tracer.Inbox(), s.tracer.Drain)
+	}
+}
+
+func (s *Simulation) register(name string, inbox any, drain func() int) {
+	agent := &inPlaceAgent{name: name, drain: drain}
+	s.sequential.agents = append(s.sequential.agents, agent)
+	s.sequential.inboxes[inbox] = agent
+}
+
+// deliver has the agent owning inbox handle its waiting messages.
+func (s *Simulation) deliver(inbox any) {
+	if agent, ok := s.sequential.inboxes[inbox]; ok {
+		s.drainAgent(agent)
+	}
+}
+
+// deliverAll handles every waiting message, including those sent by the
+// handlers themselves, so that the agents are settled between phases.
+func (s *Simulation) deliverAll() {
+	for handled := 1; handled > 0; {
+		handled = 0
+		for _, agent := range s.sequential.agents {
+			handled += s.drainAgent(agent)
+		}
+	}
+}
+
+// drainAgent handles the messages of agent under supervision. An agent is
+// restarted by handling its next message; one that stays down keeps its
+// messages, and their senders get no response.
+func (s *Simulation) drainAgent(agent *inPlaceAgent) (handled int) {
+	for !agent.down {
+		n, crashed := s.drainSafely(agent)
+		handled += n
+		if !crashed {
+			return handled
+		}
+	}
+	return handled
+}
+
+func (s *Simulation) drainSafely(agent *inPlaceAgent) (handled int, crashed bool) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			agent.down = !s.supervisor.crashed(agent.name, recovered)
+			crashed = true
+		}
+	}()
+	return agent.drain(), false
+}
+
+// stepTrains plays both phases of the tick for every running train and
+// returns how many are still running.
+func (s *Simulation) stepTrains() int {
+	sq := s.sequential
+
+	order := sq.trains
+	if sq.shuffle {
+		order = make([]*trains.Train, len(sq.trains))
+		for i, j := range sq.rng.Perm(len(sq.trains)) {
+			order[i] = sq.trains[j]
+		}
+	}
+
+	// Phase 1: percept + deliberate
+	for _, train := range order {
+		train.Perceive(s.currentTime)
+	}
+	s.deliverAll()
+
+	// Phase 2: act
+	finished := make(map[*trains.Train]bool)
+	for _, train := range order {
+		if train.Act(s.currentTime) {
+			finished[train] = true
+		}
+	}
+	s.deliverAll()
+
+	running := sq.trains[:0]
+	for _, train := range sq.trains {
+		if !finished[train] {
+			running = append(running, train)
+		}
+	}
+	sq.trains = running
+	return len(running)
+}
+
+// tickTrains plays both phases of the tick through the train goroutines and
+// returns how many are still running.
+func (s *Simulation) tickTrains() int {
+	// Phase 1: percept + deliberate
+	for range s.activeTrains {
+		s.tickChan <- s.currentTime
+	}
+	for range s.activeTrains {
+		<-s.doneChan
+	}
+
+	// Phase 2: act
+	for range s.activeTrains {
+		s.tickChan <- s.currentTime
+	}
+
+	stillActive := 0
+	for range s.activeTrains {
+		if !<-s.doneChan {
+			stillActive++
+		}
+	}
+	return stillActive
+}
+
+// noWait is a deadline already reached: agents stepped in place have answered
+// by the time it is checked, or never will.
+func noWait() <-chan time.Time {
+	expired := make(chan time.Time)
+	close(expired)
+	return expired
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,197 @@
+package simulation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ai30-project/internal/messaging"
+)
+
+// Supervision policies for crashed agents
+const (
+	SupervisionRestart = "restart" // restart the agent on its inbox, up to maxRestarts times
+	SupervisionFail    = "fail"    // abort the run
+)
+
+// maxRestarts is how many times an agent may be restarted before it stays
+// down and the run fails.
+const maxRestarts = 3
+
+// Crash is a panic of an agent. A crashed train is taken out of service
+// rather than restarted.
+type Crash struct {
+	Agent     string
+	Time      time.Duration
+	Panic     string
+	Restarted bool
+}
+
+func (c Crash) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"agent":     c.Agent,
+		"time":      c.Time,
+		"panic":     c.Panic,
+		"restarted": c.Restarted,
+	})
+}
+
+type supervisor struct {
+	policy string
+
+	mu       sync.Mutex
+	restarts map[string]int
+	pending  []Crash // crashed during the current tick, stamped at its end
+
+	crashes       []Crash
+	crashedTrains map[string]bool
+}
+
+func newSupervisor() *supervisor {
+	return &supervisor{
+		policy:        SupervisionRestart,
+		restarts:      make(map[string]int),
+		crashedTrains: make(map[string]bool),
+	}
+}
+
+// crashed records a panic of agent and reports whether to restart it.
+func (sv *supervisor) crashed(agent string, recovered any) bool {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+
+	restart := sv.policy == SupervisionRestart && sv.restarts[agent] < maxRestarts
+	if restart {
+		sv.restarts[agent]++
+	}
+	sv.pending = append(sv.pending, Crash{Agent: agent, Panic: fmt.Sprint(recovered), Restarted: restart})
+
+	fmt.Printf("[Supervisor] Agent %s crashed (restarted: %v): %v\n%s", agent, restart, recovered, debug.Stack())
+	return restart
+}
+
+// SetSupervision chooses what happens when an agent panics: restart it on its
+// inbox, or abort the run. Agents restart by default. It must be called
+// before Start.
+func (s *Simulation) SetSupervision(policy string) error {
+	switch policy {
+	case SupervisionRestart, SupervisionFail:
+	default:
+		return fmt.Errorf("unknown supervision policy %q", policy)
+	}
+	s.supervisor.policy = policy
+	return nil
+}
+
+// SetRequestTimeout bounds how long trains, the dispatcher and the simulation
+// wait for an agent to answer. It must be called before Start.
+func (s *Simulation) SetRequestTimeout(timeout time.Duration) {
+	s.requestTimeout = timeout
+	for _, train := range s.trains {
+		train.SetRequestTimeout(timeout)
+	}
+	if s.dispatcher != nil {
+		s.dispatcher.SetRequestTimeout(timeout)
+	}
+}
+
+// Crashes returns every agent crash so far.
+func (s *Simulation) Crashes() []Crash {
+	return s.supervisor.crashes
+}
+
+// spawn runs an agent under supervision until ctx is cancelled.
+func (s *Simulation) spawn(agent string, run func(context.Context)) {
+	s.agents.Add(1)
+	go func() {
+		defer s.agents.Done()
+		for s.runAgent(agent, run) {
+		}
+	}()
+}
+
+// runAgent runs the agent until it returns, and reports whether it crashed
+// and must be restarted. The message it was handling is lost: its sender
+// gets a timeout.
+func (s *Simulation) runAgent(agent string, run func(context.Context)) (restart bool) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			restart = s.supervisor.crashed(agent, recovered) && s.ctx.Err() == nil
+		}
+	}()
+	run(s.ctx)
+	return false
+}
+
+// checkCrashes stamps the crashes of the tick, including trains taken out of
+// service, and aborts the run if the policy requires it or an agent is down.
+func (s *Simulation) checkCrashes() {
+	sv := s.supervisor
+
+	sv.mu.Lock()
+	crashes := sv.pending
+	sv.pending = nil
+	sv.mu.Unlock()
+
+	var ids []string
+	for id, train := range s.trains {
+		if train.Crash() != nil && !sv.crashedTrains[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		sv.crashedTrains[id] = true
+		crashes = append(crashes, Crash{Agent: "train " + id, Panic: s.trains[id].Crash().Error()})
+	}
+
+	for i := range crashes {
+		crashes[i].Time = s.currentTime
+	}
+	sv.crashes = append(sv.crashes, crashes...)
+
+	for _, crash := range crashes {
+		isTrain := strings.HasPrefix(crash.Agent, "train ")
+		if sv.policy == SupervisionFail || (!crash.Restarted && !isTrain) {
+			s.failure = fmt.Errorf("agent %s crashed at %v: %s", crash.Agent, crash.Time, crash.Panic)
+			fmt.Printf("[Simulation] Aborted: %v\n", s.failure)
+			return
+		}
+	}
+}
+
+// request sends a request to an agent of the simulation and waits for its
+// response, within the request timeout.
+func request[M, R any](s *Simulation, agent string, inbox chan M, msg M, responseCh chan R) (R, error) {
+	var (
+		response R
+		err      error
+	)
+	if s.sequential != nil {
+		response, err = messaging.Call(agent, inbox, msg, responseCh, func() { s.deliver(inbox) })
+	} else {
+		response, err = messaging.Request(s.ctx, agent, inbox, msg, responseCh, s.requestTimeout)
+	}
+	s.tap.Request("simulation", agent, msg, response, err)
+	return response, err
+}
+
+// notify sends a notification to an agent of the simulation, giving up after
+// the request timeout if its inbox stays full.
+func notify[M any](s *Simulation, agent string, inbox chan M, msg M) {
+	var err error
+	if s.sequential != nil {
+		err = messaging.Post(agent, inbox, msg, func() { s.deliver(inbox) })
+	} else {
+		err = messaging.Notify(s.ctx, agent, inbox, msg, s.requestTimeout)
+	}
+	s.tap.Notification("simulation", agent, msg, err)
+	if err != nil {
+		fmt.Printf("[Simulation] ERROR: Notifying %v\n", err)
+	}
+}
@@ -1,7 +1,10 @@
 package stations
 
 import (
+	"ai30-project/internal/events"
+	"ai30-project/internal/passengers"
 	"fmt"
+	"sort"
 	"time"
 )
 
@@ -14,6 +17,7 @@ type DemandingEntry struct {
 	FromSegment string
 	Delay       time.Duration
 	RequestTime time.Duration
+	Passengers  int
 	ResponseCh  chan DemandingEntryResponse
 }
 
@@ -27,7 +31,13 @@ type DemandingEntryResponse struct {
 func (s *Station) handleDemandingEntry(req DemandingEntry) {
 	// At 90% the train notifies it's demanding entry.
 	// We register or update the demand in the slice and sort it.
-	s.addOrUpdateDemand(req.TrainID, req.Delay, req.RequestTime)
+	s.addOrUpdateDemand(demandInfo{
+		trainID:    req.TrainID,
+		delay:      req.Delay,
+		entryTime:  req.RequestTime,
+		category:   events.CategoryOf(req.TrainID),
+		passengers: req.Passengers,
+	})
 	s.sortDemands()
 
 	fmt.Printf("  [Station %s] Train %s registered/updated demanding entry at %v (demanding=%d)\n",
@@ -47,19 +57,26 @@ type EntryRequest struct {
 func (EntryRequest) isMessage() {}
 
 type EntryResponse struct {
-	Allowed bool
-	Error   error
+	Allowed         bool
+	BlockingTrainID string   // train occupying the track, or admitted first, when denied
+	Holders         []string // trains that must move before the train may enter, when denied
+	Error           error
 }
 
 func (s *Station) handleEntryRequest(req EntryRequest) {
 	// On 100% the train asks to actually enter the station.
 	// Allow only if the train is among the top-x demanding trains where
 	// x = remaining slots.
-	remaining := s.capacity - len(s.trainsInStation)
+	remaining := s.effectiveCapacity() - len(s.trainsInStation)
 	if remaining <= 0 {
 		fmt.Printf("  [Station %s] Train %s entry request DENIED (capacity full) at %v\n",
 			s.id, req.TrainID, req.EntryTime)
-		req.ResponseCh <- EntryResponse{Allowed: false, Error: nil}
+		occupants := make([]string, 0, len(s.trainsInStation))
+		for trainID := range s.trainsInStation {
+			occupants = append(occupants, trainID)
+		}
+		sort.Strings(occupants)
+		req.ResponseCh <- EntryResponse{Allowed: false, BlockingTrainID: s.longestStayingTrain(), Holders: occupants, Error: nil}
 		return
 	}
 
@@ -77,18 +94,39 @@ func (s *Station) handleEntryRequest(req EntryRequest) {
 		}
 	}
 
+	blockingTrainID := ""
+	var holders []string
+	if !allowed && len(s.trainsDemandingEntry) > 0 {
+		blockingTrainID = s.trainsDemandingEntry[0].trainID
+		for _, demand := range s.trainsDemandingEntry[:limit] {
+			holders = append(holders, demand.trainID)
+		}
+	}
+
 	if allowed {
 		// Admit the train
 		s.trainsInStation[req.TrainID] = &trainInfo{entryTime: req.EntryTime}
 		s.removeDemand(req.TrainID)
 		fmt.Printf("  [Station %s] Train %s entry request ALLOWED (capacity: %d/%d) at %v\n",
-			s.id, req.TrainID, len(s.trainsInStation), s.capacity, req.EntryTime)
+			s.id, req.TrainID, len(s.trainsInStation), s.effectiveCapacity(), req.EntryTime)
 	} else {
 		fmt.Printf("  [Station %s] Train %s entry request DENIED (not in top-%d) at %v\n",
 			s.id, req.TrainID, remaining, req.EntryTime)
 	}
 
-	req.ResponseCh <- EntryResponse{Allowed: allowed, Error: nil}
+	req.ResponseCh <- EntryResponse{Allowed: allowed, BlockingTrainID: blockingTrainID, Holders: holders, Error: nil}
+}
+
+// longestStayingTrain is the train that entered the station first.
+func (s *Station) longestStayingTrain() string {
+	longest := ""
+	for trainID, info := range s.trainsInStation {
+		if longest == "" || info.entryTime < s.trainsInStation[longest].entryTime ||
+			(info.entryTime == s.trainsInStation[longest].entryTime && trainID < longest) {
+			longest = trainID
+		}
+	}
+	return longest
 }
 
 type DepartureNotification struct {
@@ -97,10 +135,164 @@ type DepartureNotification struct {
 
 func (DepartureNotification) isMessage() {}
 
+// handleDeparture frees the track of the train. A train taken out of service
+// before reaching the station also withdraws its entry demand this way.
 func (s *Station) handleDeparture(notif DepartureNotification) {
+	s.removeDemand(notif.TrainID)
 	if _, exists := s.trainsInStation[notif.TrainID]; exists {
 		delete(s.trainsInStation, notif.TrainID)
 		fmt.Printf("  [Station %s] Train %s departed (capacity: %d/%d)\n",
-			s.id, notif.TrainID, len(s.trainsInStation), s.capacity)
+			s.id, notif.TrainID, len(s.trainsInStation), s.effectiveCapacity())
+	}
+}
+
+type BoardingRequest struct {
+	TrainID      string
+	Time         time.Duration
+	FreeCapacity int
+	Serves       map[string]time.Duration // destination station -> scheduled arrival
+	ResponseCh   chan BoardingResponse
+}
+
+func (BoardingRequest) isMessage() {}
+
+type BoardingResponse struct {
+	Passengers []*passengers.Passenger
+	Denied     int
+	Error      error
+}
+
+func (s *Station) handleBoarding(req BoardingRequest) {
+	if s.passengerSource != nil {
+		s.waitingPassengers = append(s.waitingPassengers, s.passengerSource.Release(req.Time)...)
+	}
+
+	// Passengers board in order of arrival on the platform until the train is
+	// full; the others served by this train are denied and keep waiting.
+	var boarded []*passengers.Passenger
+	remaining := s.waitingPassengers[:0]
+	denied := 0
+
+	for _, p := range s.waitingPassengers {
+		scheduledArrival, served := req.Serves[p.Destination]
+		if !served {
+			remaining = append(remaining, p)
+			continue
+		}
+
+		p.Offer(scheduledArrival)
+		if len(boarded) < req.FreeCapacity {
+			p.Board(req.TrainID, req.Time)
+			boarded = append(boarded, p)
+		} else {
+			p.DeniedBoardings++
+			denied++
+			remaining = append(remaining, p)
+		}
+	}
+	s.waitingPassengers = remaining
+
+	if len(boarded) > 0 || denied > 0 {
+		fmt.Printf("  [Station %s] Train %s boarded %d passengers, %d denied (waiting=%d) at %v\n",
+			s.id, req.TrainID, len(boarded), denied, len(s.waitingPassengers), req.Time)
+	}
+
+	req.ResponseCh <- BoardingResponse{Passengers: boarded, Denied: denied, Error: nil}
+}
+
+type PassengerDropOff struct {
+	TrainID    string
+	Passengers []*passengers.Passenger
+}
+
+func (PassengerDropOff) isMessage() {}
+
+func (s *Station) handlePassengerDropOff(notif PassengerDropOff) {
+	for _, p := range notif.Passengers {
+		p.Unboard()
+	}
+	s.waitingPassengers = append(s.waitingPassengers, notif.Passengers...)
+	fmt.Printf("  [Station %s] Train %s dropped off %d passengers (waiting=%d)\n",
+		s.id, notif.TrainID, len(notif.Passengers), len(s.waitingPassengers))
+}
+
+// PriorityOrder comes from the dispatcher: the listed trains are admitted in
+// this order, ahead of the station's own strategy. An empty order cancels it.
+type PriorityOrder struct {
+	TrainIDs []string
+}
+
+func (PriorityOrder) isMessage() {}
+
+func (s *Station) handlePriorityOrder(order PriorityOrder) {
+	s.dispatchOrder = order.TrainIDs
+	s.sortDemands()
+}
+
+// CapacityRestriction reduces the number of trains the station accepts, or
+// restores the nominal capacity when Lifted is set. Trains already in the
+// station stay; new ones are admitted once the occupancy is below the limit.
+type CapacityRestriction struct {
+	Capacity int
+	Lifted   bool
+}
+
+func (CapacityRestriction) isMessage() {}
+
+func (s *Station) handleCapacityRestriction(restriction CapacityRestriction) {
+	if restriction.Lifted {
+		s.restrictedCapacity = nil
+		fmt.Printf("  [Station %s] Capacity restored to %d\n", s.id, s.capacity)
+		return
+	}
+
+	capacity := restriction.Capacity
+	s.restrictedCapacity = &capacity
+	fmt.Printf("  [Station %s] Capacity reduced to %d\n", s.id, capacity)
+}
+
+// StateRequest asks for a snapshot of the station, consistent with every
+// message sent before it.
+type StateRequest struct {
+	ResponseCh chan State
+}
+
+func (StateRequest) isMessage() {}
+
+type State struct {
+	StationID         string
+	Capacity          int // nominal, which trains present when a restriction starts may still fill
+	EffectiveCapacity int
+	Trains            []string
+	Demanding         []string // trains asking to enter, in admission order
+	WaitingPassengers int
+}
+
+func (s *Station) handleStateRequest(req StateRequest) {
+	state := State{
+		StationID:         s.id,
+		Capacity:          s.capacity,
+		EffectiveCapacity: s.effectiveCapacity(),
+		WaitingPassengers: len(s.waitingPassengers),
 	}
+	for trainID := range s.trainsInStation {
+		state.Trains = append(state.Trains, trainID)
+	}
+	sort.Strings(state.Trains)
+	for _, demand := range s.trainsDemandingEntry {
+		state.Demanding = append(state.Demanding, demand.trainID)
+	}
+	req.ResponseCh <- state
+}
+
+// PassengersRequest asks for the passengers waiting at the station,
+// including those dropped off by the messages sent before it.
+type PassengersRequest struct {
+	ResponseCh chan []*passengers.Passenger
+}
+
+func (PassengersRequest) isMessage() {}
+
+func (s *Station) handlePassengersRequest(req PassengersRequest) {
+	req.ResponseCh <- append([]*passengers.Passenger(nil), s.waitingPassengers...)
 }
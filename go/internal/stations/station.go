@@ -1,8 +1,12 @@
 package stations
 
 import (
+	"ai30-project/internal/messaging"
+	"ai30-project/internal/passengers"
+	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 )
 
@@ -11,9 +15,15 @@ type Station struct {
 	name     string
 	capacity int
 
+	restrictedCapacity *int
+
 	trainsInStation      map[string]*trainInfo
 	trainsDemandingEntry []demandInfo
 	strategy             StationStrategy
+	dispatchOrder        []string
+
+	passengerSource   *passengers.Source
+	waitingPassengers []*passengers.Passenger
 
 	inbox chan StationMessage
 }
@@ -29,7 +39,8 @@ func NewStation(id, name string, capacity int) *Station {
 	}
 }
 
-func (s *Station) ID() string { return s.id }
+func (s *Station) ID() string    { return s.id }
+func (s *Station) Capacity() int { return s.capacity }
 
 func (s *Station) Inbox() chan StationMessage {
 	return s.inbox
@@ -39,35 +50,67 @@ func (s *Station) SetStrategy(strategy StationStrategy) {
 	s.strategy = strategy
 }
 
-func (s *Station) Run() {
-	for {
-		msg, ok := <-s.inbox
-		if !ok {
-			return
-		}
+// SetDemand makes passengers appear at the station according to the
+// origin-destination matrix.
+func (s *Station) SetDemand(demand *passengers.Demand) {
+	s.passengerSource = passengers.NewSource(s.id, demand)
+}
 
-		switch m := msg.(type) {
-		case DemandingEntry:
-			s.handleDemandingEntry(m)
-		case EntryRequest:
-			s.handleEntryRequest(m)
-		case DepartureNotification:
-			s.handleDeparture(m)
-		default:
-			fmt.Printf("  [Station %s] ERROR: Unknown message type\n", s.ID())
-		}
+// WaitingPassengers returns the passengers released on the platform and not yet boarded.
+func (s *Station) Run(ctx context.Context) {
+	messaging.Serve(ctx, s.inbox, s.handle)
+}
+
+// Drain handles the messages already in the inbox and returns how many.
+func (s *Station) Drain() int {
+	return messaging.Drain(s.inbox, s.handle)
+}
+
+func (s *Station) handle(msg StationMessage) {
+	switch m := msg.(type) {
+	case DemandingEntry:
+		s.handleDemandingEntry(m)
+	case EntryRequest:
+		s.handleEntryRequest(m)
+	case DepartureNotification:
+		s.handleDeparture(m)
+	case BoardingRequest:
+		s.handleBoarding(m)
+	case PassengerDropOff:
+		s.handlePassengerDropOff(m)
+	case PriorityOrder:
+		s.handlePriorityOrder(m)
+	case CapacityRestriction:
+		s.handleCapacityRestriction(m)
+	case StateRequest:
+		s.handleStateRequest(m)
+	case PassengersRequest:
+		s.handlePassengersRequest(m)
+	default:
+		fmt.Printf("  [Station %s] ERROR: Unknown message type\n", s.ID())
 	}
 }
 
 func (s *Station) MarshalJSON() ([]byte, error) {
 	return json.Marshal(map[string]any{
-		"id":              s.id,
-		"name":            s.name,
-		"capacity":        s.capacity,
-		"trainsInStation": s.trainsInStation,
+		"id":                s.id,
+		"name":              s.name,
+		"capacity":          s.effectiveCapacity(),
+		"nominalCapacity":   s.capacity,
+		"trainsInStation":   s.trainsInStation,
+		"waitingPassengers": len(s.waitingPassengers),
 	})
 }
 
+// effectiveCapacity is the capacity currently available, which a disruption
+// may reduce below the nominal one.
+func (s *Station) effectiveCapacity() int {
+	if s.restrictedCapacity != nil {
+		return *s.restrictedCapacity
+	}
+	return s.capacity
+}
+
 type trainInfo struct {
 	entryTime time.Duration
 }
@@ -79,21 +122,22 @@ func (t *trainInfo) MarshalJSON() ([]byte, error) {
 }
 
 type demandInfo struct {
-	trainID   string
-	delay     time.Duration
-	entryTime time.Duration
+	trainID    string
+	delay      time.Duration
+	entryTime  time.Duration
+	category   string // e.g. OUI, OGO
+	passengers int    // on board when asking
 }
 
-// addOrUpdateDemand adds a demand or updates the entryTime if train already present.
-func (s *Station) addOrUpdateDemand(id string, d time.Duration, t time.Duration) {
+// addOrUpdateDemand adds a demand or updates it if train already present.
+func (s *Station) addOrUpdateDemand(demand demandInfo) {
 	for i := range s.trainsDemandingEntry {
-		if s.trainsDemandingEntry[i].trainID == id {
-			s.trainsDemandingEntry[i].entryTime = t
-			s.trainsDemandingEntry[i].delay = d
+		if s.trainsDemandingEntry[i].trainID == demand.trainID {
+			s.trainsDemandingEntry[i] = demand
 			return
 		}
 	}
-	s.trainsDemandingEntry = append(s.trainsDemandingEntry, demandInfo{trainID: id, entryTime: t, delay: d})
+	s.trainsDemandingEntry = append(s.trainsDemandingEntry, demand)
 }
 
 // removeDemand removes a train from the demanding slice by id.
@@ -106,9 +150,27 @@ func (s *Station) removeDemand(id string) {
 	}
 }
 
-// sortDemands sorts the demanding slice by entryTime ascending.
+// sortDemands sorts the demanding slice with the station strategy, then moves
+// the trains ordered by the dispatcher to the front.
 func (s *Station) sortDemands() {
 	if s.strategy != nil {
 		s.strategy.Sort(s.trainsDemandingEntry)
 	}
+
+	if len(s.dispatchOrder) == 0 {
+		return
+	}
+
+	rank := make(map[string]int, len(s.dispatchOrder))
+	for i, id := range s.dispatchOrder {
+		rank[id] = i
+	}
+	sort.SliceStable(s.trainsDemandingEntry, func(i, j int) bool {
+		ri, iOrdered := rank[s.trainsDemandingEntry[i].trainID]
+		rj, jOrdered := rank[s.trainsDemandingEntry[j].trainID]
+		if iOrdered != jOrdered {
+			return iOrdered
+		}
+		return iOrdered && ri < rj
+	})
 }
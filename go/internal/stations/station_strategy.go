@@ -1,6 +1,9 @@
 package stations
 
 import (
+	"ai30-project/internal/expr"
+	"ai30-project/internal/plugins"
+	"fmt"
 	"sort"
 	"time"
 )
@@ -33,14 +36,15 @@ func (DelayAsc) Sort(demands []demandInfo) {
 }
 
 // DelayAscWithThreshold sorts by delay ascending but treats trains whose
-// delay is greater than 30 minutes as lower priority (they are placed at the end).
-type DelayAscWithThreshold struct{}
+// delay is greater than the threshold as lower priority (they are placed at the end).
+type DelayAscWithThreshold struct {
+	Threshold time.Duration
+}
 
-func (DelayAscWithThreshold) Sort(demands []demandInfo) {
-	threshold := 30 * time.Minute
+func (d DelayAscWithThreshold) Sort(demands []demandInfo) {
 	sort.SliceStable(demands, func(i, j int) bool {
-		iOk := demands[i].delay <= threshold
-		jOk := demands[j].delay <= threshold
+		iOk := demands[i].delay <= d.Threshold
+		jOk := demands[j].delay <= d.Threshold
 		if iOk != jOk {
 			return iOk // true (<=threshold) comes before false
 		}
@@ -48,15 +52,96 @@ func (DelayAscWithThreshold) Sort(demands []demandInfo) {
 	})
 }
 
-func NewStationStrategy(name string) StationStrategy {
-	switch name {
-	case "entry_time_asc":
-		return EntryTimeAsc{}
-	case "delay_asc":
-		return DelayAsc{}
-	case "delay_asc_with_threshold":
-		return DelayAscWithThreshold{}
-	default:
-		return NoSort{}
+// ScriptSort orders the demands by the keys of an expression, evaluated for
+// each demand (see package expr). Ties keep the order of the requests.
+type ScriptSort struct {
+	program *expr.Program
+}
+
+// scriptVars are the variables a script can use: delay and requested entry
+// time in minutes, train category and ID, passengers on board.
+var scriptVars = map[string]expr.Type{
+	"delay":      expr.Number,
+	"entryTime":  expr.Number,
+	"category":   expr.String,
+	"train":      expr.String,
+	"passengers": expr.Number,
+}
+
+func NewScriptSort(source string) (ScriptSort, error) {
+	program, err := expr.Compile(source, scriptVars)
+	if err != nil {
+		return ScriptSort{}, fmt.Errorf("parsing script: %w", err)
 	}
+	return ScriptSort{program: program}, nil
+}
+
+func (s ScriptSort) Sort(demands []demandInfo) {
+	keys := make(map[string][]any, len(demands))
+	for _, d := range demands {
+		key, err := s.program.Eval(expr.Vars{
+			"delay":      d.delay.Minutes(),
+			"entryTime":  d.entryTime.Minutes(),
+			"category":   d.category,
+			"train":      d.trainID,
+			"passengers": float64(d.passengers),
+		})
+		if err != nil {
+			// Keep the order of the requests rather than a partial one
+			fmt.Printf("  [Strategy] ERROR: script %q for train %s: %v\n", s.program, d.trainID, err)
+			return
+		}
+		keys[d.trainID] = key
+	}
+
+	sort.SliceStable(demands, func(i, j int) bool {
+		return expr.Compare(keys[demands[i].trainID], keys[demands[j].trainID]) < 0
+	})
+}
+
+// Strategies are the orderings a station can admit trains in, by name.
+var Strategies = plugins.NewRegistry[StationStrategy]("station strategy")
+
+func init() {
+	fixed := func(name, description string, strategy StationStrategy) plugins.Plugin[StationStrategy] {
+		return plugins.Plugin[StationStrategy]{
+			Name:        name,
+			Description: description,
+			New:         func(plugins.Values) (StationStrategy, error) { return strategy, nil },
+		}
+	}
+	Strategies.Register(fixed("no_sort", "trains in the order they asked to enter", NoSort{}))
+	Strategies.Register(fixed("entry_time_asc", "earliest requested entry first", EntryTimeAsc{}))
+	Strategies.Register(fixed("delay_asc", "smallest delay first", DelayAsc{}))
+	Strategies.Register(plugins.Plugin[StationStrategy]{
+		Name:        "delay_asc_with_threshold",
+		Description: "smallest delay first, trains later than the threshold last",
+		Params: []plugins.Param{
+			{Name: "thresholdMin", Type: plugins.Number, Description: "minutes of delay past which a train goes last", Default: 30.0, Min: 0, Max: 1440},
+		},
+		New: func(params plugins.Values) (StationStrategy, error) {
+			return DelayAscWithThreshold{Threshold: time.Duration(params.Number("thresholdMin") * float64(time.Minute))}, nil
+		},
+	})
+	Strategies.Register(plugins.Plugin[StationStrategy]{
+		Name:        "script",
+		Description: "sorted by the comma-separated keys of an expression, smallest first",
+		Params: []plugins.Param{
+			{
+				Name: "expression",
+				Type: plugins.String,
+				Description: "keys over delay and entryTime (minutes), category, train and passengers, " +
+					`e.g. delay > 30, category == "OGO" ? 0 : 1, -passengers`,
+				Default: "entryTime",
+			},
+		},
+		New: func(params plugins.Values) (StationStrategy, error) {
+			return NewScriptSort(params.String("expression"))
+		},
+	})
+}
+
+// NewStationStrategy builds a registered strategy from its parameters.
+func NewStationStrategy(name string, params map[string]any) (StationStrategy, error) {
+	return Strategies.New(name, params)
 }
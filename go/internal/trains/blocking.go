@@ -0,0 +1,21 @@
+package trains
+
+// Blocking describes a resource a train could not get: the segment or
+// station it waits to enter and the trains that must move first.
+type Blocking struct {
+	ResourceID string
+	Holders    []string
+}
+
+// Blocked returns what the train waited for during the last tick, or nil.
+// It must only be called between ticks.
+func (t *Train) Blocked() *Blocking {
+	return t.blocking
+}
+
+func (t *Train) setBlocked(resourceID string, holders ...string) {
+	if len(holders) == 0 || holders[0] == "" {
+		return
+	}
+	t.blocking = &Blocking{ResourceID: resourceID, Holders: holders}
+}
@@ -1,47 +1,88 @@
 package trains
 
 import (
+	"ai30-project/internal/circulation"
+	"ai30-project/internal/connections"
+	"ai30-project/internal/crew"
+	"ai30-project/internal/messaging"
 	"ai30-project/internal/navigation"
+	"ai30-project/internal/passengers"
+	"ai30-project/internal/power"
+	"ai30-project/internal/propagation"
 	"ai30-project/internal/segments"
 	"ai30-project/internal/stations"
-	"errors"
+	"fmt"
 	"time"
 )
 
+// request sends a request to an agent and waits for its response, within the
+// request timeout of the train.
+func request[M, R any](t *Train, agent string, inbox chan M, msg M, responseCh chan R) (R, error) {
+	var (
+		response R
+		err      error
+	)
+	if t.deliver != nil {
+		response, err = messaging.Call(agent, inbox, msg, responseCh, func() { t.deliver(inbox) })
+	} else {
+		response, err = messaging.Request(t.ctx, agent, inbox, msg, responseCh, t.requestTimeout)
+	}
+	t.tap.Request("train "+t.id, agent, msg, response, err)
+	return response, err
+}
+
+// notify sends a notification to an agent. A notification that cannot be
+// delivered is logged and dropped rather than blocking the tick.
+func notify[M any](t *Train, agent string, inbox chan M, msg M) {
+	var err error
+	if t.deliver != nil {
+		err = messaging.Post(agent, inbox, msg, func() { t.deliver(inbox) })
+	} else {
+		err = messaging.Notify(t.ctx, agent, inbox, msg, t.requestTimeout)
+	}
+	t.tap.Notification("train "+t.id, agent, msg, err)
+	if err != nil {
+		fmt.Printf("  [Train %s] ERROR: Notifying %v\n", t.id, err)
+	}
+}
+
 func (t *Train) demandingStationEntry(stationID string, fromSegment string, delay time.Duration, requestTime time.Duration) (stations.DemandingEntryResponse, error) {
 	inbox, ok := t.stationInboxes[stationID]
 	if !ok {
-		return stations.DemandingEntryResponse{Validate: false}, errors.New("station inbox not found")
+		return stations.DemandingEntryResponse{Validate: false}, messaging.Unknown("station " + stationID)
 	}
 
-	responseCh := make(chan stations.DemandingEntryResponse)
-	inbox <- stations.DemandingEntry{
+	responseCh := make(chan stations.DemandingEntryResponse, 1)
+	response, err := request[stations.StationMessage](t, "station "+stationID, inbox, stations.DemandingEntry{
 		TrainID:     t.id,
 		FromSegment: fromSegment,
 		Delay:       delay,
 		RequestTime: requestTime,
+		Passengers:  t.OnBoard(),
 		ResponseCh:  responseCh,
+	}, responseCh)
+	if err != nil {
+		return response, err
 	}
-
-	response := <-responseCh
 	return response, response.Error
 }
 
 func (t *Train) requestStationEntry(stationID string, fromSegment string, entryTime time.Duration) (stations.EntryResponse, error) {
 	inbox, ok := t.stationInboxes[stationID]
 	if !ok {
-		return stations.EntryResponse{Allowed: false}, errors.New("station inbox not found")
+		return stations.EntryResponse{Allowed: false}, messaging.Unknown("station " + stationID)
 	}
 
-	responseCh := make(chan stations.EntryResponse)
-	inbox <- stations.EntryRequest{
+	responseCh := make(chan stations.EntryResponse, 1)
+	response, err := request[stations.StationMessage](t, "station "+stationID, inbox, stations.EntryRequest{
 		TrainID:     t.id,
 		FromSegment: fromSegment,
 		EntryTime:   entryTime,
 		ResponseCh:  responseCh,
+	}, responseCh)
+	if err != nil {
+		return response, err
 	}
-
-	response := <-responseCh
 	return response, response.Error
 }
 
@@ -51,42 +92,45 @@ func (t *Train) notifyStationDeparture(stationID string) {
 		return
 	}
 
-	inbox <- stations.DepartureNotification{
+	notify[stations.StationMessage](t, "station "+stationID, inbox, stations.DepartureNotification{
 		TrainID: t.id,
-	}
+	})
 }
 
 func (t *Train) requestSegmentEntry(segmentID string, entryTime time.Duration) (segments.EntryResponse, error) {
 	inbox, ok := t.segmentInboxes[segmentID]
 	if !ok {
-		return segments.EntryResponse{Allowed: false}, errors.New("segment inbox not found")
+		return segments.EntryResponse{Allowed: false}, messaging.Unknown("segment " + segmentID)
 	}
 
-	responseCh := make(chan segments.EntryResponse)
-	inbox <- segments.EntryRequest{
+	responseCh := make(chan segments.EntryResponse, 1)
+	response, err := request[segments.SegmentMessage](t, "segment "+segmentID, inbox, segments.EntryRequest{
 		TrainID:    t.id,
 		Time:       entryTime,
 		ResponseCh: responseCh,
+	}, responseCh)
+	if err != nil {
+		return response, err
 	}
-
-	response := <-responseCh
 	return response, response.Error
 }
 
-func (t *Train) getTrainAhead(segmentID string, position float64) (segments.GetTrainAheadResponse, error) {
+func (t *Train) getTrainAhead(segmentID string, position float64, currentTime time.Duration) (segments.GetTrainAheadResponse, error) {
 	inbox, ok := t.segmentInboxes[segmentID]
 	if !ok {
-		return segments.GetTrainAheadResponse{HasTrainAhead: false}, errors.New("segment inbox not found")
+		return segments.GetTrainAheadResponse{HasTrainAhead: false}, messaging.Unknown("segment " + segmentID)
 	}
 
-	responseCh := make(chan segments.GetTrainAheadResponse)
-	inbox <- segments.GetTrainAheadRequest{
+	responseCh := make(chan segments.GetTrainAheadResponse, 1)
+	response, err := request[segments.SegmentMessage](t, "segment "+segmentID, inbox, segments.GetTrainAheadRequest{
 		TrainID:    t.id,
 		Position:   position,
+		Time:       currentTime,
 		ResponseCh: responseCh,
+	}, responseCh)
+	if err != nil {
+		return response, err
 	}
-
-	response := <-responseCh
 	return response, response.Error
 }
 
@@ -96,11 +140,11 @@ func (t *Train) notifySegmentPosition(segmentID string, position, speed float64)
 		return
 	}
 
-	inbox <- segments.UpdatePositionNotification{
+	notify[segments.SegmentMessage](t, "segment "+segmentID, inbox, segments.UpdatePositionNotification{
 		TrainID:  t.id,
 		Position: position,
 		Speed:    speed,
-	}
+	})
 }
 
 func (t *Train) notifySegmentExit(segmentID string) {
@@ -109,23 +153,230 @@ func (t *Train) notifySegmentExit(segmentID string) {
 		return
 	}
 
-	inbox <- segments.ExitNotification{
+	notify[segments.SegmentMessage](t, "segment "+segmentID, inbox, segments.ExitNotification{
 		TrainID: t.id,
-	}
+	})
 }
 
-func (t *Train) requestPath(fromStation, toStation string) (navigation.PathResponse, error) {
+// requestPath asks for a path between two stations, off the closed segments
+// and those to avoid.
+func (t *Train) requestPath(fromStation, toStation string, avoid ...string) (navigation.PathResponse, error) {
 	if t.navigationInbox == nil {
-		return navigation.PathResponse{Segments: nil}, errors.New("navigation inbox not set")
+		return navigation.PathResponse{Segments: nil}, messaging.Unknown("navigation")
 	}
 
-	responseCh := make(chan navigation.PathResponse)
-	t.navigationInbox <- navigation.PathRequest{
+	responseCh := make(chan navigation.PathResponse, 1)
+	response, err := request[navigation.NavigationMessage](t, "navigation", t.navigationInbox, navigation.PathRequest{
 		FromStation: fromStation,
 		ToStation:   toStation,
+		Avoid:       avoid,
 		ResponseCh:  responseCh,
+	}, responseCh)
+	if err != nil {
+		return response, err
+	}
+	return response, response.Error
+}
+
+func (t *Train) requestBoarding(stationID string, boardingTime time.Duration, freeCapacity int, serves map[string]time.Duration) (stations.BoardingResponse, error) {
+	inbox, ok := t.stationInboxes[stationID]
+	if !ok {
+		return stations.BoardingResponse{Passengers: nil}, messaging.Unknown("station " + stationID)
+	}
+
+	responseCh := make(chan stations.BoardingResponse, 1)
+	response, err := request[stations.StationMessage](t, "station "+stationID, inbox, stations.BoardingRequest{
+		TrainID:      t.id,
+		Time:         boardingTime,
+		FreeCapacity: freeCapacity,
+		Serves:       serves,
+		ResponseCh:   responseCh,
+	}, responseCh)
+	if err != nil {
+		return response, err
+	}
+	return response, response.Error
+}
+
+func (t *Train) notifyPassengerDropOff(stationID string, dropped []*passengers.Passenger) {
+	inbox, ok := t.stationInboxes[stationID]
+	if !ok {
+		return
+	}
+
+	notify[stations.StationMessage](t, "station "+stationID, inbox, stations.PassengerDropOff{
+		TrainID:    t.id,
+		Passengers: dropped,
+	})
+}
+
+func (t *Train) requestConnectionHold(stationID string, scheduledDeparture, currentTime time.Duration) (connections.HoldResponse, error) {
+	if t.connectionInbox == nil {
+		return connections.HoldResponse{Hold: false}, nil
 	}
 
-	response := <-responseCh
+	responseCh := make(chan connections.HoldResponse, 1)
+	response, err := request[connections.ConnectionMessage](t, "connections", t.connectionInbox, connections.HoldRequest{
+		TrainID:            t.id,
+		StationID:          stationID,
+		ScheduledDeparture: scheduledDeparture,
+		Time:               currentTime,
+		ResponseCh:         responseCh,
+	}, responseCh)
+	if err != nil {
+		return response, err
+	}
 	return response, response.Error
 }
+
+func (t *Train) notifyConnectionArrival(stationID string, arrivalTime time.Duration) {
+	if t.connectionInbox == nil {
+		return
+	}
+
+	notify[connections.ConnectionMessage](t, "connections", t.connectionInbox, connections.ArrivalNotification{
+		TrainID:   t.id,
+		StationID: stationID,
+		Time:      arrivalTime,
+	})
+}
+
+func (t *Train) notifyConnectionDeparture(stationID string, scheduledDeparture, departureTime time.Duration) {
+	if t.connectionInbox == nil {
+		return
+	}
+
+	notify[connections.ConnectionMessage](t, "connections", t.connectionInbox, connections.DepartureNotification{
+		TrainID:            t.id,
+		StationID:          stationID,
+		ScheduledDeparture: scheduledDeparture,
+		Time:               departureTime,
+	})
+}
+
+func (t *Train) requestTrainsetReady(stationID string, scheduledDeparture, currentTime time.Duration) (circulation.ReadyResponse, error) {
+	if t.circulationInbox == nil {
+		return circulation.ReadyResponse{Ready: true}, nil
+	}
+
+	responseCh := make(chan circulation.ReadyResponse, 1)
+	response, err := request[circulation.CirculationMessage](t, "circulation", t.circulationInbox, circulation.ReadyRequest{
+		TrainID:            t.id,
+		StationID:          stationID,
+		ScheduledDeparture: scheduledDeparture,
+		Time:               currentTime,
+		ResponseCh:         responseCh,
+	}, responseCh)
+	if err != nil {
+		return response, err
+	}
+	return response, response.Error
+}
+
+func (t *Train) notifyCirculationFinish(stationID string, finishTime time.Duration) {
+	if t.circulationInbox == nil {
+		return
+	}
+
+	notify[circulation.CirculationMessage](t, "circulation", t.circulationInbox, circulation.FinishNotification{
+		TrainID:   t.id,
+		StationID: stationID,
+		Time:      finishTime,
+	})
+}
+
+func (t *Train) requestCrewReady(stationID string, scheduledDeparture, currentTime time.Duration) (crew.ReadyResponse, error) {
+	if t.crewInbox == nil {
+		return crew.ReadyResponse{Ready: true}, nil
+	}
+
+	responseCh := make(chan crew.ReadyResponse, 1)
+	response, err := request[crew.CrewMessage](t, "crew", t.crewInbox, crew.ReadyRequest{
+		TrainID:            t.id,
+		StationID:          stationID,
+		ScheduledDeparture: scheduledDeparture,
+		Time:               currentTime,
+		ResponseCh:         responseCh,
+	}, responseCh)
+	if err != nil {
+		return response, err
+	}
+	return response, response.Error
+}
+
+func (t *Train) notifyCrewArrival(stationID string, arrivalTime time.Duration) {
+	if t.crewInbox == nil {
+		return
+	}
+
+	notify[crew.CrewMessage](t, "crew", t.crewInbox, crew.ArrivalNotification{
+		TrainID:   t.id,
+		StationID: stationID,
+		Time:      arrivalTime,
+	})
+}
+
+func (t *Train) notifyCrewCancellation(cancellationTime time.Duration) {
+	if t.crewInbox == nil {
+		return
+	}
+
+	notify[crew.CrewMessage](t, "crew", t.crewInbox, crew.CancellationNotification{
+		TrainID: t.id,
+		Time:    cancellationTime,
+	})
+}
+
+func (t *Train) notifyWait(reason propagation.Reason, cause, blockingTrainID, location string, currentTime time.Duration) {
+	if t.tracerInbox == nil {
+		return
+	}
+
+	notify[propagation.TracerMessage](t, "tracer", t.tracerInbox, propagation.WaitNotification{Wait: propagation.Wait{
+		TrainID:         t.id,
+		Time:            currentTime,
+		Reason:          reason,
+		Cause:           cause,
+		BlockingTrainID: blockingTrainID,
+		Location:        location,
+	}})
+}
+
+func (t *Train) notifyTracerFinish(delay time.Duration) {
+	if t.tracerInbox == nil {
+		return
+	}
+
+	notify[propagation.TracerMessage](t, "tracer", t.tracerInbox, propagation.FinishNotification{TrainID: t.id, Delay: delay})
+}
+
+func (t *Train) requestTractionShare(segmentID string, currentTime time.Duration) (float64, error) {
+	if t.powerInbox == nil {
+		return 1, nil
+	}
+
+	responseCh := make(chan power.ShareResponse, 1)
+	response, err := request[power.PowerMessage](t, "power", t.powerInbox, power.ShareRequest{
+		TrainID:    t.id,
+		SegmentID:  segmentID,
+		Time:       currentTime,
+		ResponseCh: responseCh,
+	}, responseCh)
+	if err != nil {
+		return 1, err
+	}
+	return response.Share, response.Error
+}
+
+func (t *Train) notifyPowerDemand(segmentID string, demand float64, currentTime time.Duration) {
+	if t.powerInbox == nil || demand <= 0 {
+		return
+	}
+
+	notify[power.PowerMessage](t, "power", t.powerInbox, power.DemandNotification{
+		TrainID:   t.id,
+		SegmentID: segmentID,
+		Power:     demand,
+		Time:      currentTime,
+	})
+}
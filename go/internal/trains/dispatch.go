@@ -0,0 +1,106 @@
+package trains
+
+import (
+	"ai30-project/internal/dispatcher"
+	"fmt"
+	"time"
+)
+
+// Orders returns the inbox where the dispatcher sends orders to the train.
+func (t *Train) Orders() chan dispatcher.Order {
+	return t.orders
+}
+
+func (t *Train) SetDispatcherInbox(dispatcherInbox chan dispatcher.DispatcherMessage) {
+	t.dispatcherInbox = dispatcherInbox
+}
+
+// receiveOrders applies the orders received since the previous tick.
+func (t *Train) receiveOrders() {
+	for {
+		select {
+		case order := <-t.orders:
+			switch o := order.(type) {
+			case dispatcher.HoldOrder:
+				t.holdUntil = o.Until
+			case dispatcher.SpeedOrder:
+				t.speedLimit = o.MaxSpeed
+				t.speedLimitUntil = o.Until
+			case dispatcher.RerouteOrder:
+				t.rerouteRequested = true
+			case dispatcher.ClosureNotice:
+				t.closures = append(t.closures, o.SegmentID)
+			case dispatcher.CancelOrder:
+				t.cancelOrdered = true
+			default:
+				fmt.Printf("  [Train %s] ERROR: Unknown order type\n", t.id)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (t *Train) isHeldByDispatcher(currentTime time.Duration) bool {
+	return currentTime <= t.holdUntil
+}
+
+// dispatcherSpeedLimit returns the speed cap ordered by the dispatcher, if any.
+func (t *Train) dispatcherSpeedLimit(currentTime time.Duration) (float64, bool) {
+	return t.speedLimit, currentTime <= t.speedLimitUntil
+}
+
+func (t *Train) reportStatus(currentTime time.Duration) {
+	if t.dispatcherInbox == nil {
+		return
+	}
+
+	if report, ok := t.Status(currentTime); ok {
+		notify[dispatcher.DispatcherMessage](t, "dispatcher", t.dispatcherInbox, report)
+	}
+}
+
+// Status is where the train stands and how late it is, as reported to the
+// dispatcher. It is not known for a finished train. It must only be called
+// between ticks by others than the train.
+func (t *Train) Status(currentTime time.Duration) (dispatcher.StatusReport, bool) {
+	if t.isFinished {
+		return dispatcher.StatusReport{}, false
+	}
+
+	report := dispatcher.StatusReport{
+		TrainID: t.id,
+		Time:    currentTime,
+	}
+
+	if nextStop := t.NextStop(); nextStop != nil {
+		report.NextStationID = nextStop.stationID
+		report.ScheduledArrival = nextStop.arrival
+	}
+
+	switch state := t.state.(type) {
+	case *atStationState:
+		currentStop := t.CurrentStop()
+		if currentStop == nil {
+			return report, false
+		}
+		report.AtStation = true
+		report.StationID = currentStop.stationID
+		report.ScheduledDeparture = currentStop.departure
+		report.Delay = max(currentTime-currentStop.departure, 0)
+
+	case *onSegmentState:
+		report.SegmentID = state.currentSegment().ID
+		report.Position = state.position
+		report.Speed = state.speed
+		report.RemainingDistance = state.totalLength() - state.traveledDistance()
+		report.Waiting = state.waiting
+		report.WaitingSince = state.waitingSince
+		if state.waiting && state.currentIndex+1 < len(state.segments) {
+			report.BlockedSegmentID = state.segments[state.currentIndex+1].ID
+		}
+		report.Delay = state.delay
+	}
+
+	return report, true
+}
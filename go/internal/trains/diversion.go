@@ -0,0 +1,47 @@
+package trains
+
+import (
+	"ai30-project/internal/navigation"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// diversion records a path that avoided a closed or occupied segment.
+type diversion struct {
+	fromStationID    string
+	time             time.Duration
+	addedRunningTime time.Duration
+}
+
+func (d diversion) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"fromStationId":    d.fromStationID,
+		"time":             d.time,
+		"addedRunningTime": d.addedRunningTime,
+	})
+}
+
+func (t *Train) recordDiversion(fromStationID string, path navigation.PathResponse, currentTime time.Duration) {
+	if !path.Diverted {
+		return
+	}
+
+	t.diversions = append(t.diversions, diversion{
+		fromStationID:    fromStationID,
+		time:             currentTime,
+		addedRunningTime: path.AddedRunningTime,
+	})
+	fmt.Printf("  [Train %s] DIVERTED from station %s around a closed or occupied segment (+%v) at %v\n",
+		t.id, fromStationID, path.AddedRunningTime, currentTime)
+}
+
+// Diversions returns how many times the train was diverted and the running
+// time it added.
+func (t *Train) Diversions() (int, time.Duration) {
+	added := time.Duration(0)
+	for _, d := range t.diversions {
+		added += d.addedRunningTime
+	}
+	return len(t.diversions), added
+}
@@ -0,0 +1,134 @@
+package trains
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// CurvePoint is the command of a parametric driver at a given lateness.
+type CurvePoint struct {
+	Lateness time.Duration
+	Command  DriverCommand
+}
+
+// ParametricDriver interpolates its command along a curve of lateness: the
+// delay, or the time missing to reach the next stop on schedule when that is
+// more. Outside the curve, the nearest point applies. Close to a stop, the
+// driver coasts if there is enough slack left.
+type ParametricDriver struct {
+	Curve         []CurvePoint  // by increasing lateness
+	CoastDistance float64       // meters before the stop, 0 never to coast
+	CoastMinSlack time.Duration // slack needed to coast
+}
+
+// NewAdaptiveDriver drives eco when early, pushes towards line speed as the
+// delay grows and coasts over the last 4 km before a stop it reaches in time.
+func NewAdaptiveDriver() *ParametricDriver {
+	return &ParametricDriver{
+		Curve: []CurvePoint{
+			{Lateness: -2 * time.Minute, Command: DriverCommand{DesiredSpeed: 0.7, DesiredAccel: 0.6, DesiredDecel: 0.6}},
+			{Lateness: 3 * time.Minute, Command: DriverCommand{DesiredSpeed: 1.0, DesiredAccel: 1.0, DesiredDecel: 0.8}},
+		},
+		CoastDistance: 4000,
+		CoastMinSlack: time.Minute,
+	}
+}
+
+func (d *ParametricDriver) GetCommand(situation DriverSituation) DriverCommand {
+	command := d.interpolate(max(situation.Delay, -situation.Slack))
+	command.Coast = situation.DistanceToStop <= d.CoastDistance && situation.Slack >= d.CoastMinSlack
+	return command
+}
+
+func (d *ParametricDriver) interpolate(lateness time.Duration) DriverCommand {
+	first, last := d.Curve[0], d.Curve[len(d.Curve)-1]
+	if lateness <= first.Lateness {
+		return first.Command
+	}
+	if lateness >= last.Lateness {
+		return last.Command
+	}
+
+	for i := 1; i < len(d.Curve); i++ {
+		from, to := d.Curve[i-1], d.Curve[i]
+		if lateness > to.Lateness {
+			continue
+		}
+		ratio := float64(lateness-from.Lateness) / float64(to.Lateness-from.Lateness)
+		lerp := func(a, b float64) float64 { return a + (b-a)*ratio }
+		return DriverCommand{
+			DesiredSpeed: lerp(from.Command.DesiredSpeed, to.Command.DesiredSpeed),
+			DesiredAccel: lerp(from.Command.DesiredAccel, to.Command.DesiredAccel),
+			DesiredDecel: lerp(from.Command.DesiredDecel, to.Command.DesiredDecel),
+		}
+	}
+	return last.Command
+}
+
+type curvePointEntry struct {
+	LatenessMin float64 `json:"latenessMin"`
+	Speed       float64 `json:"speed"`
+	Accel       float64 `json:"accel"`
+	Decel       float64 `json:"decel"`
+}
+
+type parametricDriverEntry struct {
+	Curve            []curvePointEntry `json:"curve"`
+	CoastDistanceM   float64           `json:"coastDistanceM"`
+	CoastMinSlackMin float64           `json:"coastMinSlackMin"`
+}
+
+// ParseParametricDriver reads a driver curve in JSON. Speed, accel and decel
+// are shares of the line speed and of the train performance, as for the
+// built-in drivers:
+//
+//	{"curve": [{"latenessMin": -2, "speed": 0.7, "accel": 0.6, "decel": 0.6},
+//	           {"latenessMin": 3, "speed": 1.0, "accel": 1.0, "decel": 0.8}],
+//	 "coastDistanceM": 4000, "coastMinSlackMin": 1}
+func ParseParametricDriver(raw []byte) (*ParametricDriver, error) {
+	var entry parametricDriverEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return nil, fmt.Errorf("parsing driver: %w", err)
+	}
+
+	if len(entry.Curve) == 0 {
+		return nil, fmt.Errorf("driver: curve needs at least one point")
+	}
+	if entry.CoastDistanceM < 0 {
+		return nil, fmt.Errorf("driver: coastDistanceM cannot be negative")
+	}
+
+	d := &ParametricDriver{
+		CoastDistance: entry.CoastDistanceM,
+		CoastMinSlack: minutes(entry.CoastMinSlackMin),
+	}
+	for i, p := range entry.Curve {
+		if p.Speed <= 0 || p.Accel <= 0 || p.Decel <= 0 {
+			return nil, fmt.Errorf("driver: curve point %d: speed, accel and decel must be positive", i)
+		}
+		point := CurvePoint{
+			Lateness: minutes(p.LatenessMin),
+			Command:  DriverCommand{DesiredSpeed: p.Speed, DesiredAccel: p.Accel, DesiredDecel: p.Decel},
+		}
+		if i > 0 && point.Lateness <= d.Curve[i-1].Lateness {
+			return nil, fmt.Errorf("driver: curve point %d: lateness must increase", i)
+		}
+		d.Curve = append(d.Curve, point)
+	}
+
+	return d, nil
+}
+
+func LoadParametricDriver(path string) (*ParametricDriver, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading driver file: %w", err)
+	}
+	return ParseParametricDriver(raw)
+}
+
+func minutes(value float64) time.Duration {
+	return time.Duration(value * float64(time.Minute))
+}
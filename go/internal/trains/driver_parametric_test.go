@@ -0,0 +1,81 @@
+package trains
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseParametricDriver(t *testing.T) {
+	driver, err := ParseParametricDriver([]byte(`{"curve": [
+		{"latenessMin": -2, "speed": 0.7, "accel": 0.6, "decel": 0.6},
+		{"latenessMin": 2, "speed": 0.9, "accel": 1.0, "decel": 0.8}],
+		"coastDistanceM": 3000, "coastMinSlackMin": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Interpolated along the lateness, the nearest point outside the curve
+	cases := []struct {
+		lateness time.Duration
+		speed    float64
+	}{
+		{-10 * time.Minute, 0.7},
+		{0, 0.8},
+		{time.Minute, 0.85},
+		{10 * time.Minute, 0.9},
+	}
+	for _, c := range cases {
+		if got := driver.interpolate(c.lateness).DesiredSpeed; got < c.speed-1e-9 || got > c.speed+1e-9 {
+			t.Errorf("speed at %v late = %v, want %v", c.lateness, got, c.speed)
+		}
+	}
+
+	// Lateness is the time missing to the next stop when that is more
+	command := driver.GetCommand(DriverSituation{Delay: -5 * time.Minute, Slack: -2 * time.Minute, DistanceToStop: 10000})
+	if command.DesiredSpeed != 0.9 || command.Coast {
+		t.Errorf("command short of time = %+v, want speed 0.9 without coasting", command)
+	}
+	command = driver.GetCommand(DriverSituation{Slack: 2 * time.Minute, DistanceToStop: 2000})
+	if !command.Coast {
+		t.Error("driver with time to spare near the stop does not coast")
+	}
+	command = driver.GetCommand(DriverSituation{Slack: 30 * time.Second, DistanceToStop: 2000})
+	if command.Coast {
+		t.Error("driver without enough slack coasts")
+	}
+
+	invalid := []string{
+		`{"curve": []}`,
+		`{"curve": [{"latenessMin": 0, "speed": 0, "accel": 1, "decel": 1}]}`,
+		`{"curve": [{"latenessMin": 2, "speed": 1, "accel": 1, "decel": 1}, {"latenessMin": 1, "speed": 1, "accel": 1, "decel": 1}]}`,
+		`{"curve": [{"latenessMin": 0, "speed": 1, "accel": 1, "decel": 1}], "coastDistanceM": -1}`,
+	}
+	for _, raw := range invalid {
+		if _, err := ParseParametricDriver([]byte(raw)); err == nil {
+			t.Errorf("driver %s accepted", raw)
+		}
+	}
+}
+
+func TestFileDriver(t *testing.T) {
+	if _, err := Drivers.New("file", nil); err == nil {
+		t.Error("file driver without a path accepted")
+	}
+	if _, err := Drivers.New("file", map[string]any{"path": filepath.Join(t.TempDir(), "missing.json")}); err == nil {
+		t.Error("missing driver file accepted")
+	}
+
+	path := filepath.Join(t.TempDir(), "driver.json")
+	if err := os.WriteFile(path, []byte(`{"curve": [{"latenessMin": 0, "speed": 0.8, "accel": 0.7, "decel": 0.7}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	driver, err := Drivers.New("file", map[string]any{"path": path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := driver.GetCommand(DriverSituation{}).DesiredSpeed; got != 0.8 {
+		t.Errorf("speed = %v, want 0.8 from the file", got)
+	}
+}
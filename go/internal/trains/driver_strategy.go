@@ -1,6 +1,9 @@
 package trains
 
 import (
+	"ai30-project/internal/constants"
+	"ai30-project/internal/das"
+	"ai30-project/internal/plugins"
 	"time"
 )
 
@@ -8,15 +11,26 @@ type DriverCommand struct {
 	DesiredSpeed float64
 	DesiredAccel float64
 	DesiredDecel float64
+	Coast        bool    // cut traction and let running resistance slow the train
+	TargetSpeed  float64 // m/s, replaces the speed keeping the schedule when set
+}
+
+// DriverSituation is what the driver knows of the schedule when choosing a
+// command.
+type DriverSituation struct {
+	Delay          time.Duration // negative when ahead of the schedule
+	Slack          time.Duration // time to spare to the next stop, running at line speed
+	DistanceToStop float64       // meters
+	Advice         *das.Advice   // set for a driver following the advisory system
 }
 
 type DriverBehavior interface {
-	GetCommand(delay time.Duration) DriverCommand
+	GetCommand(situation DriverSituation) DriverCommand
 }
 
 type EcoDriver struct{}
 
-func (d EcoDriver) GetCommand(delay time.Duration) DriverCommand {
+func (d EcoDriver) GetCommand(situation DriverSituation) DriverCommand {
 	return DriverCommand{
 		DesiredSpeed: 0.7,
 		DesiredAccel: 0.6,
@@ -26,7 +40,7 @@ func (d EcoDriver) GetCommand(delay time.Duration) DriverCommand {
 
 type IntermediateDriver struct{}
 
-func (d IntermediateDriver) GetCommand(delay time.Duration) DriverCommand {
+func (d IntermediateDriver) GetCommand(situation DriverSituation) DriverCommand {
 	return DriverCommand{
 		DesiredSpeed: 0.7,
 		DesiredAccel: 0.8,
@@ -36,7 +50,7 @@ func (d IntermediateDriver) GetCommand(delay time.Duration) DriverCommand {
 
 type CrazyDriver struct{}
 
-func (d CrazyDriver) GetCommand(delay time.Duration) DriverCommand {
+func (d CrazyDriver) GetCommand(situation DriverSituation) DriverCommand {
 	return DriverCommand{
 		DesiredSpeed: 1.0,
 		DesiredAccel: 0.8,
@@ -46,7 +60,7 @@ func (d CrazyDriver) GetCommand(delay time.Duration) DriverCommand {
 
 type VeryCrazyDriver struct{}
 
-func (d VeryCrazyDriver) GetCommand(delay time.Duration) DriverCommand {
+func (d VeryCrazyDriver) GetCommand(situation DriverSituation) DriverCommand {
 	return DriverCommand{
 		DesiredSpeed: 1.2,
 		DesiredAccel: 1.0,
@@ -56,7 +70,7 @@ func (d VeryCrazyDriver) GetCommand(delay time.Duration) DriverCommand {
 
 type SoigneuxDriver struct{}
 
-func (d SoigneuxDriver) GetCommand(delay time.Duration) DriverCommand {
+func (d SoigneuxDriver) GetCommand(situation DriverSituation) DriverCommand {
 	return DriverCommand{
 		DesiredSpeed: 1.0,
 		DesiredAccel: 0.6,
@@ -64,17 +78,86 @@ func (d SoigneuxDriver) GetCommand(delay time.Duration) DriverCommand {
 	}
 }
 
-func NewDriverBehavior(name string) DriverBehavior {
-	switch name {
-	case "intermediate":
-		return IntermediateDriver{}
-	case "crazy":
-		return CrazyDriver{}
-	case "very_crazy":
-		return VeryCrazyDriver{}
-	case "soigneux":
-		return SoigneuxDriver{}
-	default:
-		return EcoDriver{}
+// AdvisedDriver is a driver following the Driver Advisory System, which fits
+// the profiles to how it drives.
+type AdvisedDriver interface {
+	DriverBehavior
+	Performance() das.Performance
+}
+
+// DASDriver follows the speed profile of the Driver Advisory System, and
+// drives like an intermediate driver when it gets no advice.
+type DASDriver struct{}
+
+func (d DASDriver) GetCommand(situation DriverSituation) DriverCommand {
+	command := IntermediateDriver{}.GetCommand(situation)
+	if situation.Advice == nil {
+		return command
+	}
+	command.DesiredSpeed = 1.0
+	command.TargetSpeed = situation.Advice.Speed
+	command.Coast = situation.Advice.Phase == das.Coast
+	return command
+}
+
+func (d DASDriver) Performance() das.Performance {
+	command := IntermediateDriver{}.GetCommand(DriverSituation{})
+	return das.Performance{
+		Accel:         command.DesiredAccel * constants.MaxAcceleration,
+		Brake:         -command.DesiredDecel * constants.MaxServiceBrake,
+		Coast:         -constants.CoastingDeceleration,
+		MinCoastSpeed: constants.MinCoastingSpeed,
 	}
 }
+
+// Drivers are the driver behaviours a simulation can be run with, by name.
+var Drivers = plugins.NewRegistry[DriverBehavior]("driver")
+
+func init() {
+	fixed := func(name, description string, driver DriverBehavior) plugins.Plugin[DriverBehavior] {
+		return plugins.Plugin[DriverBehavior]{
+			Name:        name,
+			Description: description,
+			New:         func(plugins.Values) (DriverBehavior, error) { return driver, nil },
+		}
+	}
+	Drivers.Register(fixed("eco", "keeps to the schedule, at most 70% of line speed when late, gentle acceleration and braking", EcoDriver{}))
+	Drivers.Register(fixed("intermediate", "keeps to the schedule, at most 70% of line speed when late, firmer acceleration and braking", IntermediateDriver{}))
+	Drivers.Register(fixed("crazy", "keeps to the schedule, up to line speed when late, firm acceleration and braking", CrazyDriver{}))
+	Drivers.Register(fixed("very_crazy", "keeps to the schedule, up to 20% over line speed when late, full acceleration and braking", VeryCrazyDriver{}))
+	Drivers.Register(fixed("soigneux", "keeps to the schedule, up to line speed when late, gentle acceleration and braking", SoigneuxDriver{}))
+	Drivers.Register(plugins.Plugin[DriverBehavior]{
+		Name:        "adaptive",
+		Description: "eco when early, pushing towards line speed as the delay grows, coasting before stops reached in time",
+		Params: []plugins.Param{
+			{Name: "coastDistanceM", Type: plugins.Number, Description: "meters before a stop to start coasting, 0 never to coast", Default: 4000.0, Min: 0, Max: 50000},
+			{Name: "coastMinSlackMin", Type: plugins.Number, Description: "minutes to spare needed to coast", Default: 1.0, Min: 0, Max: 60},
+		},
+		New: func(params plugins.Values) (DriverBehavior, error) {
+			driver := NewAdaptiveDriver()
+			driver.CoastDistance = params.Number("coastDistanceM")
+			driver.CoastMinSlack = minutes(params.Number("coastMinSlackMin"))
+			return driver, nil
+		},
+	})
+	Drivers.Register(plugins.Plugin[DriverBehavior]{
+		Name:        "file",
+		Description: "parametric driver reading its lateness curve from a JSON file",
+		Params: []plugins.Param{
+			{Name: "path", Type: plugins.String, Description: "driver curve file (see examples/driver.json)", Default: "", Required: true},
+		},
+		New: func(params plugins.Values) (DriverBehavior, error) {
+			driver, err := LoadParametricDriver(params.String("path"))
+			if err != nil {
+				return nil, err
+			}
+			return driver, nil
+		},
+	})
+	Drivers.Register(fixed("das", "follows the speed profiles of the Driver Advisory System", DASDriver{}))
+}
+
+// NewDriverBehavior builds a registered driver from its parameters.
+func NewDriverBehavior(name string, params map[string]any) (DriverBehavior, error) {
+	return Drivers.New(name, params)
+}
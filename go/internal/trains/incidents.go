@@ -0,0 +1,55 @@
+package trains
+
+import (
+	"math/rand"
+
+	"ai30-project/internal/events"
+)
+
+// GenerateEvents draws the event timeline of the train from the model of its
+// group, drawing from rng. It must be called before Run.
+func (t *Train) GenerateEvents(models *events.ModelSet, rng *rand.Rand) {
+	eventStops := make([]events.Stop, len(t.stops))
+	for i, stop := range t.stops {
+		eventStops[i] = events.Stop{StationID: stop.stationID, Arrival: stop.arrival, Departure: stop.departure}
+	}
+
+	model := models.For(events.Profile{
+		Line:      t.Line(),
+		Category:  t.Category(),
+		Departure: t.StartStop().departure,
+	})
+	t.events = events.GenerateTimeline(model, eventStops, rng)
+}
+
+// AnchorIncidents places the infrastructure delays of the train on a segment
+// of the leg it runs when they start, given the segments between two stations.
+// It returns the anchored delays, which then hold every train crossing the
+// segment. Delays that cannot be placed keep following the train. It must be
+// called before Run.
+func (t *Train) AnchorIncidents(path func(fromStationID, toStationID string) ([]string, error), rng *rand.Rand) []events.DelayEvent {
+	var incidents []events.DelayEvent
+
+	for i, e := range t.events {
+		delay, ok := e.(events.DelayEvent)
+		if !ok || delay.Cause != events.DelayCauseInfrastructure || !delay.FollowsTrain() {
+			continue
+		}
+
+		leg := 0
+		for leg+2 < len(t.stops) && t.stops[leg+1].departure <= delay.StartTime {
+			leg++
+		}
+
+		segmentIDs, err := path(t.stops[leg].stationID, t.stops[leg+1].stationID)
+		if err != nil || len(segmentIDs) == 0 {
+			continue
+		}
+
+		delay.SegmentID = segmentIDs[rng.Intn(len(segmentIDs))]
+		t.events[i] = delay
+		incidents = append(incidents, delay)
+	}
+
+	return incidents
+}
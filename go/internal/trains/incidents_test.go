@@ -0,0 +1,64 @@
+package trains
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"ai30-project/internal/events"
+)
+
+// TestAnchorIncidents checks infrastructure delays are placed on the leg the
+// train runs when they start, and the other delays are left alone.
+func TestAnchorIncidents(t *testing.T) {
+	train := NewTrain("T", []*TrainStop{
+		NewTrainStop("A", 8*time.Hour, 8*time.Hour),
+		NewTrainStop("B", 8*time.Hour+30*time.Minute, 8*time.Hour+35*time.Minute),
+		NewTrainStop("C", 9*time.Hour, 9*time.Hour),
+	})
+	train.events = events.Timeline{
+		events.DelayEvent{Cause: events.DelayCauseInfrastructure, Duration: 10 * time.Minute, StartTime: 8*time.Hour + 10*time.Minute},
+		events.DelayEvent{Cause: events.DelayCauseInfrastructure, Duration: 10 * time.Minute, StartTime: 8*time.Hour + 40*time.Minute},
+		events.DelayEvent{Cause: events.DelayCauseRollingStock, Duration: 10 * time.Minute, StartTime: 8*time.Hour + 10*time.Minute},
+		events.DelayEvent{Cause: events.DelayCauseInfrastructure, Duration: 10 * time.Minute, StartTime: 8 * time.Hour, SegmentID: "A-X"},
+	}
+	routes := map[string][]string{
+		"A-B": {"A-X", "X-B"},
+		"B-C": {"B-C"},
+	}
+	path := func(fromStationID, toStationID string) ([]string, error) {
+		if segmentIDs, ok := routes[fromStationID+"-"+toStationID]; ok {
+			return segmentIDs, nil
+		}
+		return nil, fmt.Errorf("no path from %s to %s", fromStationID, toStationID)
+	}
+
+	incidents := train.AnchorIncidents(path, rand.New(rand.NewSource(42)))
+
+	if len(incidents) != 2 {
+		t.Fatalf("%d incidents anchored, want the 2 following the train", len(incidents))
+	}
+	if !slices.Contains(routes["A-B"], incidents[0].SegmentID) {
+		t.Errorf("incident starting between A and B anchored on %s", incidents[0].SegmentID)
+	}
+	if incidents[1].SegmentID != "B-C" {
+		t.Errorf("incident starting between B and C anchored on %s", incidents[1].SegmentID)
+	}
+	for i, want := range []string{incidents[0].SegmentID, incidents[1].SegmentID, "", "A-X"} {
+		if got := train.events[i].(events.DelayEvent).SegmentID; got != want {
+			t.Errorf("event %d anchored on %q, want %q", i, got, want)
+		}
+	}
+
+	// Without a route, incidents keep following the train
+	train.events = events.Timeline{events.DelayEvent{Cause: events.DelayCauseInfrastructure, Duration: 10 * time.Minute, StartTime: 8 * time.Hour}}
+	noRoute := func(fromStationID, toStationID string) ([]string, error) { return nil, fmt.Errorf("no route") }
+	if incidents := train.AnchorIncidents(noRoute, rand.New(rand.NewSource(42))); len(incidents) != 0 {
+		t.Errorf("incidents %v anchored without a route", incidents)
+	}
+	if !train.events[0].(events.DelayEvent).FollowsTrain() {
+		t.Error("unplaced incident no longer follows the train")
+	}
+}
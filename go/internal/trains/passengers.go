@@ -0,0 +1,119 @@
+package trains
+
+import (
+	"ai30-project/internal/passengers"
+	"fmt"
+	"time"
+)
+
+// SetCapacity replaces the default capacity with the one of the rolling stock.
+func (t *Train) SetCapacity(seats, standing int) {
+	t.seatCapacity = seats
+	t.standingCapacity = standing
+}
+
+// Capacity is the maximum number of passengers on board, seated or standing.
+func (t *Train) Capacity() int {
+	return t.seatCapacity + t.standingCapacity
+}
+
+// OnBoard is the number of passengers on the train.
+func (t *Train) OnBoard() int {
+	return len(t.onBoard) + len(t.overcarried)
+}
+
+// Passengers returns every passenger the train has carried, on board or alighted.
+func (t *Train) Passengers() []*passengers.Passenger {
+	all := make([]*passengers.Passenger, 0, len(t.onBoard)+len(t.alighted))
+	all = append(all, t.onBoard...)
+	return append(all, t.alighted...)
+}
+
+func (t *Train) alightPassengers(stationID string, currentTime time.Duration) {
+	staying := t.onBoard[:0]
+	alighted := 0
+
+	for _, p := range t.overcarried {
+		p.Alight(currentTime)
+		t.alighted = append(t.alighted, p)
+		alighted++
+	}
+	t.overcarried = nil
+
+	for _, p := range t.onBoard {
+		if p.Destination == stationID {
+			p.Alight(currentTime)
+			t.alighted = append(t.alighted, p)
+			alighted++
+		} else {
+			staying = append(staying, p)
+		}
+	}
+	t.onBoard = staying
+
+	if alighted > 0 {
+		fmt.Printf("  [Train %s] %d passengers alighted at station %s\n", t.id, alighted, stationID)
+	}
+}
+
+// overcarryPassengers keeps on board the passengers bound for a station the
+// train runs through; they alight at the next stop.
+func (t *Train) overcarryPassengers(stationID string) {
+	staying := t.onBoard[:0]
+	for _, p := range t.onBoard {
+		if p.Destination == stationID {
+			t.overcarried = append(t.overcarried, p)
+		} else {
+			staying = append(staying, p)
+		}
+	}
+	t.onBoard = staying
+
+	if len(t.overcarried) > 0 {
+		fmt.Printf("  [Train %s] %d passengers carried past skipped station %s\n", t.id, len(t.overcarried), stationID)
+	}
+}
+
+// boardPassengers picks up the passengers waiting at the current stop whose
+// destination is one of the remaining stops, within the train capacity.
+func (t *Train) boardPassengers(stop *TrainStop, currentTime time.Duration) {
+	serves := make(map[string]time.Duration)
+	for _, next := range t.stops {
+		if next.arrivedAt != nil || t.events.Skips(next.stationID, currentTime) {
+			continue
+		}
+		serves[next.stationID] = next.arrival
+		if t.events.ShortTurnsAt(next.stationID, currentTime) {
+			break
+		}
+	}
+
+	response, err := t.requestBoarding(stop.stationID, currentTime, t.Capacity()-len(t.onBoard), serves)
+	if err != nil {
+		fmt.Printf("  [Train %s] ERROR: Boarding passengers: %v\n", t.id, err)
+		return
+	}
+
+	t.onBoard = append(t.onBoard, response.Passengers...)
+}
+
+// dropOffPassengers leaves every passenger on board at the station, where they
+// wait for another train.
+func (t *Train) dropOffPassengers(stationID string) {
+	if len(t.onBoard) == 0 {
+		return
+	}
+
+	t.notifyPassengerDropOff(stationID, t.onBoard)
+	t.onBoard = nil
+}
+
+func (t *Train) recordLeg(from, to *TrainStop, departedAt time.Duration) {
+	t.legs = append(t.legs, passengers.LegLoad{
+		From:       from.stationID,
+		To:         to.stationID,
+		DepartedAt: departedAt,
+		OnBoard:    len(t.onBoard),
+		Capacity:   t.Capacity(),
+	})
+}
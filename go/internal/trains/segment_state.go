@@ -2,10 +2,13 @@ package trains
 
 import (
 	"ai30-project/internal/constants"
+	"ai30-project/internal/das"
 	"ai30-project/internal/events"
 	"ai30-project/internal/navigation"
+	"ai30-project/internal/propagation"
 	"fmt"
 	"math"
+	"slices"
 	"time"
 )
 
@@ -20,16 +23,30 @@ type onSegmentState struct {
 	position     float64 // meters
 	speed        float64 // m/s
 	announced    bool
+	waiting      bool
+	waitingSince time.Duration
 
 	// From percept
 	delay               time.Duration
 	trainAhead          *trainAheadInfo
+	restriction         float64 // m/s, segment or rolling-stock speed cap, 0 when none
+	tractionShare       float64 // of the tractive effort, below 1 on a short power supply
 	destinationDistance float64 // meters
 	remainingTime       time.Duration
-	isDelayed           bool
+	delayEffect         events.Effect // empty when no delay is active
+	delayCause          events.DelayCause
+	incident            bool // stopped by an infrastructure incident on the segment
+
+	// Driver Advisory System, for a driver following it
+	profile      *das.Profile
+	profileStart float64 // meters traveled when the profile was computed
+	advice       *das.Advice
+	replan       bool // delayed or held since the profile was computed
 
 	// From deliberate
 	targetSpeed float64 // m/s
+	startSpeed  float64 // m/s, before the driver acted
+	powerDemand float64 // W, drawn with the full tractive effort
 }
 
 func newOnSegmentState(segments []navigation.SegmentInfo) *onSegmentState {
@@ -59,14 +76,41 @@ func (s *onSegmentState) traveledDistance() float64 {
 	return traveled
 }
 
+// fastestRunningTime is how long the rest of the path takes at line speed.
+func (s *onSegmentState) fastestRunningTime() time.Duration {
+	seconds := 0.0
+	for i, seg := range s.segments[s.currentIndex:] {
+		if seg.LineSpeed() <= 0 {
+			continue
+		}
+		length := seg.Length
+		if i == 0 {
+			length -= s.position
+		}
+		seconds += length / seg.LineSpeed()
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// slack is how much time the train could lose and still arrive on time
+// running the rest of the path at line speed.
+func (s *onSegmentState) slack() time.Duration {
+	return s.remainingTime - s.fastestRunningTime()
+}
+
 func (s *onSegmentState) percept(train *Train, currentTime time.Duration) {
+	if len(train.closures) > 0 {
+		s.avoidClosures(train, currentTime)
+	}
+
 	seg := s.currentSegment()
 
-	trainAheadResp, err := train.getTrainAhead(seg.ID, s.position)
+	trainAheadResp, err := train.getTrainAhead(seg.ID, s.position, currentTime)
 	if err != nil {
 		fmt.Printf("  [Train %s] ERROR: Getting train ahead: %v\n", train.id, err)
 		s.trainAhead = nil
-	} else if trainAheadResp.HasTrainAhead {
+		s.restriction = 0
+	} else if s.restriction = trainAheadResp.SpeedLimit; trainAheadResp.HasTrainAhead {
 		s.trainAhead = &trainAheadInfo{
 			position: trainAheadResp.Position,
 			speed:    trainAheadResp.Speed,
@@ -94,23 +138,79 @@ func (s *onSegmentState) percept(train *Train, currentTime time.Duration) {
 		s.remainingTime = time.Duration(1.0 * float64(time.Second))
 	}
 
-	// Event
-	delay, isDelay := train.event.(events.DelayEvent)
-	s.isDelayed = isDelay && delay.IsActive(currentTime)
+	// Events
+	s.delayEffect, s.delayCause, s.incident = "", "", false
+	if delay, isDelayed := train.events.ActiveDelay(currentTime); isDelayed {
+		s.delayEffect, s.delayCause = delay.Cause.Effect(), delay.Cause
+	}
+	if err == nil && trainAheadResp.Incident && s.delayEffect != events.EffectEmergencyStop {
+		s.delayEffect, s.delayCause, s.incident = events.DelayCauseInfrastructure.Effect(), events.DelayCauseInfrastructure, true
+	}
+	if maxSpeed, isReduced := train.events.MaxSpeed(currentTime); isReduced && (s.restriction == 0 || maxSpeed < s.restriction) {
+		s.restriction = maxSpeed
+	}
+
+	if driver, ok := train.driver.(AdvisedDriver); ok {
+		s.advise(train, driver, currentTime)
+	}
+
+	// Share of the tractive effort the power supply provides
+	share, err := train.requestTractionShare(seg.ID, currentTime)
+	if err != nil {
+		fmt.Printf("  [Train %s] ERROR: Requesting traction share: %v\n", train.id, err)
+	}
+	s.tractionShare = share
+}
+
+// advise reads the advice of the profile at the current position, computing
+// the profile again when starting the run, or once the train moves again
+// after it was delayed or held.
+func (s *onSegmentState) advise(train *Train, driver AdvisedDriver, currentTime time.Duration) {
+	if s.profile == nil || (s.replan && !s.waiting) {
+		profile := das.Compute(s.sections(), s.speed, s.remainingTime, driver.Performance())
+		s.profile, s.profileStart, s.replan = &profile, s.traveledDistance(), false
+		fmt.Printf("  [Train %s] DAS profile: cruise=%.1f m/s, coast from %.0f m, running time %v (on time: %v) at %v\n",
+			train.id, profile.Cruise, profile.CoastFrom, profile.RunningTime, profile.OnTime, currentTime)
+	}
+
+	advice := s.profile.Advise(s.traveledDistance()-s.profileStart, s.speed, driver.Performance())
+	s.advice = &advice
+}
+
+// sections is the rest of the path as the Driver Advisory System sees it.
+func (s *onSegmentState) sections() []das.Section {
+	sections := make([]das.Section, 0, len(s.segments)-s.currentIndex)
+	for i, seg := range s.segments[s.currentIndex:] {
+		length := seg.Length
+		if i == 0 {
+			length -= s.position
+		}
+		sections = append(sections, das.Section{Length: length, MaxSpeed: seg.LineSpeed()})
+	}
+	return sections
 }
 
 func (s *onSegmentState) deliberate(train *Train, currentTime time.Duration) {
 	seg := s.currentSegment()
 	dt := time.Duration(60) * time.Second
-	acceleration := train.driver.GetCommand(s.delay).DesiredAccel * constants.MaxAcceleration
-	service_brake := train.driver.GetCommand(s.delay).DesiredDecel * constants.MaxServiceBrake
+	command := train.driver.GetCommand(DriverSituation{
+		Delay:          s.delay,
+		Slack:          s.slack(),
+		DistanceToStop: s.destinationDistance,
+		Advice:         s.advice,
+	})
+	acceleration := command.DesiredAccel * constants.MaxAcceleration
+	service_brake := command.DesiredDecel * constants.MaxServiceBrake
 
 	// Calculate normal speed
-	normalSpeed := (s.destinationDistance / float64(s.remainingTime.Seconds())) / train.driver.GetCommand(s.delay).DesiredSpeed
+	normalSpeed := (s.destinationDistance / float64(s.remainingTime.Seconds())) / command.DesiredSpeed
+	if command.TargetSpeed > 0 {
+		normalSpeed = command.TargetSpeed
+	}
 
 	// Calculate safety speed
 	safetySpeed := 1e6
-	if s.isDelayed {
+	if s.delayEffect == events.EffectServiceStop || s.delayEffect == events.EffectEmergencyStop {
 		safetySpeed = 0.0
 	} else if s.trainAhead != nil {
 		// No train ahead: set a very large safety speed so it does not constrain
@@ -118,7 +218,9 @@ func (s *onSegmentState) deliberate(train *Train, currentTime time.Duration) {
 		if safetyDistance < 0 {
 			safetyDistance = 0
 		}
-		safetySpeed = math.Sqrt(2 * math.Abs(service_brake) * safetyDistance)
+		// Nor may the train run into the margin before the next tick,
+		// should the train ahead stop meanwhile
+		safetySpeed = math.Min(math.Sqrt(2*math.Abs(service_brake)*safetyDistance), safetyDistance/dt.Seconds())
 	}
 
 	// Calculate station speed limit
@@ -128,21 +230,59 @@ func (s *onSegmentState) deliberate(train *Train, currentTime time.Duration) {
 	// Determine target speed
 	s.targetSpeed = math.Min(normalSpeed, math.Min(safetySpeed, stationSpeedLimit))
 
+	// The profile no longer holds once the train is held below its speed. One
+	// already at line speed cannot do better.
+	if s.advice != nil && s.profile.OnTime {
+		held := safetySpeed
+		if s.restriction > 0 {
+			held = math.Min(held, s.restriction)
+		}
+		if s.delayEffect != "" || held < math.Min(s.advice.Speed, s.speed) {
+			s.replan = true
+		}
+	}
+
 	// Ensure target speed does not exceed max speed
 	if s.targetSpeed > seg.MaxSpeed {
 		s.targetSpeed = seg.MaxSpeed
 	}
 
-	driverSpeed := s.targetSpeed * train.driver.GetCommand(s.delay).DesiredSpeed
+	// Apply the temporary speed restriction of the segment or the train
+	if s.restriction > 0 && s.targetSpeed > s.restriction {
+		s.targetSpeed = s.restriction
+	}
+
+	// A rolling-stock fault degrades running without stopping the train
+	if s.delayEffect == events.EffectSlowdown {
+		s.targetSpeed *= constants.DegradedSpeedFactor
+	}
+
+	// Apply the speed ordered by the dispatcher
+	if limit, ok := train.dispatcherSpeedLimit(currentTime); ok && s.targetSpeed > limit {
+		s.targetSpeed = limit
+	}
+
+	driverSpeed := s.targetSpeed * command.DesiredSpeed
 	fmt.Printf("	[Train %s] Segment %s: pos=%.1f m, speed=%.1f m/s, target=%.1f m/s, driver=%.1f m/s, delay=%v, rem.time=%v, train.ahead=%v\n",
 		train.id, seg.ID, s.position, s.speed, s.targetSpeed, driverSpeed, s.delay, s.remainingTime, s.trainAhead)
 
-	if s.isDelayed {
+	// A coasting train keeps traction below the speed it needs to get going
+	coasting := command.Coast && s.speed >= constants.MinCoastingSpeed
+	s.startSpeed = s.speed
+	wanted := 0.0 // speed reached with the full tractive effort, 0 without traction
+
+	if s.delayEffect == events.EffectEmergencyStop {
 		s.speed += constants.EmergencyBrake * dt.Seconds()
-	} else if s.speed < driverSpeed {
-		s.speed += acceleration * dt.Seconds()
 	} else if s.speed > driverSpeed {
-		s.speed += service_brake * dt.Seconds()
+		// Braking stops at the speed the driver aims for
+		s.speed = math.Max(driverSpeed, s.speed+service_brake*dt.Seconds())
+	} else if coasting {
+		s.speed += constants.CoastingDeceleration * dt.Seconds()
+	} else if s.speed < driverSpeed {
+		wanted = math.Min(driverSpeed, s.speed+acceleration*dt.Seconds())
+		s.speed += acceleration * s.tractionShare * dt.Seconds()
+	} else {
+		wanted = s.speed
 	}
 
 	if s.speed > driverSpeed {
@@ -150,6 +290,8 @@ func (s *onSegmentState) deliberate(train *Train, currentTime time.Duration) {
 	} else if s.speed < 0 {
 		s.speed = 0
 	}
+
+	s.powerDemand = train.tractionPower(s.startSpeed, wanted, dt)
 }
 
 func (s *onSegmentState) act(train *Train, currentTime time.Duration) {
@@ -157,18 +299,51 @@ func (s *onSegmentState) act(train *Train, currentTime time.Duration) {
 	dt := time.Duration(60) * time.Second
 	seg := s.currentSegment()
 
+	if train.cancelOrdered {
+		s.leaveLine(train, currentTime)
+		return
+	}
+
+	// book the energy of the tick once the distance run is known
+	start := s.traveledDistance()
+	defer func() {
+		train.consume(seg.ID, s.startSpeed, s.speed, s.traveledDistance()-start)
+	}()
+
 	// advance position and notify controller
 	s.position += s.speed * dt.Seconds()
 	train.notifySegmentPosition(seg.ID, s.position, s.speed)
+	train.notifyPowerDemand(seg.ID, s.powerDemand, currentTime)
+
+	// Record a minute stopped on the line by an incident or a delay
+	if s.speed == 0 && (s.delayEffect == events.EffectServiceStop || s.delayEffect == events.EffectEmergencyStop) {
+		if s.incident {
+			train.notifyWait(propagation.ReasonIncident, string(s.delayCause), "", seg.ID, currentTime)
+		} else {
+			train.notifyWait(propagation.ReasonDelayEvent, string(s.delayCause), "", seg.ID, currentTime)
+		}
+	}
 
 	// Helper to clamp position to the last meter of the segment when waiting
+	wasWaiting := s.waiting
+	s.waiting = false
 	setWaitingAtSegmentEnd := func() {
 		s.position = seg.Length - 1
+		train.notifySegmentPosition(seg.ID, s.position, s.speed)
+		s.waiting = true
+		s.replan = true
+		if !wasWaiting {
+			s.waitingSince = currentTime
+		}
 	}
 
 	// Prepare for entering the station: when near the end of final segment and not yet announced
 	isApproachingStation := s.position >= 0.9*seg.Length && s.currentIndex+1 >= len(s.segments) && !s.announced
 	if isApproachingStation {
+		// The train cannot run past the segment end before the station knows of it
+		if s.position >= seg.Length {
+			setWaitingAtSegmentEnd()
+		}
 		nextStop := train.NextStop()
 		// defensive check: if there's no next stop, nothing to demand
 		if nextStop == nil {
@@ -212,6 +387,13 @@ func (s *onSegmentState) act(train *Train, currentTime time.Duration) {
 			// rollback index and wait at end of current segment
 			s.currentIndex--
 			setWaitingAtSegmentEnd()
+			if response.BlockingTrainID != "" {
+				train.notifyWait(propagation.ReasonTrainAhead, "", response.BlockingTrainID, nextSeg.ID, currentTime)
+			}
+			train.setBlocked(nextSeg.ID, response.BlockingTrainID)
+			if train.rerouteRequested {
+				s.reroute(train, currentTime, nextSeg.ID)
+			}
 			return
 		}
 
@@ -220,6 +402,14 @@ func (s *onSegmentState) act(train *Train, currentTime time.Duration) {
 			// rollback index and wait
 			s.currentIndex--
 			setWaitingAtSegmentEnd()
+			if response.Closed {
+				train.notifyWait(propagation.ReasonClosure, "", "", nextSeg.ID, currentTime)
+			} else {
+				train.notifyWait(propagation.ReasonTrainAhead, "", response.BlockingTrainID, nextSeg.ID, currentTime)
+			}
+			if response.Closed || train.rerouteRequested {
+				s.reroute(train, currentTime, nextSeg.ID)
+			}
 			return
 		}
 
@@ -244,12 +434,82 @@ func (s *onSegmentState) act(train *Train, currentTime time.Duration) {
 
 	if response.Allowed {
 		s.announced = false
+		s.speed = 0 // stopped at the platform
 		train.notifySegmentExit(seg.ID)
 		nextStop.SetArrivedAt(currentTime)
+		if nextStop != train.EndStop() && train.events.Skips(nextStop.stationID, currentTime) {
+			nextStop.SetSkipped()
+			train.overcarryPassengers(nextStop.stationID)
+		} else {
+			train.alightPassengers(nextStop.stationID, currentTime)
+			train.notifyConnectionArrival(nextStop.stationID, currentTime)
+		}
+		train.notifyCrewArrival(nextStop.stationID, currentTime)
 		train.state = newAtStationState()
 		fmt.Printf("  [Train %s] Entered station %s at %v\n", train.id, nextStop.stationID, currentTime)
 	} else {
 		fmt.Printf("  [Train %s] Cannot enter station %s (capacity full)\n", train.id, nextStop.stationID)
 		setWaitingAtSegmentEnd()
+		train.notifyWait(propagation.ReasonStationFull, "", response.BlockingTrainID, nextStop.stationID, currentTime)
+		train.setBlocked(nextStop.stationID, response.Holders...)
+	}
+}
+
+// leaveLine takes the train out of service where it stands: it releases the
+// segment and its entry demand, and its passengers are brought to the end of
+// the segment.
+func (s *onSegmentState) leaveLine(train *Train, currentTime time.Duration) {
+	seg := s.currentSegment()
+
+	train.isFinished = true
+	train.notifySegmentExit(seg.ID)
+	if nextStop := train.NextStop(); nextStop != nil && s.announced {
+		train.notifyStationDeparture(nextStop.stationID)
+	}
+	train.dropOffPassengers(seg.ToStationID)
+	train.notifyCirculationFinish(seg.ToStationID, currentTime)
+	train.notifyCrewCancellation(currentTime)
+	fmt.Printf("  [Train %s] TAKEN OUT OF SERVICE on segment %s at %v\n", train.id, seg.ID, currentTime)
+}
+
+// avoidClosures reroutes the train when the rest of its path crosses a
+// segment announced closed. The train may still run to the end of a closed
+// segment it is already on.
+func (s *onSegmentState) avoidClosures(train *Train, currentTime time.Duration) {
+	closures := train.closures
+	train.closures = nil
+
+	for _, ahead := range s.segments[s.currentIndex+1:] {
+		if slices.Contains(closures, ahead.ID) {
+			s.reroute(train, currentTime, closures...)
+			return
+		}
 	}
 }
+
+// reroute replaces the rest of the path with a new one from the end of the
+// current segment to the next stop, off the closed segments and those to
+// avoid.
+func (s *onSegmentState) reroute(train *Train, currentTime time.Duration, avoid ...string) {
+	seg := s.currentSegment()
+	nextStop := train.NextStop()
+	if nextStop == nil || s.currentIndex+1 >= len(s.segments) {
+		return
+	}
+
+	path, err := train.requestPath(seg.ToStationID, nextStop.stationID, avoid...)
+	if err != nil || len(path.Segments) == 0 {
+		fmt.Printf("  [Train %s] ERROR: Rerouting from %s: %v\n", train.id, seg.ToStationID, err)
+		return
+	}
+
+	train.recordDiversion(seg.ToStationID, path, currentTime)
+
+	segments := make([]navigation.SegmentInfo, 0, s.currentIndex+1+len(path.Segments))
+	segments = append(segments, s.segments[:s.currentIndex+1]...)
+	s.segments = append(segments, path.Segments...)
+	s.replan = true
+	train.rerouteRequested = false
+	fmt.Printf("  [Train %s] Rerouted from %s to %s via %d segments at %v\n",
+		train.id, seg.ToStationID, nextStop.stationID, len(path.Segments), currentTime)
+}
@@ -0,0 +1,48 @@
+package trains
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"ai30-project/internal/constants"
+	"ai30-project/internal/das"
+	"ai30-project/internal/navigation"
+)
+
+// TestSlack checks the slack on a one-segment route run at 216 km/h, 60 m/s.
+func TestSlack(t *testing.T) {
+	s := newOnSegmentState([]navigation.SegmentInfo{
+		{ID: "A-B", FromStationID: "A", ToStationID: "B", Length: 60000, MaxSpeed: 216 * constants.KmHToMPerMin},
+	})
+	s.position = 12000
+	s.remainingTime = 20 * time.Minute
+
+	if got, want := s.fastestRunningTime(), 800*time.Second; got.Round(time.Millisecond) != want {
+		t.Errorf("fastest running time = %v, want %v", got, want)
+	}
+	if got, want := s.slack(), 400*time.Second; got.Round(time.Millisecond) != want {
+		t.Errorf("slack = %v, want %v", got, want)
+	}
+}
+
+// TestAdviceKeepsSectionLimit checks a late train is advised line speed,
+// and no more than the limit of the 108 km/h section it runs on.
+func TestAdviceKeepsSectionLimit(t *testing.T) {
+	s := newOnSegmentState([]navigation.SegmentInfo{
+		{ID: "A-B", FromStationID: "A", ToStationID: "B", Length: 20000, MaxSpeed: 108 * constants.KmHToMPerMin},
+		{ID: "B-C", FromStationID: "B", ToStationID: "C", Length: 40000, MaxSpeed: 216 * constants.KmHToMPerMin},
+	})
+	perf := DASDriver{}.Performance()
+
+	profile := das.Compute(s.sections(), 0, time.Minute, perf)
+	if profile.OnTime {
+		t.Fatalf("profile keeps one minute for 60 km")
+	}
+	if want := 60.0; math.Abs(profile.Cruise-want) > 1e-9 {
+		t.Errorf("cruise = %.1f m/s, want line speed %.1f m/s", profile.Cruise, want)
+	}
+	if advice := profile.Advise(5000, 30, perf); advice.Speed > 30+1e-9 {
+		t.Errorf("advised %.1f m/s on the 30 m/s section", advice.Speed)
+	}
+}
@@ -2,13 +2,17 @@ package trains
 
 import (
 	"ai30-project/internal/events"
+	"ai30-project/internal/propagation"
 	"fmt"
 	"time"
 )
 
 type atStationState struct {
 	// From deliberate
-	action string
+	action          string
+	holdReason      string
+	delayCause      events.DelayCause
+	blockingTrainID string
 }
 
 func newAtStationState() *atStationState {
@@ -20,26 +24,85 @@ func (s *atStationState) percept(train *Train, currentTime time.Duration) {}
 func (s *atStationState) deliberate(train *Train, currentTime time.Duration) {
 	currentStop := train.CurrentStop()
 	endStop := train.EndStop()
+	s.blockingTrainID = ""
 
 	if currentStop == endStop {
 		s.action = "FINISH"
 		return
 	}
 
-	cancellation, isCancellation := train.event.(events.CancellationEvent)
-	if isCancellation && cancellation.IsActive(currentTime) {
+	if train.cancelOrdered || train.events.IsCancelled(currentTime) {
 		s.action = "CANCEL"
 		return
 	}
 
-	if currentTime < currentStop.departure {
+	if train.events.ShortTurnsAt(currentStop.stationID, currentTime) {
+		s.action = "SHORT_TURN"
+		return
+	}
+
+	// A skipped stop has no dwell: the train leaves as soon as it may
+	if currentTime < currentStop.departure && !currentStop.skipped {
 		s.action = "WAIT"
 		return
 	}
 
-	delay, isDelay := train.event.(events.DelayEvent)
-	if isDelay && delay.IsActive(currentTime) {
+	if currentStop == train.StartStop() {
+		trainset, err := train.requestTrainsetReady(currentStop.stationID, currentStop.departure, currentTime)
+		if err != nil {
+			fmt.Printf("  [Train %s] ERROR: Checking trainset: %v\n", train.id, err)
+		} else if trainset.Cancel {
+			s.action = "CANCEL"
+			return
+		} else if !trainset.Ready {
+			s.action = "HOLD"
+			s.holdReason = "turnaround"
+			s.blockingTrainID = trainset.PreviousTrainID
+			return
+		}
+	}
+
+	crewReady, err := train.requestCrewReady(currentStop.stationID, currentStop.departure, currentTime)
+	if err != nil {
+		fmt.Printf("  [Train %s] ERROR: Checking crew: %v\n", train.id, err)
+	} else if !crewReady.Ready {
+		s.action = "HOLD"
+		s.holdReason = "crew"
+		s.blockingTrainID = crewReady.PreviousTrainID
+		return
+	}
+
+	if delay, isDelayed := train.events.ActiveDelay(currentTime); isDelayed {
 		s.action = "DELAYED"
+		s.delayCause = delay.Cause
+		return
+	}
+
+	// Station and passenger incidents extend the dwell at their stop
+	if extra, cause := train.events.ExtraDwell(currentStop.stationID); extra > 0 && currentTime < currentStop.earliestDeparture()+extra {
+		s.action = "DELAYED"
+		s.delayCause = cause
+		return
+	}
+
+	if train.isHeldByDispatcher(currentTime) {
+		s.action = "HOLD"
+		s.holdReason = "dispatcher"
+		return
+	}
+
+	if currentStop.skipped {
+		s.action = "DEPART"
+		return
+	}
+
+	hold, err := train.requestConnectionHold(currentStop.stationID, currentStop.departure, currentTime)
+	if err != nil {
+		fmt.Printf("  [Train %s] ERROR: Checking connections: %v\n", train.id, err)
+	} else if hold.Hold {
+		s.action = "HOLD"
+		s.holdReason = "connection"
+		s.blockingTrainID = hold.FeederTrainID
 		return
 	}
 
@@ -53,22 +116,38 @@ func (s *atStationState) act(train *Train, currentTime time.Duration) {
 	case "FINISH":
 		train.isFinished = true
 		train.notifyStationDeparture(currentStop.stationID)
+		train.notifyCirculationFinish(currentStop.stationID, currentTime)
+		train.notifyTracerFinish(*currentStop.arrivedAt - currentStop.arrival)
 		fmt.Printf("  [Train %s] Reached end of journey at station %s at %v\n", train.id, currentStop.stationID, currentTime)
 		return
 
-	case "CANCEL":
+	case "CANCEL", "SHORT_TURN":
 		train.isFinished = true
+		train.dropOffPassengers(currentStop.stationID)
 		train.notifyStationDeparture(currentStop.stationID)
-		fmt.Printf("  [Train %s] CANCELLED at station %s at %v\n", train.id, currentStop.stationID, currentTime)
+		train.notifyCirculationFinish(currentStop.stationID, currentTime)
+		train.notifyCrewCancellation(currentTime)
+		if s.action == "SHORT_TURN" {
+			fmt.Printf("  [Train %s] SHORT-TURNED at station %s at %v\n", train.id, currentStop.stationID, currentTime)
+		} else {
+			fmt.Printf("  [Train %s] CANCELLED at station %s at %v\n", train.id, currentStop.stationID, currentTime)
+		}
 		return
 
 	case "WAIT":
 		return
 
 	case "DELAYED":
+		train.notifyWait(propagation.ReasonDelayEvent, string(s.delayCause), "", currentStop.stationID, currentTime)
 		fmt.Printf("  [Train %s] DELAYED at station %s\n", train.id, currentStop.stationID)
 		return
 
+	case "HOLD":
+		train.holds[s.holdReason] += time.Minute
+		train.notifyWait(propagation.Reason(s.holdReason), "", s.blockingTrainID, currentStop.stationID, currentTime)
+		fmt.Printf("  [Train %s] HELD at station %s (%s)\n", train.id, currentStop.stationID, s.holdReason)
+		return
+
 	case "DEPART":
 		nextStop := train.NextStop()
 
@@ -86,17 +165,34 @@ func (s *atStationState) act(train *Train, currentTime time.Duration) {
 		firstSegment := path.Segments[0]
 		response, err := train.requestSegmentEntry(firstSegment.ID, currentTime)
 		if err != nil {
+			if response.BlockingTrainID != "" {
+				train.notifyWait(propagation.ReasonTrainAhead, "", response.BlockingTrainID, firstSegment.ID, currentTime)
+			}
+			train.setBlocked(firstSegment.ID, response.BlockingTrainID)
 			fmt.Printf("  [Train %s] ERROR: Requesting segment entry: %v\n", train.id, err)
 			return
 		}
 
 		if response.Allowed {
+			train.rerouteRequested = false
+			train.closures = nil
+			train.recordDiversion(currentStop.stationID, path, currentTime)
+			if !currentStop.skipped {
+				train.boardPassengers(currentStop, currentTime)
+			}
+			train.recordLeg(currentStop, nextStop, currentTime)
 			train.notifyStationDeparture(currentStop.stationID)
+			train.notifyConnectionDeparture(currentStop.stationID, currentStop.departure, currentTime)
 			currentStop.SetDepartedAt(currentTime)
 			train.state = newOnSegmentState(path.Segments)
 			fmt.Printf("  [Train %s] Leaving station %s, entering segment %s (path has %d segments) at %v\n",
 				train.id, currentStop.stationID, firstSegment.ID, len(path.Segments), currentTime)
 		} else {
+			if response.Closed {
+				train.notifyWait(propagation.ReasonClosure, "", "", firstSegment.ID, currentTime)
+			} else {
+				train.notifyWait(propagation.ReasonTrainAhead, "", response.BlockingTrainID, firstSegment.ID, currentTime)
+			}
 			fmt.Printf("  [Train %s] Cannot leave station %s, segment %s not available\n", train.id, currentStop.stationID, firstSegment.ID)
 		}
 	}
@@ -11,6 +11,7 @@ type TrainStop struct {
 	arrivedAt  *time.Duration
 	departure  time.Duration
 	departedAt *time.Duration
+	skipped    bool
 }
 
 func NewTrainStop(stationID string, arrival, departure time.Duration) *TrainStop {
@@ -21,10 +22,38 @@ func NewTrainStop(stationID string, arrival, departure time.Duration) *TrainStop
 	}
 }
 
+func (ts *TrainStop) StationID() string {
+	return ts.stationID
+}
+
+func (ts *TrainStop) Arrival() time.Duration {
+	return ts.arrival
+}
+
 func (ts *TrainStop) Departure() time.Duration {
 	return ts.departure
 }
 
+// ArrivedAt returns when the train actually arrived, if it did.
+func (ts *TrainStop) ArrivedAt() (time.Duration, bool) {
+	if ts.arrivedAt == nil {
+		return 0, false
+	}
+	return *ts.arrivedAt, true
+}
+
+// DepartedAt returns when the train actually departed, if it did.
+func (ts *TrainStop) DepartedAt() (time.Duration, bool) {
+	if ts.departedAt == nil {
+		return 0, false
+	}
+	return *ts.departedAt, true
+}
+
+func (ts *TrainStop) Skipped() bool {
+	return ts.skipped
+}
+
 func (ts *TrainStop) SetArrivedAt(arrivedAt time.Duration) {
 	ts.arrivedAt = &arrivedAt
 }
@@ -33,6 +62,20 @@ func (ts *TrainStop) SetDepartedAt(departedAt time.Duration) {
 	ts.departedAt = &departedAt
 }
 
+// earliestDeparture is when the train may leave after a full planned dwell,
+// which comes after the scheduled departure when it arrived late.
+func (ts *TrainStop) earliestDeparture() time.Duration {
+	if ts.arrivedAt == nil {
+		return ts.departure
+	}
+	return max(ts.departure, *ts.arrivedAt+ts.departure-ts.arrival)
+}
+
+// SetSkipped records that the train ran through the station without stopping.
+func (ts *TrainStop) SetSkipped() {
+	ts.skipped = true
+}
+
 func (ts *TrainStop) MarshalJSON() ([]byte, error) {
 	return json.Marshal(map[string]any{
 		"stationId":  ts.stationID,
@@ -40,5 +83,6 @@ func (ts *TrainStop) MarshalJSON() ([]byte, error) {
 		"arrivedAt":  ts.arrivedAt,
 		"departure":  ts.departure,
 		"departedAt": ts.departedAt,
+		"skipped":    ts.skipped,
 	})
 }
@@ -1,37 +1,93 @@
 package trains
 
 import (
+	"ai30-project/internal/circulation"
+	"ai30-project/internal/connections"
+	"ai30-project/internal/constants"
+	"ai30-project/internal/crew"
+	"ai30-project/internal/dispatcher"
+	"ai30-project/internal/energy"
 	"ai30-project/internal/events"
+	"ai30-project/internal/messaging"
 	"ai30-project/internal/navigation"
+	"ai30-project/internal/passengers"
+	"ai30-project/internal/power"
+	"ai30-project/internal/propagation"
 	"ai30-project/internal/segments"
 	"ai30-project/internal/stations"
+	"context"
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
 type Train struct {
-	id    string
-	stops []*TrainStop
-	event events.Event
+	id     string
+	stops  []*TrainStop
+	events events.Timeline
 
 	state      trainState
 	driver     DriverBehavior
 	isFinished bool
 
-	tickChan        <-chan time.Duration
-	doneChan        chan<- bool
-	stationInboxes  map[string]chan stations.StationMessage
-	segmentInboxes  map[string]chan segments.SegmentMessage
-	navigationInbox chan navigation.NavigationMessage
+	seatCapacity     int
+	standingCapacity int
+	onBoard          []*passengers.Passenger
+	overcarried      []*passengers.Passenger // destination skipped, alight at the next stop
+	alighted         []*passengers.Passenger
+	legs             []passengers.LegLoad
+
+	holds      map[string]time.Duration // reason -> time held at stations
+	diversions []diversion
+
+	energyModel energy.Model
+	energy      map[string]energy.Usage // segment ID -> energy used on it
+
+	// Dispatcher orders
+	holdUntil        time.Duration
+	speedLimit       float64 // m/s
+	speedLimitUntil  time.Duration
+	rerouteRequested bool
+	cancelOrdered    bool
+	closures         []string // segments announced closed since the path was last planned
+
+	blocking *Blocking // resource the train failed to get during the last tick
+	crash    error     // why the train was taken out of service after a panic
+
+	ctx              context.Context
+	requestTimeout   time.Duration
+	deliver          func(inbox any) // set when stepped in place rather than run
+	tap              *messaging.Tap
+	tickChan         <-chan time.Duration
+	doneChan         chan<- bool
+	stationInboxes   map[string]chan stations.StationMessage
+	segmentInboxes   map[string]chan segments.SegmentMessage
+	navigationInbox  chan navigation.NavigationMessage
+	connectionInbox  chan connections.ConnectionMessage
+	circulationInbox chan circulation.CirculationMessage
+	crewInbox        chan crew.CrewMessage
+	dispatcherInbox  chan dispatcher.DispatcherMessage
+	powerInbox       chan power.PowerMessage
+	tracerInbox      chan propagation.TracerMessage
+	orders           chan dispatcher.Order
 }
 
 func NewTrain(id string, stops []*TrainStop) *Train {
 	stops[0].SetArrivedAt(stops[0].arrival)
 	return &Train{
-		id:    id,
-		stops: stops,
-		event: events.GenerateEvent(stops[0].departure, stops[len(stops)-1].arrival),
-		state: newAtStationState(),
+		id:     id,
+		stops:  stops,
+		events: events.Timeline{},
+		state:  newAtStationState(),
+
+		seatCapacity:     constants.DefaultSeatCapacity,
+		standingCapacity: constants.DefaultStandingCapacity,
+
+		holds:          make(map[string]time.Duration),
+		energyModel:    energy.DefaultModel(),
+		energy:         make(map[string]energy.Usage),
+		orders:         make(chan dispatcher.Order, 100),
+		requestTimeout: messaging.DefaultTimeout,
 	}
 }
 
@@ -39,6 +95,25 @@ func (t *Train) ID() string {
 	return t.id
 }
 
+// Line is the commercial line identifier found in the train ID.
+func (t *Train) Line() string {
+	return events.LineOf(t.id)
+}
+
+// Category is the commercial category (OUI, OGO...) found in the train ID.
+func (t *Train) Category() string {
+	return events.CategoryOf(t.id)
+}
+
+func (t *Train) Stops() []*TrainStop {
+	return t.stops
+}
+
+// Events returns the event timeline drawn for the train.
+func (t *Train) Events() events.Timeline {
+	return t.events
+}
+
 func (t *Train) StartStop() *TrainStop {
 	return t.stops[0]
 }
@@ -93,39 +168,193 @@ func (t *Train) SetChannels(
 	t.navigationInbox = navigationInbox
 }
 
-func (t *Train) Run() {
+// SetConnectionInbox enables transfer connections: the train reports its
+// arrivals and departures and asks before leaving whether to wait for a feeder.
+func (t *Train) SetConnectionInbox(connectionInbox chan connections.ConnectionMessage) {
+	t.connectionInbox = connectionInbox
+}
+
+// SetCirculationInbox links the train to the rotation of its trainset: it
+// cannot leave its first stop before the previous trip has been turned around.
+func (t *Train) SetCirculationInbox(circulationInbox chan circulation.CirculationMessage) {
+	t.circulationInbox = circulationInbox
+}
+
+// SetCrewInbox enables the crew roster: departures from relief points wait
+// for the incoming crew to change over.
+func (t *Train) SetCrewInbox(crewInbox chan crew.CrewMessage) {
+	t.crewInbox = crewInbox
+}
+
+// SetTracerInbox enables delay propagation tracing: the train reports every
+// minute it waits and why.
+func (t *Train) SetTracerInbox(tracerInbox chan propagation.TracerMessage) {
+	t.tracerInbox = tracerInbox
+}
+
+// Holds returns how long the train was held at stations, by reason.
+func (t *Train) Holds() map[string]time.Duration {
+	return t.holds
+}
+
+// SetEnergyModel sets the traction of the train.
+func (t *Train) SetEnergyModel(model energy.Model) {
+	t.energyModel = model
+}
+
+// Energy returns the energy the train used, by segment.
+func (t *Train) Energy() map[string]energy.Usage {
+	return t.energy
+}
+
+// SetPowerInbox limits the tractive effort of the train to what the power
+// supply provides: the train reports the power it asks on a segment, and
+// learns which share of it it gets.
+func (t *Train) SetPowerInbox(powerInbox chan power.PowerMessage) {
+	t.powerInbox = powerInbox
+}
+
+// tractionPower is the power asked to run dt from speed from to speed to.
+func (t *Train) tractionPower(from, to float64, dt time.Duration) float64 {
+	return t.energyModel.Power(len(t.onBoard), from, to, dt)
+}
+
+// consume books the energy of running distance meters on a segment.
+func (t *Train) consume(segmentID string, from, to, distance float64) {
+	usage := t.energyModel.Step(len(t.onBoard), from, to, distance)
+	t.energy[segmentID] = t.energy[segmentID].Add(usage)
+}
+
+// SetRequestTimeout bounds how long the train waits for an agent to answer.
+func (t *Train) SetRequestTimeout(timeout time.Duration) {
+	t.requestTimeout = timeout
+}
+
+// SetTap records the messages the train sends.
+func (t *Train) SetTap(tap *messaging.Tap) {
+	t.tap = tap
+}
+
+// Crash returns why the train was taken out of service after a panic, or nil.
+// It must only be called between ticks.
+func (t *Train) Crash() error {
+	return t.crash
+}
+
+// Run plays the ticks of the simulation until the train finishes its journey
+// or ctx is cancelled. A panic takes the train out of service instead of
+// stalling the tick.
+func (t *Train) Run(ctx context.Context) {
+	t.ctx = ctx
 	for {
 		// Phase 1: percept + deliberate
-		currentTime, ok := <-t.tickChan
+		currentTime, ok := t.waitTick(ctx)
 		if !ok {
 			return
 		}
 
-		t.state.percept(t, currentTime)
-		t.state.deliberate(t, currentTime)
+		t.Perceive(currentTime)
 		t.doneChan <- false
 
 		// Phase 2: act
-		currentTime, ok = <-t.tickChan
+		currentTime, ok = t.waitTick(ctx)
 		if !ok {
 			return
 		}
 
-		t.state.act(t, currentTime)
-
-		if t.isFinished {
+		if t.Act(currentTime) {
 			t.doneChan <- true
 			return
 		}
-
 		t.doneChan <- false
 	}
 }
 
+// SetDeliver lets the caller step the train with Perceive and Act instead of
+// Run. Agents do not run on their own goroutine then: deliver must have the
+// agent owning inbox handle its waiting messages.
+func (t *Train) SetDeliver(deliver func(inbox any)) {
+	t.deliver = deliver
+}
+
+// Perceive is the first phase of a tick: the train reads its orders and its
+// surroundings and decides what to do.
+func (t *Train) Perceive(currentTime time.Duration) {
+	t.crash = t.safely(func() {
+		t.receiveOrders()
+		t.state.percept(t, currentTime)
+		t.state.deliberate(t, currentTime)
+	})
+}
+
+// Act is the second phase of a tick. It reports whether the train finished
+// its journey or was taken out of service.
+func (t *Train) Act(currentTime time.Duration) bool {
+	if t.crash == nil {
+		t.crash = t.safely(func() {
+			t.blocking = nil
+			t.state.act(t, currentTime)
+		})
+	}
+
+	if t.crash != nil {
+		t.release()
+		fmt.Printf("  [Train %s] CRASHED and taken out of service at %v: %v\n", t.id, currentTime, t.crash)
+		t.isFinished = true
+	}
+
+	if t.isFinished {
+		return true
+	}
+
+	t.reportStatus(currentTime)
+	return false
+}
+
+// safely runs a step of the train and turns a panic into an error.
+func (t *Train) safely(step func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	step()
+	return nil
+}
+
+// release frees the segment or station held by a crashed train so that other
+// trains are not blocked behind it.
+func (t *Train) release() {
+	_ = t.safely(func() {
+		switch state := t.state.(type) {
+		case *onSegmentState:
+			t.notifySegmentExit(state.currentSegment().ID)
+		case *atStationState:
+			if currentStop := t.CurrentStop(); currentStop != nil {
+				t.notifyStationDeparture(currentStop.stationID)
+			}
+		}
+	})
+}
+
+func (t *Train) waitTick(ctx context.Context) (time.Duration, bool) {
+	select {
+	case <-ctx.Done():
+		return 0, false
+	case currentTime, ok := <-t.tickChan:
+		return currentTime, ok
+	}
+}
+
 func (t *Train) MarshalJSON() ([]byte, error) {
 	return json.Marshal(map[string]any{
-		"id":    t.id,
-		"stops": t.stops,
-		"event": t.event,
+		"id":         t.id,
+		"stops":      t.stops,
+		"events":     t.events,
+		"capacity":   t.Capacity(),
+		"onBoard":    len(t.onBoard),
+		"legs":       t.legs,
+		"holds":      t.holds,
+		"diversions": t.diversions,
 	})
 }
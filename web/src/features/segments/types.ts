@@ -1,3 +1,5 @@
+import type { DelayEvent } from "@/features/trains/types";
+
 export type SegmentTrainInfo = {
   position: number; // meters
   speed: number; // m/min
@@ -10,5 +12,8 @@ export type Segment = {
   toStationId: string;
   length: number; // meters
   maxSpeed: number; // m/min
+  closed: boolean;
+  speedRestriction: number; // m/s, 0 when none
+  incidents: DelayEvent[] | null;
   trainsOnSegment: Record<string, SegmentTrainInfo>;
 };
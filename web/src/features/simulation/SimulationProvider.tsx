@@ -6,14 +6,22 @@ import {
   useState,
 } from "react";
 import { useSimulationHistory } from "./SimulationHistoryProvider";
-import type { DriverBehavior, Simulation, StationStrategy } from "./types";
+import type {
+  DriverBehavior,
+  PluginParams,
+  Plugins,
+  Simulation,
+  StationStrategy,
+} from "./types";
 
 type SimulationContextType = {
   state: Simulation | null;
   start: (
     driverBehavior: DriverBehavior,
     stationStrategy: StationStrategy,
-  ) => void;
+    driverParams: PluginParams,
+    strategyParams: PluginParams,
+  ) => string | null;
   nextTick: () => void;
   restart: () => void;
   isPlaying: boolean;
@@ -26,11 +34,24 @@ type SimulationContextType = {
 
 const SimulationContext = createContext<SimulationContextType | null>(null);
 
+export function listPlugins(): Plugins {
+  return JSON.parse(window.ListPlugins());
+}
+
+// startSimulation returns the new simulation, or the error when a parameter
+// is rejected.
 export function startSimulation(
   driverBehavior: DriverBehavior,
   stationStrategy: StationStrategy,
-): Simulation {
-  const newState = window.Start(driverBehavior, stationStrategy);
+  driverParams: PluginParams,
+  strategyParams: PluginParams,
+): Simulation | { error: string } {
+  const newState = window.Start(
+    driverBehavior,
+    stationStrategy,
+    JSON.stringify(driverParams),
+    JSON.stringify(strategyParams),
+  );
   return JSON.parse(newState);
 }
 
@@ -39,6 +60,10 @@ export function tickSimulation(): Simulation {
   return JSON.parse(newState);
 }
 
+export function stopSimulation(): void {
+  window.Stop();
+}
+
 export const SimulationProvider = ({ children }: React.PropsWithChildren) => {
   const [state, setState] = useState<Simulation | null>(null);
   const [isPlaying, setIsPlaying] = useState(false);
@@ -47,10 +72,25 @@ export const SimulationProvider = ({ children }: React.PropsWithChildren) => {
   const { addToHistory } = useSimulationHistory();
 
   const start = useCallback(
-    (driver: DriverBehavior, station: StationStrategy) => {
-      setState(startSimulation(driver, station));
+    (
+      driver: DriverBehavior,
+      station: StationStrategy,
+      driverParams: PluginParams,
+      strategyParams: PluginParams,
+    ) => {
+      const result = startSimulation(
+        driver,
+        station,
+        driverParams,
+        strategyParams,
+      );
+      if ("error" in result) {
+        return result.error;
+      }
+      setState(result);
       setIsPlaying(true);
       setIsStartDialogOpen(false);
+      return null;
     },
     [],
   );
@@ -60,6 +100,7 @@ export const SimulationProvider = ({ children }: React.PropsWithChildren) => {
   }, []);
 
   const restart = useCallback(() => {
+    stopSimulation();
     setState(null);
     setIsPlaying(false);
     setIsStartDialogOpen(true);
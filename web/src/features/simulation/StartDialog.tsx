@@ -1,5 +1,5 @@
 import { PlayIcon } from "lucide-react";
-import { useState } from "react";
+import { useMemo, useState } from "react";
 import {
   Button,
   Dialog,
@@ -8,6 +8,8 @@ import {
   DialogHeader,
   DialogTitle,
   Field,
+  FieldDescription,
+  FieldError,
   FieldLabel,
   Select,
   SelectContent,
@@ -15,17 +17,87 @@ import {
   SelectTrigger,
   SelectValue,
 } from "@/components/ui";
-import { useSimulation } from "./SimulationProvider";
-import type { DriverBehavior, StationStrategy } from "./types";
+import { driverLabel, strategyLabel } from "./labels";
+import { listPlugins, useSimulation } from "./SimulationProvider";
+import type {
+  DriverBehavior,
+  PluginInfo,
+  PluginParams,
+  StationStrategy,
+} from "./types";
+
+const inputClassName =
+  "border-input h-9 w-full rounded-md border bg-transparent px-3 py-1 text-sm shadow-xs";
+
+const ParamFields = ({
+  plugin,
+  values,
+  onChange,
+}: {
+  plugin: PluginInfo | undefined;
+  values: PluginParams;
+  onChange: (values: PluginParams) => void;
+}) => {
+  if (!plugin) {
+    return null;
+  }
+
+  return plugin.params.map((param) => {
+    const value = values[param.name] ?? param.default;
+    return (
+      <Field key={param.name}>
+        <FieldLabel>{param.name}</FieldLabel>
+        {param.type === "bool" ? (
+          <input
+            type="checkbox"
+            checked={value === true}
+            onChange={(event) =>
+              onChange({ ...values, [param.name]: event.target.checked })
+            }
+          />
+        ) : (
+          <input
+            className={inputClassName}
+            type={param.type === "number" ? "number" : "text"}
+            min={param.min}
+            max={param.max}
+            value={String(value)}
+            onChange={(event) =>
+              onChange({
+                ...values,
+                [param.name]:
+                  param.type === "number"
+                    ? Number(event.target.value)
+                    : event.target.value,
+              })
+            }
+          />
+        )}
+        <FieldDescription>{param.description}</FieldDescription>
+      </Field>
+    );
+  });
+};
 
 export const StartDialog = () => {
   const { isStartDialogOpen, start } = useSimulation();
+  const plugins = useMemo(() => listPlugins(), []);
   const [driverBehavior, setDriverBehavior] = useState<DriverBehavior>("eco");
   const [stationStrategy, setStationStrategy] =
     useState<StationStrategy>("no_sort");
+  const [driverParams, setDriverParams] = useState<PluginParams>({});
+  const [strategyParams, setStrategyParams] = useState<PluginParams>({});
+  const [error, setError] = useState<string | null>(null);
+
+  const driver = plugins.drivers.find((d) => d.name === driverBehavior);
+  const strategy = plugins.stationStrategies.find(
+    (s) => s.name === stationStrategy,
+  );
 
   function handleStart() {
-    start(driverBehavior, stationStrategy);
+    setError(
+      start(driverBehavior, stationStrategy, driverParams, strategyParams),
+    );
   }
 
   return (
@@ -40,47 +112,57 @@ export const StartDialog = () => {
             <FieldLabel>Comportement du conducteur</FieldLabel>
             <Select
               value={driverBehavior}
-              onValueChange={(value: DriverBehavior) =>
-                setDriverBehavior(value)
-              }
+              onValueChange={(value: DriverBehavior) => {
+                setDriverBehavior(value);
+                setDriverParams({});
+              }}
             >
               <SelectTrigger className="w-full">
                 <SelectValue placeholder="Choisir un comportement" />
               </SelectTrigger>
               <SelectContent>
-                <SelectItem value="eco">Éco</SelectItem>
-                <SelectItem value="intermediate">Intermédiaire</SelectItem>
-                <SelectItem value="crazy">Crazy</SelectItem>
-                <SelectItem value="very_crazy">Very Crazy</SelectItem>
-                <SelectItem value="soigneux">Soigneux</SelectItem>
+                {plugins.drivers.map((d) => (
+                  <SelectItem key={d.name} value={d.name}>
+                    {driverLabel(d.name)}
+                  </SelectItem>
+                ))}
               </SelectContent>
             </Select>
+            <FieldDescription>{driver?.description}</FieldDescription>
           </Field>
+          <ParamFields
+            plugin={driver}
+            values={driverParams}
+            onChange={setDriverParams}
+          />
           <Field>
             <FieldLabel>Stratégie de gare</FieldLabel>
             <Select
               value={stationStrategy}
-              onValueChange={(value: StationStrategy) =>
-                setStationStrategy(value)
-              }
+              onValueChange={(value: StationStrategy) => {
+                setStationStrategy(value);
+                setStrategyParams({});
+              }}
             >
               <SelectTrigger className="w-full">
                 <SelectValue placeholder="Choisir une stratégie" />
               </SelectTrigger>
               <SelectContent>
-                <SelectItem value="no_sort">Aucun tri</SelectItem>
-                <SelectItem value="entry_time_asc">
-                  Par heure d'entrée (croissant)
-                </SelectItem>
-                <SelectItem value="delay_asc">
-                  Par retard (croissant)
-                </SelectItem>
-                <SelectItem value="delay_asc_with_threshold">
-                  Par retard avec seuil (30 min)
-                </SelectItem>
+                {plugins.stationStrategies.map((s) => (
+                  <SelectItem key={s.name} value={s.name}>
+                    {strategyLabel(s.name)}
+                  </SelectItem>
+                ))}
               </SelectContent>
             </Select>
+            <FieldDescription>{strategy?.description}</FieldDescription>
           </Field>
+          <ParamFields
+            plugin={strategy}
+            values={strategyParams}
+            onChange={setStrategyParams}
+          />
+          {error && <FieldError>{error}</FieldError>}
         </div>
 
         <DialogFooter>
@@ -51,13 +51,13 @@ export const AverageDelayByCauseCard = () => {
     };
 
     Object.values(state.trains).forEach((train) => {
-      if (train.event?.kind === "delay") {
-        const delayEvent = train.event;
+      train.events.forEach((delayEvent) => {
+        if (delayEvent.kind !== "delay") return;
         const delayMinutes = Math.floor(
           delayEvent.duration / 1_000_000_000 / 60,
         );
         causeDelays[delayEvent.cause].push(delayMinutes);
-      }
+      });
     });
 
     return Object.entries(causeDelays)
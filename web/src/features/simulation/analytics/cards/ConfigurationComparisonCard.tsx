@@ -17,6 +17,7 @@ import {
 } from "@/components/ui/chart";
 import { formatDelay } from "@/utils/numbers";
 import { useSimulationHistory } from "../../SimulationHistoryProvider";
+import { driverLabel, strategyLabel } from "../../labels";
 import type { DriverBehavior, StationStrategy } from "../../types";
 
 type ConfigurationKey = `${DriverBehavior}-${StationStrategy}`;
@@ -40,20 +41,7 @@ function formatConfigurationName(
   driverBehavior: DriverBehavior,
   stationStrategy: StationStrategy,
 ): string {
-  const driverLabels: Record<DriverBehavior, string> = {
-    eco: "Éco",
-    intermediate: "Intermédiaire",
-    crazy: "Crazy",
-    very_crazy: "Very Crazy",
-    soigneux: "Soigneux",
-  };
-  const strategyLabels: Record<StationStrategy, string> = {
-    no_sort: "Aucun tri",
-    entry_time_asc: "Temps d'entrée",
-    delay_asc: "Retard croissant",
-    delay_asc_with_threshold: "Retard avec seuil",
-  };
-  return `${driverLabels[driverBehavior]} - ${strategyLabels[stationStrategy]}`;
+  return `${driverLabel(driverBehavior)} - ${strategyLabel(stationStrategy)}`;
 }
 
 function calculateStatistics(delays: number[]): ConfigurationStats {
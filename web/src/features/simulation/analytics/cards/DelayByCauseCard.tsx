@@ -52,10 +52,10 @@ export const DelayByCauseCard = () => {
     };
 
     Object.values(state.trains).forEach((train) => {
-      if (train.event?.kind === "delay") {
-        const delayEvent = train.event;
+      train.events.forEach((delayEvent) => {
+        if (delayEvent.kind !== "delay") return;
         causeCounts[delayEvent.cause] += 1;
-      }
+      });
     });
 
     return Object.entries(causeCounts)
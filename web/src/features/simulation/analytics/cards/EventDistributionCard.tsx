@@ -21,21 +21,28 @@ export const EventDistributionCard = () => {
   const data = useMemo(() => {
     let delays = 0;
     let cancellations = 0;
+    let others = 0;
     let none = 0;
 
     if (state?.trains) {
       Object.values(state.trains).forEach((train) => {
-        switch (train.event.kind) {
-          case "delay":
-            delays += 1;
-            break;
-          case "cancellation":
-            cancellations += 1;
-            break;
-          case "none":
-            none += 1;
-            break;
+        if (train.events.length === 0) {
+          none += 1;
         }
+        train.events.forEach((event) => {
+          switch (event.kind) {
+            case "delay":
+              delays += 1;
+              break;
+            case "cancellation":
+            case "partial_cancellation":
+              cancellations += 1;
+              break;
+            default:
+              others += 1;
+              break;
+          }
+        });
       });
     }
 
@@ -50,6 +57,11 @@ export const EventDistributionCard = () => {
         value: cancellations,
         fill: "var(--color-chart-2)",
       },
+      {
+        type: "Autres incidents",
+        value: others,
+        fill: "var(--color-chart-3)",
+      },
       {
         type: "Aucun événement",
         value: none,
@@ -65,6 +77,9 @@ export const EventDistributionCard = () => {
     cancellations: {
       label: "Annulations",
     },
+    others: {
+      label: "Autres incidents",
+    },
     none: {
       label: "Aucun événement",
     },
@@ -75,7 +90,7 @@ export const EventDistributionCard = () => {
       <CardHeader>
         <CardTitle>Répartition des événements</CardTitle>
         <CardDescription>
-          Distribution des retards, annulations et trains sans événement
+          Distribution des événements par type et trains sans événement
         </CardDescription>
       </CardHeader>
       <CardContent>
@@ -0,0 +1,28 @@
+import type { DriverBehavior, StationStrategy } from "./types";
+
+// Display names of the known plugins; the others show their registered name.
+const driverLabels: Record<DriverBehavior, string> = {
+  eco: "Éco",
+  intermediate: "Intermédiaire",
+  crazy: "Crazy",
+  very_crazy: "Very Crazy",
+  soigneux: "Soigneux",
+  adaptive: "Adaptatif",
+  das: "Assisté (DAS)",
+};
+
+const strategyLabels: Record<StationStrategy, string> = {
+  no_sort: "Aucun tri",
+  entry_time_asc: "Par heure d'entrée (croissant)",
+  delay_asc: "Par retard (croissant)",
+  delay_asc_with_threshold: "Par retard avec seuil",
+  script: "Expression personnalisée",
+};
+
+export function driverLabel(driverBehavior: DriverBehavior): string {
+  return driverLabels[driverBehavior] ?? driverBehavior;
+}
+
+export function strategyLabel(stationStrategy: StationStrategy): string {
+  return strategyLabels[stationStrategy] ?? stationStrategy;
+}
@@ -2,18 +2,34 @@ import type { Segment } from "@/features/segments";
 import type { Station } from "@/features/stations";
 import type { Train } from "@/features/trains";
 
-export type DriverBehavior =
-  | "eco"
-  | "intermediate"
-  | "crazy"
-  | "very_crazy"
-  | "soigneux";
+// Names of the drivers and station strategies registered in the simulation,
+// listed by window.ListPlugins().
+export type DriverBehavior = string;
 
-export type StationStrategy =
-  | "no_sort"
-  | "entry_time_asc"
-  | "delay_asc"
-  | "delay_asc_with_threshold";
+export type StationStrategy = string;
+
+export type PluginParam = {
+  name: string;
+  type: "number" | "string" | "bool";
+  description: string;
+  default: number | string | boolean;
+  min?: number;
+  max?: number;
+  required: boolean;
+};
+
+export type PluginInfo = {
+  name: string;
+  description: string;
+  params: PluginParam[];
+};
+
+export type Plugins = {
+  drivers: PluginInfo[];
+  stationStrategies: PluginInfo[];
+};
+
+export type PluginParams = Record<string, number | string | boolean>;
 
 export type Simulation = {
   isStarted: boolean;
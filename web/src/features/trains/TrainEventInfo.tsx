@@ -14,32 +14,55 @@ function formatDelayCause(cause: DelayCause): string {
   return causeMap[cause];
 }
 
+function formatEventTitle(event: TrainEvent): string {
+  switch (event.kind) {
+    case "delay":
+      return `Incident : ${formatDelayCause(event.cause)}`;
+    case "cancellation":
+      return "Annulation";
+    case "partial_cancellation":
+      return `Limité à ${event.stationId}`;
+    case "skipped_stop":
+      return `Arrêt supprimé : ${event.stationId}`;
+    case "speed_reduction":
+      return `Vitesse réduite : ${Math.round(event.maxSpeed * 3.6)} km/h`;
+  }
+}
+
 type TrainEventInfoProps = {
   event: TrainEvent;
 };
 
 export const TrainEventInfo = ({ event }: TrainEventInfoProps) => {
-  if (event.kind !== "delay") {
-    return null;
-  }
-
   return (
     <div className="rounded-lg border bg-background p-3">
       <div className="flex items-start gap-2">
         <AlertTriangleIcon className="mt-0.5 size-4" />
         <div className="space-y-1">
           <div className="font-semibold text-foreground text-sm">
-            Incident : {formatDelayCause(event.cause)}
+            {formatEventTitle(event)}
           </div>
           <div className="space-y-0.5 text-muted-foreground text-xs">
             <div>
               Début :&nbsp;
               <span className="font-medium">{formatTime(event.startTime)}</span>
             </div>
-            <div>
-              Durée :&nbsp;
-              <span className="font-medium">{formatTime(event.duration)}</span>
-            </div>
+            {event.kind === "delay" && (event.stationId || event.segmentId) && (
+              <div>
+                Lieu :&nbsp;
+                <span className="font-medium">
+                  {event.stationId ?? event.segmentId}
+                </span>
+              </div>
+            )}
+            {"duration" in event && (
+              <div>
+                Durée :&nbsp;
+                <span className="font-medium">
+                  {formatTime(event.duration)}
+                </span>
+              </div>
+            )}
           </div>
         </div>
       </div>
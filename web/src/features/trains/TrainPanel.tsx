@@ -24,7 +24,9 @@ export const TrainPanel = () => {
         </Button>
       </div>
 
-      {train.event && <TrainEventInfo event={train.event} />}
+      {train.events.map((event, index) => (
+        <TrainEventInfo key={index} event={event} />
+      ))}
 
       <div className="overflow-y-auto">
         {train.stops.map((stop, index) => (
@@ -4,6 +4,7 @@ export type TrainStop = {
   arrivedAt: number | null;
   departure: number;
   departedAt: number | null;
+  skipped: boolean;
 };
 
 export type DelayCause =
@@ -19,6 +20,8 @@ export type DelayEvent = {
   cause: DelayCause;
   duration: number;
   startTime: number;
+  stationId?: string;
+  segmentId?: string;
 };
 
 export type CancellationEvent = {
@@ -26,14 +29,35 @@ export type CancellationEvent = {
   startTime: number;
 };
 
-export type NoEvent = {
-  kind: "none";
+export type PartialCancellationEvent = {
+  kind: "partial_cancellation";
+  stationId: string;
+  startTime: number;
+};
+
+export type SkippedStopEvent = {
+  kind: "skipped_stop";
+  stationId: string;
+  startTime: number;
+};
+
+export type SpeedReductionEvent = {
+  kind: "speed_reduction";
+  cause: DelayCause;
+  maxSpeed: number;
+  duration: number;
+  startTime: number;
 };
 
-export type TrainEvent = DelayEvent | CancellationEvent | NoEvent;
+export type TrainEvent =
+  | DelayEvent
+  | CancellationEvent
+  | PartialCancellationEvent
+  | SkippedStopEvent
+  | SpeedReductionEvent;
 
 export type Train = {
   id: string;
   stops: TrainStop[];
-  event: TrainEvent;
+  events: TrainEvent[];
 };
@@ -44,11 +44,12 @@ function getPositionAlongLine(
 }
 
 function isDelayActive(train: Train, currentTime: number): boolean {
-  if (train.event.kind !== "delay") {
-    return false;
-  }
-  const { startTime, duration } = train.event;
-  return currentTime >= startTime && currentTime < startTime + duration;
+  return train.events.some(
+    (event) =>
+      event.kind === "delay" &&
+      currentTime >= event.startTime &&
+      currentTime < event.startTime + event.duration,
+  );
 }
 
 function createTrainFeatures(
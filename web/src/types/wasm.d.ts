@@ -5,6 +5,16 @@ interface GoWasm {
 
 interface Window {
   Go: new () => GoWasm;
-  Start: (driverBehavior: string, stationStrategy: string) => string;
+  ListPlugins: () => string;
+  Start: (
+    driverBehavior: string,
+    stationStrategy: string,
+    driverParams?: string,
+    strategyParams?: string,
+  ) => string;
   Tick: () => string;
+  Stop: () => string;
+  CloseSegment: (segmentId: string) => string;
+  OpenSegment: (segmentId: string) => string;
+  AddDisruption: (disruptionsJson: string) => string;
 }